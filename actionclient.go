@@ -106,6 +106,9 @@ type ActionClientGoalHandler struct {
 	commState     ActionClientCommState
 	terminalState ActionClientTerminalState
 	result        interface{}
+
+	// out
+	done chan struct{}
 }
 
 // CommState returns the communication state of the goal handler.
@@ -353,6 +356,12 @@ func (gh *ActionClientGoalHandler) onResult(resAction reflect.Value) bool {
 func (gh *ActionClientGoalHandler) transitionTo(newCommState ActionClientCommState) {
 	gh.commState = newCommState
 
+	if newCommState == ActionClientCommStateDone {
+		gh.ac.conf.Node.metrics.onGoalDone(gh.ac.conf.Node.absoluteTopicName(gh.ac.conf.Name),
+			"client", gh.terminalState.String())
+		defer close(gh.done)
+	}
+
 	if gh.conf.OnTransition != nil {
 		dres := gh.result
 		if dres == nil {
@@ -560,30 +569,29 @@ func (ac *ActionClient) Close() error {
 
 // WaitForServer waits for the action server to start.
 func (ac *ActionClient) WaitForServer() {
-	select {
-	case <-ac.statusSubOk:
-	case <-ac.ctx.Done():
-	}
-
-	select {
-	case <-ac.feedbackSubOk:
-	case <-ac.ctx.Done():
-	}
-
-	select {
-	case <-ac.resultSubOk:
-	case <-ac.ctx.Done():
-	}
+	ac.WaitForServerContext(context.Background())
+}
 
-	select {
-	case <-ac.goalPubOk:
-	case <-ac.ctx.Done():
+// WaitForServerContext waits for the action server to start, or for the context
+// to be canceled.
+func (ac *ActionClient) WaitForServerContext(ctx context.Context) error {
+	for _, ch := range []chan struct{}{
+		ac.statusSubOk,
+		ac.feedbackSubOk,
+		ac.resultSubOk,
+		ac.goalPubOk,
+		ac.cancelPubOk,
+	} {
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ac.ctx.Done():
+			return fmt.Errorf("terminated")
+		}
 	}
 
-	select {
-	case <-ac.cancelPubOk:
-	case <-ac.ctx.Done():
-	}
+	return nil
 }
 
 // SendGoal sends a goal.
@@ -630,7 +638,7 @@ func (ac *ActionClient) SendGoal(conf ActionClientGoalConf) (*ActionClientGoalHa
 		}
 		if cbt.In(0) != reflect.PtrTo(ac.fbType) {
 			return nil, fmt.Errorf("OnFeedback 1st argument must must be %s, while is %v",
-				reflect.PtrTo(ac.fbType), cbt.In(1))
+				reflect.PtrTo(ac.fbType), cbt.In(0))
 		}
 	}
 
@@ -646,10 +654,14 @@ func (ac *ActionClient) SendGoal(conf ActionClientGoalConf) (*ActionClientGoalHa
 	goalID := actionlib_msgs.GoalID{
 		Stamp: now,
 		Id: func() string {
+			ac.mutex.Lock()
+			ac.goalCount++
+			goalCount := ac.goalCount
+			ac.mutex.Unlock()
+
 			// https://github.com/ros/actionlib/blob/c3b2bd84f07ff54c36033c92861d3b63b7420590/actionlib/src/actionlib/goal_id_generator.py#L62
 			ss := ac.conf.Node.absoluteName() + "-"
-			ac.goalCount++
-			ss += strconv.FormatInt(int64(ac.goalCount), 10) + "-"
+			ss += strconv.FormatInt(int64(goalCount), 10) + "-"
 			nowSecs := float64(now.UnixNano()) / 1000000000
 			ss += strconv.FormatFloat(nowSecs, 'f', -1, 64)
 			return ss
@@ -663,6 +675,7 @@ func (ac *ActionClient) SendGoal(conf ActionClientGoalConf) (*ActionClientGoalHa
 		ac:   ac,
 		conf: conf,
 		id:   goalID.Id,
+		done: make(chan struct{}),
 	}
 
 	func() {
@@ -676,6 +689,36 @@ func (ac *ActionClient) SendGoal(conf ActionClientGoalConf) (*ActionClientGoalHa
 	return gh, nil
 }
 
+// SendGoalAndWait sends a goal and waits until it reaches a terminal state.
+// It returns the result and the terminal state of the goal.
+// If the context is canceled before the goal is done, the goal is canceled
+// and the context error is returned.
+func (ac *ActionClient) SendGoalAndWait(ctx context.Context, goal interface{}) (
+	interface{}, ActionClientTerminalState, error) {
+	gh, err := ac.SendGoal(ActionClientGoalConf{
+		Goal: goal,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	select {
+	case <-gh.done:
+	case <-ctx.Done():
+		gh.Cancel()
+		return nil, 0, ctx.Err()
+	case <-ac.ctx.Done():
+		return nil, 0, fmt.Errorf("terminated")
+	}
+
+	res := gh.result
+	if res == nil {
+		res = reflect.New(ac.resType).Interface()
+	}
+
+	return res, gh.terminalState, nil
+}
+
 // CancelAllGoals cancels all goals running on the server.
 func (ac *ActionClient) CancelAllGoals() {
 	ac.cancelPub.Write(&actionlib_msgs.GoalID{
@@ -683,6 +726,14 @@ func (ac *ActionClient) CancelAllGoals() {
 	})
 }
 
+// CancelGoalsAtAndBeforeTime cancels all goals running on the server
+// that have been sent at or before the given time.
+func (ac *ActionClient) CancelGoalsAtAndBeforeTime(t time.Time) {
+	ac.cancelPub.Write(&actionlib_msgs.GoalID{
+		Stamp: t,
+	})
+}
+
 func (ac *ActionClient) onStatus(msg *actionlib_msgs.GoalStatusArray) {
 	func() {
 		ac.mutex.Lock()
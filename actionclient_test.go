@@ -1,6 +1,7 @@
 package goroslib
 
 import (
+	"context"
 	"sync"
 	"testing"
 	"time"
@@ -311,3 +312,136 @@ func TestActionClientDoubleGoal(t *testing.T) {
 		})
 	}
 }
+
+func TestActionClientWaitForServerContext(t *testing.T) {
+	m, err := newContainerMaster()
+	require.NoError(t, err)
+	defer m.close()
+
+	nc, err := NewNode(NodeConf{
+		Namespace:     "/myns",
+		Name:          "goroslib",
+		MasterAddress: m.IP() + ":11311",
+	})
+	require.NoError(t, err)
+	defer nc.Close()
+
+	ac, err := NewActionClient(ActionClientConf{
+		Node:   nc,
+		Name:   "test_action",
+		Action: &DoSomethingAction{},
+	})
+	require.NoError(t, err)
+	defer ac.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	err = ac.WaitForServerContext(ctx)
+	require.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestActionClientSendGoalAndWait(t *testing.T) {
+	m, err := newContainerMaster()
+	require.NoError(t, err)
+	defer m.close()
+
+	ns, err := NewNode(NodeConf{
+		Namespace:     "/myns",
+		Name:          "goroslib-server",
+		MasterAddress: m.IP() + ":11311",
+	})
+	require.NoError(t, err)
+	defer ns.Close()
+
+	as, err := NewActionServer(ActionServerConf{
+		Node:   ns,
+		Name:   "test_action",
+		Action: &DoSomethingAction{},
+		OnGoal: func(gh *ActionServerGoalHandler, goal *DoSomethingActionGoal) {
+			go func() {
+				gh.SetAccepted()
+
+				if goal.Input == 2 {
+					return
+				}
+
+				time.Sleep(200 * time.Millisecond)
+
+				gh.SetSucceeded(&DoSomethingActionResult{
+					Output: goal.Input * 2,
+				})
+			}()
+		},
+		OnCancel: func(gh *ActionServerGoalHandler) {
+			gh.SetCanceled(&DoSomethingActionResult{})
+		},
+	})
+	require.NoError(t, err)
+	defer as.Close()
+
+	nc, err := NewNode(NodeConf{
+		Namespace:     "/myns",
+		Name:          "goroslib",
+		MasterAddress: m.IP() + ":11311",
+	})
+	require.NoError(t, err)
+	defer nc.Close()
+
+	t.Run("untyped", func(t *testing.T) {
+		ac, err := NewActionClient(ActionClientConf{
+			Node:   nc,
+			Name:   "test_action",
+			Action: &DoSomethingAction{},
+		})
+		require.NoError(t, err)
+		defer ac.Close()
+
+		err = ac.WaitForServerContext(context.Background())
+		require.NoError(t, err)
+
+		res, ts, err := ac.SendGoalAndWait(context.Background(), &DoSomethingActionGoal{Input: 3})
+		require.NoError(t, err)
+		require.Equal(t, ActionClientTerminalStateSucceeded, ts)
+		require.Equal(t, &DoSomethingActionResult{6}, res)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+
+		_, _, err = ac.SendGoalAndWait(ctx, &DoSomethingActionGoal{Input: 2})
+		require.Equal(t, context.DeadlineExceeded, err)
+	})
+
+	t.Run("typed", func(t *testing.T) {
+		ac, err := NewActionClientOf(
+			ActionClientConfOf[DoSomethingActionGoal, DoSomethingActionResult, DoSomethingActionFeedback]{
+				Node: nc,
+				Name: "test_action",
+			})
+		require.NoError(t, err)
+		defer ac.Close()
+
+		err = ac.WaitForServer()
+		require.NoError(t, err)
+
+		res, ts, err := ac.SendGoalAndWait(context.Background(), &DoSomethingActionGoal{Input: 3})
+		require.NoError(t, err)
+		require.Equal(t, ActionClientTerminalStateSucceeded, ts)
+		require.Equal(t, &DoSomethingActionResult{6}, res)
+
+		done := make(chan struct{})
+
+		_, err = ac.SendGoal(ActionClientGoalConfOf[DoSomethingActionGoal, DoSomethingActionResult, DoSomethingActionFeedback]{
+			Goal: &DoSomethingActionGoal{Input: 4},
+			OnTransition: func(gh *ActionClientGoalHandler, res *DoSomethingActionResult) {
+				if gh.CommState() == ActionClientCommStateDone {
+					require.Equal(t, &DoSomethingActionResult{8}, res)
+					close(done)
+				}
+			},
+		})
+		require.NoError(t, err)
+
+		<-done
+	})
+}
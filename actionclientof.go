@@ -0,0 +1,135 @@
+package goroslib
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// ActionClientGoalConfOf is the configuration of ActionClientOf.SendGoal().
+type ActionClientGoalConfOf[G, R, F any] struct {
+	// the goal to send.
+	Goal *G
+
+	// (optional) function that will be called when a status transition happens.
+	OnTransition func(*ActionClientGoalHandler, *R)
+
+	// (optional) function that will be called when a feedback is received.
+	OnFeedback func(*F)
+}
+
+// ActionClientConfOf is the configuration of an ActionClientOf.
+type ActionClientConfOf[G, R, F any] struct {
+	// parent node.
+	Node *Node
+
+	// name of the action.
+	Name string
+}
+
+// ActionClientOf is a type-safe wrapper around ActionClient, where G, R and F
+// are the goal, result and feedback types of the action.
+type ActionClientOf[G, R, F any] struct {
+	ac *ActionClient
+}
+
+// NewActionClientOf allocates an ActionClientOf. See ActionClientConfOf for the options.
+func NewActionClientOf[G, R, F any](conf ActionClientConfOf[G, R, F]) (*ActionClientOf[G, R, F], error) {
+	// the action is built from G, R and F, since its name and package
+	// are not part of the messages exchanged with the server.
+	action := reflect.New(reflect.StructOf([]reflect.StructField{
+		{
+			Name: "Goal",
+			Type: reflect.TypeOf((*G)(nil)).Elem(),
+		},
+		{
+			Name: "Result",
+			Type: reflect.TypeOf((*R)(nil)).Elem(),
+		},
+		{
+			Name: "Feedback",
+			Type: reflect.TypeOf((*F)(nil)).Elem(),
+		},
+	})).Interface()
+
+	ac, err := NewActionClient(ActionClientConf{
+		Node:   conf.Node,
+		Name:   conf.Name,
+		Action: action,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ActionClientOf[G, R, F]{
+		ac: ac,
+	}, nil
+}
+
+// Close closes an ActionClientOf and shuts down all its operations.
+func (ac *ActionClientOf[G, R, F]) Close() error {
+	return ac.ac.Close()
+}
+
+// WaitForServer waits for the action server to start.
+// It returns an error if the client is closed in the meanwhile.
+func (ac *ActionClientOf[G, R, F]) WaitForServer() error {
+	return ac.ac.WaitForServerContext(context.Background())
+}
+
+// WaitForServerContext waits for the action server to start, or for the context
+// to be canceled.
+func (ac *ActionClientOf[G, R, F]) WaitForServerContext(ctx context.Context) error {
+	return ac.ac.WaitForServerContext(ctx)
+}
+
+// SendGoal sends a goal.
+func (ac *ActionClientOf[G, R, F]) SendGoal(conf ActionClientGoalConfOf[G, R, F]) (*ActionClientGoalHandler, error) {
+	if conf.Goal == nil {
+		return nil, fmt.Errorf("Goal is empty")
+	}
+
+	uconf := ActionClientGoalConf{
+		Goal: conf.Goal,
+	}
+
+	// a nil function stored into an interface{} is not nil
+	if conf.OnTransition != nil {
+		uconf.OnTransition = conf.OnTransition
+	}
+	if conf.OnFeedback != nil {
+		uconf.OnFeedback = conf.OnFeedback
+	}
+
+	return ac.ac.SendGoal(uconf)
+}
+
+// SendGoalAndWait sends a goal and waits until it reaches a terminal state.
+// It returns the result and the terminal state of the goal.
+// If the context is canceled before the goal is done, the goal is canceled
+// and the context error is returned.
+func (ac *ActionClientOf[G, R, F]) SendGoalAndWait(ctx context.Context, goal *G) (
+	*R, ActionClientTerminalState, error) {
+	if goal == nil {
+		return nil, 0, fmt.Errorf("Goal is empty")
+	}
+
+	res, state, err := ac.ac.SendGoalAndWait(ctx, goal)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return res.(*R), state, nil
+}
+
+// CancelAllGoals cancels all goals running on the server.
+func (ac *ActionClientOf[G, R, F]) CancelAllGoals() {
+	ac.ac.CancelAllGoals()
+}
+
+// CancelGoalsAtAndBeforeTime cancels all goals running on the server
+// that have been sent at or before the given time.
+func (ac *ActionClientOf[G, R, F]) CancelGoalsAtAndBeforeTime(t time.Time) {
+	ac.ac.CancelGoalsAtAndBeforeTime(t)
+}
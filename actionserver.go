@@ -55,12 +55,71 @@ func (s ActionServerGoalState) String() string {
 	}
 }
 
+// ActionServerGoalPolicy is the policy used by an ActionServer to handle
+// concurrent goals.
+type ActionServerGoalPolicy int
+
+// goal policies.
+const (
+	// every goal is passed to OnGoal as soon as it arrives.
+	ActionServerGoalPolicyParallel ActionServerGoalPolicy = iota
+
+	// goals that arrive while another goal is pending or active are rejected.
+	ActionServerGoalPolicySingle
+
+	// goals that arrive while another goal is pending or active are queued,
+	// and are passed to OnGoal once the previous goal reaches a terminal state.
+	ActionServerGoalPolicyQueue
+
+	// goals that arrive while another goal is pending or active cause
+	// the cancellation of the previous goal.
+	ActionServerGoalPolicyPreempt
+)
+
 // ActionServerGoalHandler is a goal handler of an ActionServer.
 type ActionServerGoalHandler struct {
-	as      *ActionServer
-	id      string
-	created time.Time
-	state   ActionServerGoalState
+	as        *ActionServer
+	id        string
+	stamp     time.Time
+	goal      reflect.Value
+	delivered bool
+	doneTime  time.Time
+	state     ActionServerGoalState
+}
+
+// ID returns the ID of the goal.
+func (gh *ActionServerGoalHandler) ID() string {
+	return gh.id
+}
+
+// State returns the current state of the goal.
+func (gh *ActionServerGoalHandler) State() ActionServerGoalState {
+	gh.as.mutex.Lock()
+	defer gh.as.mutex.Unlock()
+	return gh.state
+}
+
+func (gh *ActionServerGoalHandler) goalID() actionlib_msgs.GoalID {
+	return actionlib_msgs.GoalID{
+		Stamp: gh.stamp,
+		Id:    gh.id,
+	}
+}
+
+// requestCancel moves the goal into the RECALLING or PREEMPTING state.
+// It returns true if the goal is waiting for a cancellation.
+func (gh *ActionServerGoalHandler) requestCancel() bool {
+	switch gh.state {
+	case ActionServerGoalStatePending:
+		gh.state = ActionServerGoalStateRecalling
+		return true
+
+	case ActionServerGoalStateActive:
+		gh.state = ActionServerGoalStatePreempting
+		return true
+	}
+
+	return false
 }
 
 // PublishFeedback publishes a feedback about the goal,
@@ -83,9 +142,7 @@ func (gh *ActionServerGoalHandler) PublishFeedback(fb interface{}) {
 	fbAction.Elem().FieldByName("Header").Set(reflect.ValueOf(header))
 
 	status := actionlib_msgs.GoalStatus{
-		GoalId: actionlib_msgs.GoalID{
-			Id: gh.id,
-		},
+		GoalId: gh.goalID(),
 		Status: uint8(gh.state),
 		Text:   "",
 	}
@@ -112,9 +169,7 @@ func (gh *ActionServerGoalHandler) publishResult(res interface{}) {
 	resAction.Elem().FieldByName("Header").Set(reflect.ValueOf(header))
 
 	status := actionlib_msgs.GoalStatus{
-		GoalId: actionlib_msgs.GoalID{
-			Id: gh.id,
-		},
+		GoalId: gh.goalID(),
 		Status: uint8(gh.state),
 		Text:   "",
 	}
@@ -123,6 +178,15 @@ func (gh *ActionServerGoalHandler) publishResult(res interface{}) {
 	resAction.Elem().FieldByName("Result").Set(reflect.ValueOf(res).Elem())
 
 	gh.as.resultPub.Write(resAction.Interface())
+
+	// the goal reached a terminal state
+	gh.as.conf.Node.metrics.onGoalDone(gh.as.conf.Node.absoluteTopicName(gh.as.conf.Name),
+		"server", gh.state.String())
+	gh.doneTime = now
+	select {
+	case gh.as.goalDone <- struct{}{}:
+	default:
+	}
 }
 
 // SetAccepted sets the goal as accepted.
@@ -211,16 +275,23 @@ type ActionServerConf struct {
 	// It defaults to 200 ms.
 	StatusPeriod time.Duration
 
-	// (optional) goals are deleted after this duration.
+	// (optional) goals are deleted after they have been in a terminal state
+	// for this duration.
 	// It defaults to 5 secs.
 	DeleteGoalAfter time.Duration
 
+	// (optional) policy used to handle goals that arrive while another goal
+	// is pending or active.
+	// It defaults to ActionServerGoalPolicyParallel.
+	GoalPolicy ActionServerGoalPolicy
+
 	// (optional) function in the form func(*ActionServerGoalHandler, *ActionGoal) that will be called
 	// whenever a goal arrives.
 	OnGoal interface{}
 
 	// (optional) function in the form func(*ActionServerGoalHandler) that will be called
-	// whenever a goal cancellation request arrives.
+	// whenever a goal cancellation request arrives. When this is called, the goal
+	// is in the RECALLING or PREEMPTING state.
 	OnCancel func(gh *ActionServerGoalHandler)
 }
 
@@ -243,9 +314,15 @@ type ActionServer struct {
 	cancelSub      *Subscriber
 	mutex          sync.Mutex
 	goals          map[string]*ActionServerGoalHandler
+	queue          []*ActionServerGoalHandler
+	lastCancel     time.Time
+
+	// in
+	goalDone chan struct{}
 
 	// out
-	done chan struct{}
+	done      chan struct{}
+	queueDone chan struct{}
 }
 
 // NewActionServer allocates an ActionServer. See ActionServerConf for the options.
@@ -293,7 +370,9 @@ func NewActionServer(conf ActionServerConf) (*ActionServer, error) {
 		resActionType:  reflect.TypeOf(resAction),
 		fbActionType:   reflect.TypeOf(fbAction),
 		goals:          make(map[string]*ActionServerGoalHandler),
+		goalDone:       make(chan struct{}, 1),
 		done:           make(chan struct{}),
+		queueDone:      make(chan struct{}),
 	}
 
 	if conf.OnGoal != nil {
@@ -376,6 +455,7 @@ func NewActionServer(conf ActionServerConf) (*ActionServer, error) {
 	}
 
 	go as.run()
+	go as.runQueue()
 
 	return as, nil
 }
@@ -384,6 +464,7 @@ func NewActionServer(conf ActionServerConf) (*ActionServer, error) {
 func (as *ActionServer) Close() error {
 	as.ctxCancel()
 	<-as.done
+	<-as.queueDone
 	return nil
 }
 
@@ -406,17 +487,15 @@ outer:
 				// remove expired goals
 				now := time.Now()
 				for id, gh := range as.goals {
-					if now.Sub(gh.created) >= as.conf.DeleteGoalAfter {
+					if !gh.doneTime.IsZero() && now.Sub(gh.doneTime) >= as.conf.DeleteGoalAfter {
 						delete(as.goals, id)
 					}
 				}
 
 				var ret []actionlib_msgs.GoalStatus
-				for id, gh := range as.goals {
+				for _, gh := range as.goals {
 					ret = append(ret, actionlib_msgs.GoalStatus{
-						GoalId: actionlib_msgs.GoalID{
-							Id: id,
-						},
+						GoalId: gh.goalID(),
 						Status: uint8(gh.state),
 					})
 				}
@@ -446,6 +525,86 @@ outer:
 	as.statusPub.Close()
 }
 
+// runQueue passes queued goals to OnGoal when the current goal is done.
+// It runs in a dedicated routine, like the goal subscriber does for goals
+// that are not queued, in order not to block the publishing of statuses.
+func (as *ActionServer) runQueue() {
+	defer close(as.queueDone)
+
+	for {
+		select {
+		case <-as.goalDone:
+			as.dispatchQueued()
+
+		case <-as.ctx.Done():
+			return
+		}
+	}
+}
+
+func (as *ActionServer) emptyResult() interface{} {
+	return reflect.New(as.resType).Interface()
+}
+
+// busy returns whether there's a goal that has been passed to OnGoal and
+// is not in a terminal state yet.
+func (as *ActionServer) busy() bool {
+	for _, gh := range as.goals {
+		if gh.delivered && gh.doneTime.IsZero() {
+			return true
+		}
+	}
+	return false
+}
+
+func (as *ActionServer) removeFromQueue(gh *ActionServerGoalHandler) bool {
+	for i, qgh := range as.queue {
+		if qgh == gh {
+			as.queue = append(as.queue[:i], as.queue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (as *ActionServer) callOnGoal(gh *ActionServerGoalHandler) {
+	if as.conf.OnGoal != nil {
+		reflect.ValueOf(as.conf.OnGoal).Call([]reflect.Value{
+			reflect.ValueOf(gh),
+			gh.goal,
+		})
+	}
+}
+
+func (as *ActionServer) callOnCancel(ghs []*ActionServerGoalHandler) {
+	if as.conf.OnCancel != nil {
+		for _, gh := range ghs {
+			as.conf.OnCancel(gh)
+		}
+	}
+}
+
+func (as *ActionServer) dispatchQueued() {
+	gh := func() *ActionServerGoalHandler {
+		as.mutex.Lock()
+		defer as.mutex.Unlock()
+
+		if len(as.queue) == 0 || as.busy() {
+			return nil
+		}
+
+		gh := as.queue[0]
+		as.queue = as.queue[1:]
+		gh.delivered = true
+		return gh
+	}()
+	if gh == nil {
+		return
+	}
+
+	as.callOnGoal(gh)
+}
+
 func (as *ActionServer) onGoal(in []reflect.Value) []reflect.Value {
 	msg := in[0]
 
@@ -453,65 +612,126 @@ func (as *ActionServer) onGoal(in []reflect.Value) []reflect.Value {
 		Interface().(actionlib_msgs.GoalID)
 	goal := msg.Elem().FieldByName("Goal")
 
-	gh := &ActionServerGoalHandler{
-		as:      as,
-		id:      goalID.Id,
-		created: time.Now(),
-	}
-
-	func() {
+	toCancel, toDeliver := func() ([]*ActionServerGoalHandler, *ActionServerGoalHandler) {
 		as.mutex.Lock()
 		defer as.mutex.Unlock()
 
+		if gh, ok := as.goals[goalID.Id]; ok {
+			// a cancellation request arrived before the goal
+			if !gh.delivered && gh.state == ActionServerGoalStateRecalling {
+				gh.stamp = goalID.Stamp
+				gh.state = ActionServerGoalStateRecalled
+				gh.publishResult(as.emptyResult())
+			}
+			return nil, nil
+		}
+
+		gh := &ActionServerGoalHandler{
+			as:    as,
+			id:    goalID.Id,
+			stamp: goalID.Stamp,
+			goal:  goal.Addr(),
+		}
 		as.goals[goalID.Id] = gh
+
+		// the goal has been canceled by a cancellation request with a stamp
+		if !goalID.Stamp.IsZero() && !goalID.Stamp.After(as.lastCancel) {
+			gh.state = ActionServerGoalStateRecalled
+			gh.publishResult(as.emptyResult())
+			return nil, nil
+		}
+
+		var toCancel []*ActionServerGoalHandler
+
+		switch as.conf.GoalPolicy {
+		case ActionServerGoalPolicySingle:
+			if as.busy() {
+				gh.state = ActionServerGoalStateRejected
+				gh.publishResult(as.emptyResult())
+				return nil, nil
+			}
+
+		case ActionServerGoalPolicyQueue:
+			if as.busy() || len(as.queue) != 0 {
+				as.queue = append(as.queue, gh)
+				return nil, nil
+			}
+
+		case ActionServerGoalPolicyPreempt:
+			for _, ogh := range as.goals {
+				if ogh.delivered && ogh.requestCancel() {
+					toCancel = append(toCancel, ogh)
+				}
+			}
+		}
+
+		gh.delivered = true
+		return toCancel, gh
 	}()
 
-	if as.conf.OnGoal != nil {
-		reflect.ValueOf(as.conf.OnGoal).Call([]reflect.Value{
-			reflect.ValueOf(gh),
-			goal.Addr(),
-		})
+	as.callOnCancel(toCancel)
+
+	if toDeliver != nil {
+		as.callOnGoal(toDeliver)
 	}
 
 	return []reflect.Value{}
 }
 
 func (as *ActionServer) onCancel(msg *actionlib_msgs.GoalID) {
-	if msg.Id == "" { // cancel all goals
-		goals := func() []*ActionServerGoalHandler {
-			as.mutex.Lock()
-			defer as.mutex.Unlock()
+	toCancel := func() []*ActionServerGoalHandler {
+		as.mutex.Lock()
+		defer as.mutex.Unlock()
 
-			var ret []*ActionServerGoalHandler
-			for _, gh := range as.goals {
-				ret = append(ret, gh)
+		// goals with a stamp lower or equal than this one will be canceled
+		// as soon as they arrive
+		if msg.Stamp.After(as.lastCancel) {
+			as.lastCancel = msg.Stamp
+		}
+
+		cancelAll := (msg.Id == "" && msg.Stamp.IsZero())
+		found := false
+		var ret []*ActionServerGoalHandler
+
+		for _, gh := range as.goals {
+			if !cancelAll && gh.id != msg.Id &&
+				(msg.Stamp.IsZero() || gh.stamp.After(msg.Stamp)) {
+				continue
 			}
-			return ret
-		}()
 
-		for _, gh := range goals {
-			if as.conf.OnCancel != nil {
-				as.conf.OnCancel(gh)
+			if gh.id == msg.Id {
+				found = true
 			}
-		}
 
-	} else { // cancel specific goal
-		gh := func() *ActionServerGoalHandler {
-			as.mutex.Lock()
-			defer as.mutex.Unlock()
+			// goals that have not been passed to OnGoal yet can be
+			// recalled immediately
+			if !gh.delivered {
+				if as.removeFromQueue(gh) {
+					gh.state = ActionServerGoalStateRecalled
+					gh.publishResult(as.emptyResult())
+				}
+				continue
+			}
 
-			gh, ok := as.goals[msg.Id]
-			if !ok {
-				return nil
+			if gh.requestCancel() {
+				ret = append(ret, gh)
 			}
-			return gh
-		}()
-		if gh == nil {
-			return
 		}
 
-		if as.conf.OnCancel != nil {
-			as.conf.OnCancel(gh)
+		// the goal has not arrived yet. Store the cancellation request, in order
+		// to recall the goal when it arrives.
+		if msg.Id != "" && !found {
+			as.goals[msg.Id] = &ActionServerGoalHandler{
+				as:       as,
+				id:       msg.Id,
+				stamp:    msg.Stamp,
+				state:    ActionServerGoalStateRecalling,
+				doneTime: time.Now(),
+			}
 		}
-	}
+
+		return ret
+	}()
+
+	as.callOnCancel(toCancel)
 }
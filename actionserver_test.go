@@ -1,6 +1,7 @@
 package goroslib
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -121,3 +122,153 @@ func TestActionServer(t *testing.T) {
 		})
 	}
 }
+
+func TestActionServerGoalPolicy(t *testing.T) {
+	for _, ca := range []struct {
+		name   string
+		policy ActionServerGoalPolicy
+		first  ActionClientTerminalState
+		second ActionClientTerminalState
+	}{
+		{
+			"single",
+			ActionServerGoalPolicySingle,
+			ActionClientTerminalStateSucceeded,
+			ActionClientTerminalStateRejected,
+		},
+		{
+			"queue",
+			ActionServerGoalPolicyQueue,
+			ActionClientTerminalStateSucceeded,
+			ActionClientTerminalStateSucceeded,
+		},
+		{
+			"preempt",
+			ActionServerGoalPolicyPreempt,
+			ActionClientTerminalStatePreempted,
+			ActionClientTerminalStateSucceeded,
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			m, err := newContainerMaster()
+			require.NoError(t, err)
+			defer m.close()
+
+			n, err := NewNode(NodeConf{
+				Namespace:     "/myns",
+				Name:          "goroslib",
+				MasterAddress: m.IP() + ":11311",
+			})
+			require.NoError(t, err)
+			defer n.Close()
+
+			as, err := NewActionServer(ActionServerConf{
+				Node:       n,
+				Name:       "test_action",
+				Action:     &DoSomethingAction{},
+				GoalPolicy: ca.policy,
+				OnGoal: func(gh *ActionServerGoalHandler, goal *DoSomethingActionGoal) {
+					gh.SetAccepted()
+
+					go func() {
+						time.Sleep(500 * time.Millisecond)
+						gh.SetSucceeded(&DoSomethingActionResult{})
+					}()
+				},
+				OnCancel: func(gh *ActionServerGoalHandler) {
+					require.Equal(t, ActionServerGoalStatePreempting, gh.State())
+					gh.SetCanceled(&DoSomethingActionResult{})
+				},
+			})
+			require.NoError(t, err)
+			defer as.Close()
+
+			ac, err := NewActionClient(ActionClientConf{
+				Node:   n,
+				Name:   "test_action",
+				Action: &DoSomethingAction{},
+			})
+			require.NoError(t, err)
+			defer ac.Close()
+
+			ac.WaitForServer()
+
+			type result struct {
+				ts  ActionClientTerminalState
+				err error
+			}
+			firstDone := make(chan result)
+
+			go func() {
+				_, ts, err := ac.SendGoalAndWait(context.Background(), &DoSomethingActionGoal{})
+				firstDone <- result{ts, err}
+			}()
+
+			time.Sleep(200 * time.Millisecond)
+
+			_, ts, err := ac.SendGoalAndWait(context.Background(), &DoSomethingActionGoal{})
+			require.NoError(t, err)
+			require.Equal(t, ca.second, ts)
+
+			res := <-firstDone
+			require.NoError(t, res.err)
+			require.Equal(t, ca.first, res.ts)
+		})
+	}
+}
+
+func TestActionServerCancelByStamp(t *testing.T) {
+	m, err := newContainerMaster()
+	require.NoError(t, err)
+	defer m.close()
+
+	n, err := NewNode(NodeConf{
+		Namespace:     "/myns",
+		Name:          "goroslib",
+		MasterAddress: m.IP() + ":11311",
+	})
+	require.NoError(t, err)
+	defer n.Close()
+
+	as, err := NewActionServer(ActionServerConf{
+		Node:   n,
+		Name:   "test_action",
+		Action: &DoSomethingAction{},
+		OnCancel: func(gh *ActionServerGoalHandler) {
+			require.Equal(t, ActionServerGoalStateRecalling, gh.State())
+			gh.SetCanceled(&DoSomethingActionResult{})
+		},
+	})
+	require.NoError(t, err)
+	defer as.Close()
+
+	ac, err := NewActionClient(ActionClientConf{
+		Node:   n,
+		Name:   "test_action",
+		Action: &DoSomethingAction{},
+	})
+	require.NoError(t, err)
+	defer ac.Close()
+
+	ac.WaitForServer()
+
+	done := make(chan ActionClientTerminalState)
+
+	_, err = ac.SendGoal(ActionClientGoalConf{
+		Goal: &DoSomethingActionGoal{},
+		OnTransition: func(gh *ActionClientGoalHandler, res *DoSomethingActionResult) {
+			if gh.CommState() == ActionClientCommStateDone {
+				ts, _ := gh.TerminalState()
+				done <- ts
+			}
+		},
+	})
+	require.NoError(t, err)
+
+	time.Sleep(200 * time.Millisecond)
+
+	// cancel all goals sent before now
+	ac.CancelGoalsAtAndBeforeTime(time.Now())
+
+	require.Equal(t, ActionClientTerminalStateRecalled, <-done)
+}
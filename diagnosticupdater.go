@@ -0,0 +1,613 @@
+package goroslib
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aler9/goroslib/pkg/msgs/diagnostic_msgs"
+	"github.com/aler9/goroslib/pkg/msgs/std_msgs"
+)
+
+// DiagnosticStatus is the status filled by a diagnostic task.
+type DiagnosticStatus struct {
+	Level   int8
+	Message string
+	Values  []diagnostic_msgs.KeyValue
+}
+
+// Summary sets the level and the message of the status.
+func (s *DiagnosticStatus) Summary(level int8, message string) {
+	s.Level = level
+	s.Message = message
+}
+
+// MergeSummary merges a level and a message into the status.
+// The resulting level is the highest one, while messages of the same severity
+// are concatenated.
+func (s *DiagnosticStatus) MergeSummary(level int8, message string) {
+	// https://github.com/ros/diagnostics/blob/noetic-devel/diagnostic_updater/include/diagnostic_updater/DiagnosticStatusWrapper.h#L116
+	switch {
+	case (level > 0) == (s.Level > 0):
+		if s.Message != "" {
+			s.Message += "; "
+		}
+		s.Message += message
+
+	case level > s.Level:
+		s.Message = message
+	}
+
+	if level > s.Level {
+		s.Level = level
+	}
+}
+
+// Add adds a key-value pair to the status.
+func (s *DiagnosticStatus) Add(key string, value interface{}) {
+	s.Values = append(s.Values, diagnostic_msgs.KeyValue{
+		Key:   key,
+		Value: fmt.Sprint(value),
+	})
+}
+
+type diagnosticUpdaterTask struct {
+	name string
+	cb   func(*DiagnosticStatus)
+}
+
+// DiagnosticUpdaterConf is the configuration of a DiagnosticUpdater.
+type DiagnosticUpdaterConf struct {
+	// parent node.
+	Node *Node
+
+	// (optional) hardware ID that is attached to every status.
+	HardwareID string
+
+	// (optional) diagnostics are published with this period.
+	// It defaults to 1 sec.
+	Period time.Duration
+}
+
+// DiagnosticUpdater is an entity that periodically collects the status of
+// a set of named tasks and publishes it to /diagnostics.
+type DiagnosticUpdater struct {
+	conf DiagnosticUpdaterConf
+
+	ctx       context.Context
+	ctxCancel func()
+	pub       *Publisher
+	mutex     sync.Mutex
+	tasks     []*diagnosticUpdaterTask
+
+	// out
+	done chan struct{}
+}
+
+// NewDiagnosticUpdater allocates a DiagnosticUpdater. See DiagnosticUpdaterConf for the options.
+func NewDiagnosticUpdater(conf DiagnosticUpdaterConf) (*DiagnosticUpdater, error) {
+	if conf.Node == nil {
+		return nil, fmt.Errorf("Node is empty")
+	}
+
+	if conf.Period < 0 {
+		return nil, fmt.Errorf("Period must not be negative")
+	}
+
+	if conf.Period == 0 {
+		conf.Period = 1 * time.Second
+	}
+
+	pub, err := NewPublisher(PublisherConf{
+		Node:  conf.Node,
+		Topic: "/diagnostics",
+		Msg:   &diagnostic_msgs.DiagnosticArray{},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, ctxCancel := context.WithCancel(context.Background())
+
+	du := &DiagnosticUpdater{
+		conf:      conf,
+		ctx:       ctx,
+		ctxCancel: ctxCancel,
+		pub:       pub,
+		done:      make(chan struct{}),
+	}
+
+	go du.run()
+
+	return du, nil
+}
+
+// Close closes a DiagnosticUpdater and shuts down all its operations.
+func (du *DiagnosticUpdater) Close() error {
+	du.ctxCancel()
+	<-du.done
+	return nil
+}
+
+// Add adds a task. The callback is called periodically in order to fill the
+// status of the task.
+func (du *DiagnosticUpdater) Add(name string, cb func(*DiagnosticStatus)) error {
+	du.mutex.Lock()
+	defer du.mutex.Unlock()
+
+	for _, t := range du.tasks {
+		if t.name == name {
+			return fmt.Errorf("task '%s' already exists", name)
+		}
+	}
+
+	du.tasks = append(du.tasks, &diagnosticUpdaterTask{
+		name: name,
+		cb:   cb,
+	})
+	return nil
+}
+
+// Remove removes a task.
+func (du *DiagnosticUpdater) Remove(name string) {
+	du.mutex.Lock()
+	defer du.mutex.Unlock()
+
+	for i, t := range du.tasks {
+		if t.name == name {
+			du.tasks = append(du.tasks[:i], du.tasks[i+1:]...)
+			return
+		}
+	}
+}
+
+// Update collects and publishes diagnostics immediately.
+func (du *DiagnosticUpdater) Update() {
+	du.pub.Write(du.collect())
+}
+
+func (du *DiagnosticUpdater) collect() *diagnostic_msgs.DiagnosticArray {
+	du.mutex.Lock()
+	tasks := append([]*diagnosticUpdaterTask(nil), du.tasks...)
+	du.mutex.Unlock()
+
+	// status names are prefixed with the node name, like in the C++ implementation
+	prefix := strings.TrimPrefix(du.conf.Node.absoluteName(), "/") + ": "
+
+	statuses := make([]diagnostic_msgs.DiagnosticStatus, len(tasks))
+	for i, t := range tasks {
+		var st DiagnosticStatus
+		t.cb(&st)
+
+		statuses[i] = diagnostic_msgs.DiagnosticStatus{
+			Level:      st.Level,
+			Name:       prefix + t.name,
+			Message:    st.Message,
+			HardwareId: du.conf.HardwareID,
+			Values:     st.Values,
+		}
+	}
+
+	return &diagnostic_msgs.DiagnosticArray{
+		Header: std_msgs.Header{
+			Stamp: du.conf.Node.TimeNow(),
+		},
+		Status: statuses,
+	}
+}
+
+func (du *DiagnosticUpdater) run() {
+	defer close(du.done)
+
+	t := time.NewTicker(du.conf.Period)
+	defer t.Stop()
+
+outer:
+	for {
+		select {
+		case <-t.C:
+			du.Update()
+
+		case <-du.ctx.Done():
+			break outer
+		}
+	}
+
+	du.ctxCancel()
+
+	du.pub.Close()
+}
+
+// DiagnosticFrequencyStatusConf is the configuration of a DiagnosticFrequencyStatus.
+type DiagnosticFrequencyStatusConf struct {
+	// parent node.
+	Node *Node
+
+	// (optional) minimum acceptable frequency, in Hz.
+	// It defaults to 0.
+	MinFreq float64
+
+	// (optional) maximum acceptable frequency, in Hz.
+	// Zero means that the frequency is unbounded.
+	// It defaults to 0.
+	MaxFreq float64
+
+	// (optional) tolerance that is applied to the acceptable frequencies.
+	// It defaults to 0.1.
+	Tolerance *float64
+
+	// (optional) number of updates that are used to compute the frequency.
+	// It defaults to 5.
+	WindowSize int
+}
+
+type diagnosticFrequencyHistoryEntry struct {
+	count int
+	time  time.Time
+}
+
+// DiagnosticFrequencyStatus is a diagnostic task that checks whether
+// events happen with a frequency inside a given range.
+type DiagnosticFrequencyStatus struct {
+	conf      DiagnosticFrequencyStatusConf
+	maxFreq   float64
+	tolerance float64
+
+	mutex   sync.Mutex
+	count   int
+	history []diagnosticFrequencyHistoryEntry
+	histPos int
+}
+
+// NewDiagnosticFrequencyStatus allocates a DiagnosticFrequencyStatus.
+// See DiagnosticFrequencyStatusConf for the options.
+func NewDiagnosticFrequencyStatus(conf DiagnosticFrequencyStatusConf) (*DiagnosticFrequencyStatus, error) {
+	if conf.Node == nil {
+		return nil, fmt.Errorf("Node is empty")
+	}
+
+	if conf.MinFreq < 0 {
+		return nil, fmt.Errorf("MinFreq must not be negative")
+	}
+
+	maxFreq := conf.MaxFreq
+	if maxFreq == 0 {
+		maxFreq = math.Inf(1)
+	}
+
+	if maxFreq < conf.MinFreq {
+		return nil, fmt.Errorf("MaxFreq is lower than MinFreq")
+	}
+
+	tolerance := 0.1
+	if conf.Tolerance != nil {
+		if *conf.Tolerance < 0 {
+			return nil, fmt.Errorf("Tolerance must not be negative")
+		}
+		tolerance = *conf.Tolerance
+	}
+
+	if conf.WindowSize < 0 {
+		return nil, fmt.Errorf("WindowSize must not be negative")
+	}
+
+	if conf.WindowSize == 0 {
+		conf.WindowSize = 5
+	}
+
+	fs := &DiagnosticFrequencyStatus{
+		conf:      conf,
+		maxFreq:   maxFreq,
+		tolerance: tolerance,
+	}
+	fs.Clear()
+
+	return fs, nil
+}
+
+// Clear resets the statistics.
+func (fs *DiagnosticFrequencyStatus) Clear() {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	now := fs.conf.Node.TimeNow()
+
+	fs.count = 0
+	fs.histPos = 0
+	fs.history = make([]diagnosticFrequencyHistoryEntry, fs.conf.WindowSize)
+	for i := range fs.history {
+		fs.history[i] = diagnosticFrequencyHistoryEntry{0, now}
+	}
+}
+
+// Tick signals that an event has happened.
+func (fs *DiagnosticFrequencyStatus) Tick() {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	fs.count++
+}
+
+// Run fills a status. It can be passed to DiagnosticUpdater.Add().
+func (fs *DiagnosticFrequencyStatus) Run(st *DiagnosticStatus) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	// https://github.com/ros/diagnostics/blob/noetic-devel/diagnostic_updater/include/diagnostic_updater/update_functions.h#L149
+
+	now := fs.conf.Node.TimeNow()
+	events := fs.count - fs.history[fs.histPos].count
+	window := now.Sub(fs.history[fs.histPos].time).Seconds()
+
+	// the window is empty when the clock didn't advance, i.e. when
+	// the simulated time is paused, and the frequency can't be computed.
+	freq := float64(0)
+	if window > 0 {
+		freq = float64(events) / window
+	}
+
+	fs.history[fs.histPos] = diagnosticFrequencyHistoryEntry{fs.count, now}
+	fs.histPos = (fs.histPos + 1) % fs.conf.WindowSize
+
+	switch {
+	case events == 0:
+		st.Summary(diagnostic_msgs.DiagnosticStatus_ERROR, "No events recorded.")
+
+	case window <= 0:
+		st.Summary(diagnostic_msgs.DiagnosticStatus_WARN, "Frequency unknown.")
+
+	case freq < fs.conf.MinFreq*(1-fs.tolerance):
+		st.Summary(diagnostic_msgs.DiagnosticStatus_WARN, "Frequency too low.")
+
+	case freq > fs.maxFreq*(1+fs.tolerance):
+		st.Summary(diagnostic_msgs.DiagnosticStatus_WARN, "Frequency too high.")
+
+	default:
+		st.Summary(diagnostic_msgs.DiagnosticStatus_OK, "Desired frequency met")
+	}
+
+	st.Add("Events in window", events)
+	st.Add("Events since startup", fs.count)
+	st.Add("Duration of window (s)", window)
+	if window > 0 {
+		st.Add("Actual frequency (Hz)", freq)
+	} else {
+		st.Add("Actual frequency (Hz)", "unknown")
+	}
+
+	if fs.conf.MinFreq == fs.maxFreq {
+		st.Add("Target frequency (Hz)", fs.conf.MinFreq)
+	}
+	if fs.conf.MinFreq > 0 {
+		st.Add("Minimum acceptable frequency (Hz)", fs.conf.MinFreq*(1-fs.tolerance))
+	}
+	if !math.IsInf(fs.maxFreq, 1) {
+		st.Add("Maximum acceptable frequency (Hz)", fs.maxFreq*(1+fs.tolerance))
+	}
+}
+
+// DiagnosticTimestampStatusConf is the configuration of a DiagnosticTimestampStatus.
+type DiagnosticTimestampStatusConf struct {
+	// parent node.
+	Node *Node
+
+	// (optional) minimum acceptable difference between the current time
+	// and a timestamp. A negative value allows timestamps in the future.
+	// It defaults to -1 sec.
+	MinDelay *time.Duration
+
+	// (optional) maximum acceptable difference between the current time
+	// and a timestamp.
+	// It defaults to 5 secs.
+	MaxDelay *time.Duration
+}
+
+// DiagnosticTimestampStatus is a diagnostic task that checks whether
+// timestamps are within a given delay from the current time.
+type DiagnosticTimestampStatus struct {
+	conf               DiagnosticTimestampStatusConf
+	minAcceptableDelay time.Duration
+	maxAcceptableDelay time.Duration
+
+	mutex      sync.Mutex
+	count      int
+	minDelay   time.Duration
+	maxDelay   time.Duration
+	zeroSeen   bool
+	earlyCount int
+	lateCount  int
+	zeroCount  int
+}
+
+// NewDiagnosticTimestampStatus allocates a DiagnosticTimestampStatus.
+// See DiagnosticTimestampStatusConf for the options.
+func NewDiagnosticTimestampStatus(conf DiagnosticTimestampStatusConf) (*DiagnosticTimestampStatus, error) {
+	if conf.Node == nil {
+		return nil, fmt.Errorf("Node is empty")
+	}
+
+	minDelay := -1 * time.Second
+	if conf.MinDelay != nil {
+		minDelay = *conf.MinDelay
+	}
+
+	maxDelay := 5 * time.Second
+	if conf.MaxDelay != nil {
+		maxDelay = *conf.MaxDelay
+	}
+
+	if maxDelay < minDelay {
+		return nil, fmt.Errorf("MaxDelay is lower than MinDelay")
+	}
+
+	return &DiagnosticTimestampStatus{
+		conf:               conf,
+		minAcceptableDelay: minDelay,
+		maxAcceptableDelay: maxDelay,
+	}, nil
+}
+
+// Tick signals that an event with the given timestamp has happened.
+func (ts *DiagnosticTimestampStatus) Tick(stamp time.Time) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	if stamp.IsZero() || stamp.Equal(time.Unix(0, 0)) {
+		ts.zeroSeen = true
+		return
+	}
+
+	delay := ts.conf.Node.TimeNow().Sub(stamp)
+
+	if ts.count == 0 || delay < ts.minDelay {
+		ts.minDelay = delay
+	}
+	if ts.count == 0 || delay > ts.maxDelay {
+		ts.maxDelay = delay
+	}
+	ts.count++
+}
+
+// Run fills a status. It can be passed to DiagnosticUpdater.Add().
+func (ts *DiagnosticTimestampStatus) Run(st *DiagnosticStatus) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	// https://github.com/ros/diagnostics/blob/noetic-devel/diagnostic_updater/include/diagnostic_updater/update_functions.h#L319
+
+	st.Summary(diagnostic_msgs.DiagnosticStatus_OK, "Timestamps are reasonable.")
+
+	if ts.count == 0 {
+		st.Summary(diagnostic_msgs.DiagnosticStatus_WARN, "No data since last update.")
+	} else {
+		if ts.minDelay < ts.minAcceptableDelay {
+			st.Summary(diagnostic_msgs.DiagnosticStatus_ERROR, "Timestamps too far in future seen.")
+			ts.earlyCount++
+		}
+
+		if ts.maxDelay > ts.maxAcceptableDelay {
+			st.Summary(diagnostic_msgs.DiagnosticStatus_ERROR, "Timestamps too far in past seen.")
+			ts.lateCount++
+		}
+
+		if ts.zeroSeen {
+			st.Summary(diagnostic_msgs.DiagnosticStatus_ERROR, "Zero timestamp seen.")
+			ts.zeroCount++
+		}
+	}
+
+	st.Add("Earliest timestamp delay:", ts.minDelay.Seconds())
+	st.Add("Latest timestamp delay:", ts.maxDelay.Seconds())
+	st.Add("Earliest acceptable timestamp delay:", ts.minAcceptableDelay.Seconds())
+	st.Add("Latest acceptable timestamp delay:", ts.maxAcceptableDelay.Seconds())
+	st.Add("Late diagnostic update count:", ts.lateCount)
+	st.Add("Early diagnostic update count:", ts.earlyCount)
+	st.Add("Zero seen diagnostic count:", ts.zeroCount)
+
+	ts.count = 0
+	ts.minDelay = 0
+	ts.maxDelay = 0
+	ts.zeroSeen = false
+}
+
+// DiagnosticTopicMonitorConf is the configuration of a DiagnosticTopicMonitor.
+type DiagnosticTopicMonitorConf struct {
+	// parent updater.
+	Updater *DiagnosticUpdater
+
+	// name of the monitored topic.
+	Topic string
+
+	// configuration of the frequency check.
+	// Node is filled automatically.
+	Frequency DiagnosticFrequencyStatusConf
+
+	// (optional) configuration of the timestamp check.
+	// If not provided, timestamps are not checked.
+	// Node is filled automatically.
+	Timestamp *DiagnosticTimestampStatusConf
+}
+
+// DiagnosticTopicMonitor is an entity that monitors the frequency and
+// the timestamps of the messages of a topic, and reports them to a DiagnosticUpdater.
+type DiagnosticTopicMonitor struct {
+	conf DiagnosticTopicMonitorConf
+
+	name string
+	fs   *DiagnosticFrequencyStatus
+	ts   *DiagnosticTimestampStatus
+}
+
+// NewDiagnosticTopicMonitor allocates a DiagnosticTopicMonitor.
+// See DiagnosticTopicMonitorConf for the options.
+func NewDiagnosticTopicMonitor(conf DiagnosticTopicMonitorConf) (*DiagnosticTopicMonitor, error) {
+	if conf.Updater == nil {
+		return nil, fmt.Errorf("Updater is empty")
+	}
+
+	if conf.Topic == "" {
+		return nil, fmt.Errorf("Topic is empty")
+	}
+
+	tm := &DiagnosticTopicMonitor{
+		conf: conf,
+		name: conf.Topic + " topic status",
+	}
+
+	fconf := conf.Frequency
+	fconf.Node = conf.Updater.conf.Node
+	var err error
+	tm.fs, err = NewDiagnosticFrequencyStatus(fconf)
+	if err != nil {
+		return nil, err
+	}
+
+	if conf.Timestamp != nil {
+		tconf := *conf.Timestamp
+		tconf.Node = conf.Updater.conf.Node
+		tm.ts, err = NewDiagnosticTimestampStatus(tconf)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = conf.Updater.Add(tm.name, tm.run)
+	if err != nil {
+		return nil, err
+	}
+
+	return tm, nil
+}
+
+// Close removes the monitor from the updater.
+func (tm *DiagnosticTopicMonitor) Close() error {
+	tm.conf.Updater.Remove(tm.name)
+	return nil
+}
+
+// Tick signals that a message with the given timestamp has been received.
+// The timestamp is ignored when timestamps are not checked.
+func (tm *DiagnosticTopicMonitor) Tick(stamp time.Time) {
+	tm.fs.Tick()
+
+	if tm.ts != nil {
+		tm.ts.Tick(stamp)
+	}
+}
+
+func (tm *DiagnosticTopicMonitor) run(st *DiagnosticStatus) {
+	var fst DiagnosticStatus
+	tm.fs.Run(&fst)
+	st.MergeSummary(fst.Level, fst.Message)
+	st.Values = append(st.Values, fst.Values...)
+
+	if tm.ts != nil {
+		var tst DiagnosticStatus
+		tm.ts.Run(&tst)
+		st.MergeSummary(tst.Level, tst.Message)
+		st.Values = append(st.Values, tst.Values...)
+	}
+}
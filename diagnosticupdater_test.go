@@ -0,0 +1,252 @@
+package goroslib
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/aler9/goroslib/pkg/msgs/diagnostic_msgs"
+)
+
+func TestDiagnosticStatusMergeSummary(t *testing.T) {
+	var st DiagnosticStatus
+	st.MergeSummary(diagnostic_msgs.DiagnosticStatus_OK, "first")
+	st.MergeSummary(diagnostic_msgs.DiagnosticStatus_OK, "second")
+	require.Equal(t, DiagnosticStatus{
+		Level:   diagnostic_msgs.DiagnosticStatus_OK,
+		Message: "first; second",
+	}, st)
+
+	st.MergeSummary(diagnostic_msgs.DiagnosticStatus_ERROR, "third")
+	st.MergeSummary(diagnostic_msgs.DiagnosticStatus_OK, "fourth")
+	st.MergeSummary(diagnostic_msgs.DiagnosticStatus_WARN, "fifth")
+	require.Equal(t, DiagnosticStatus{
+		Level:   diagnostic_msgs.DiagnosticStatus_ERROR,
+		Message: "third; fifth",
+	}, st)
+}
+
+func TestDiagnosticFrequencyStatusEmptyWindow(t *testing.T) {
+	// simulated time that doesn't advance
+	n := &Node{
+		simtimeEnabled: true,
+		simtimeValue:   time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	fs, err := NewDiagnosticFrequencyStatus(DiagnosticFrequencyStatusConf{
+		Node:    n,
+		MinFreq: 1,
+		MaxFreq: 10,
+	})
+	require.NoError(t, err)
+
+	fs.Tick()
+
+	var st DiagnosticStatus
+	fs.Run(&st)
+	require.Equal(t, diagnostic_msgs.DiagnosticStatus_WARN, st.Level)
+	require.Equal(t, "Frequency unknown.", st.Message)
+	require.Contains(t, st.Values, diagnostic_msgs.KeyValue{Key: "Actual frequency (Hz)", Value: "unknown"})
+
+	_, err = NewDiagnosticFrequencyStatus(DiagnosticFrequencyStatusConf{
+		Node:       n,
+		WindowSize: -1,
+	})
+	require.EqualError(t, err, "WindowSize must not be negative")
+}
+
+func TestDiagnosticConfErrors(t *testing.T) {
+	n := &Node{}
+
+	_, err := NewDiagnosticUpdater(DiagnosticUpdaterConf{
+		Node:   n,
+		Period: -1 * time.Second,
+	})
+	require.EqualError(t, err, "Period must not be negative")
+
+	_, err = NewDiagnosticFrequencyStatus(DiagnosticFrequencyStatusConf{
+		Node:    n,
+		MinFreq: -1,
+	})
+	require.EqualError(t, err, "MinFreq must not be negative")
+
+	_, err = NewDiagnosticFrequencyStatus(DiagnosticFrequencyStatusConf{
+		Node:    n,
+		MinFreq: 10,
+		MaxFreq: 1,
+	})
+	require.EqualError(t, err, "MaxFreq is lower than MinFreq")
+
+	tolerance := -0.1
+	_, err = NewDiagnosticFrequencyStatus(DiagnosticFrequencyStatusConf{
+		Node:      n,
+		Tolerance: &tolerance,
+	})
+	require.EqualError(t, err, "Tolerance must not be negative")
+
+	minDelay := 10 * time.Second
+	_, err = NewDiagnosticTimestampStatus(DiagnosticTimestampStatusConf{
+		Node:     n,
+		MinDelay: &minDelay,
+	})
+	require.EqualError(t, err, "MaxDelay is lower than MinDelay")
+}
+
+func TestDiagnosticFrequencyStatusUnbounded(t *testing.T) {
+	n := &Node{
+		simtimeEnabled: true,
+		simtimeValue:   time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	tolerance := float64(0)
+	fs, err := NewDiagnosticFrequencyStatus(DiagnosticFrequencyStatusConf{
+		Node:      n,
+		MinFreq:   1,
+		Tolerance: &tolerance,
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 1000; i++ {
+		fs.Tick()
+	}
+	n.simtimeValue = n.simtimeValue.Add(1 * time.Second)
+
+	var st DiagnosticStatus
+	fs.Run(&st)
+	require.Equal(t, diagnostic_msgs.DiagnosticStatus_OK, st.Level)
+	require.Equal(t, "Desired frequency met", st.Message)
+	require.Contains(t, st.Values, diagnostic_msgs.KeyValue{Key: "Minimum acceptable frequency (Hz)", Value: "1"})
+	for _, v := range st.Values {
+		require.NotEqual(t, "Maximum acceptable frequency (Hz)", v.Key)
+	}
+}
+
+func TestDiagnosticTimestampStatusZeroDelay(t *testing.T) {
+	n := &Node{
+		simtimeEnabled: true,
+		simtimeValue:   time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	minDelay := time.Duration(0)
+	ts, err := NewDiagnosticTimestampStatus(DiagnosticTimestampStatusConf{
+		Node:     n,
+		MinDelay: &minDelay,
+	})
+	require.NoError(t, err)
+
+	ts.Tick(n.simtimeValue.Add(500 * time.Millisecond))
+
+	var st DiagnosticStatus
+	ts.Run(&st)
+	require.Equal(t, diagnostic_msgs.DiagnosticStatus_ERROR, st.Level)
+	require.Equal(t, "Timestamps too far in future seen.", st.Message)
+	require.Contains(t, st.Values, diagnostic_msgs.KeyValue{Key: "Earliest acceptable timestamp delay:", Value: "0"})
+}
+
+func TestDiagnosticUpdater(t *testing.T) {
+	m, err := newContainerMaster()
+	require.NoError(t, err)
+	defer m.close()
+
+	n, err := NewNode(NodeConf{
+		Namespace:     "/myns",
+		Name:          "goroslib",
+		MasterAddress: m.IP() + ":11311",
+	})
+	require.NoError(t, err)
+	defer n.Close()
+
+	du, err := NewDiagnosticUpdater(DiagnosticUpdaterConf{
+		Node:       n,
+		HardwareID: "myhardware",
+		Period:     500 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer du.Close()
+
+	err = du.Add("mytask", func(st *DiagnosticStatus) {
+		st.Summary(diagnostic_msgs.DiagnosticStatus_WARN, "mymessage")
+		st.Add("mykey", 123)
+	})
+	require.NoError(t, err)
+
+	err = du.Add("mytask", func(st *DiagnosticStatus) {})
+	require.EqualError(t, err, "task 'mytask' already exists")
+
+	tm, err := NewDiagnosticTopicMonitor(DiagnosticTopicMonitorConf{
+		Updater: du,
+		Topic:   "/mytopic",
+		Frequency: DiagnosticFrequencyStatusConf{
+			MinFreq: 1,
+			MaxFreq: 1000,
+		},
+		Timestamp: &DiagnosticTimestampStatusConf{},
+	})
+	require.NoError(t, err)
+	defer tm.Close()
+
+	tickerDone := make(chan struct{})
+	defer func() { <-tickerDone }()
+	tickerTerminate := make(chan struct{})
+	defer close(tickerTerminate)
+
+	go func() {
+		defer close(tickerDone)
+
+		t := time.NewTicker(20 * time.Millisecond)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-t.C:
+				tm.Tick(time.Now())
+			case <-tickerTerminate:
+				return
+			}
+		}
+	}()
+
+	recv := make(chan *diagnostic_msgs.DiagnosticArray, 10)
+
+	sub, err := NewSubscriber(SubscriberConf{
+		Node:  n,
+		Topic: "/diagnostics",
+		Callback: func(msg *diagnostic_msgs.DiagnosticArray) {
+			select {
+			case recv <- msg:
+			default:
+			}
+		},
+	})
+	require.NoError(t, err)
+	defer sub.Close()
+
+	// skip first messages, in which the frequency window is not full
+	var msg *diagnostic_msgs.DiagnosticArray
+	for i := 0; i < 3; i++ {
+		msg = <-recv
+	}
+
+	require.Equal(t, 2, len(msg.Status))
+
+	require.Equal(t, diagnostic_msgs.DiagnosticStatus_WARN, msg.Status[0].Level)
+	require.Equal(t, "myns/goroslib: mytask", msg.Status[0].Name)
+	require.Equal(t, "mymessage", msg.Status[0].Message)
+	require.Equal(t, "myhardware", msg.Status[0].HardwareId)
+	require.Equal(t, []diagnostic_msgs.KeyValue{{Key: "mykey", Value: "123"}}, msg.Status[0].Values)
+
+	require.Equal(t, diagnostic_msgs.DiagnosticStatus_OK, msg.Status[1].Level)
+	require.Equal(t, "myns/goroslib: /mytopic topic status", msg.Status[1].Name)
+	require.Equal(t, "Desired frequency met; Timestamps are reasonable.", msg.Status[1].Message)
+
+	du.Remove("mytask")
+	tm.Close()
+
+	for {
+		msg = <-recv
+		if len(msg.Status) == 0 {
+			break
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"time"
+
+	"github.com/aler9/goroslib"
+	"github.com/aler9/goroslib/pkg/msgs/diagnostic_msgs"
+)
+
+func main() {
+	// create a node and connect to the master
+	n, err := goroslib.NewNode(goroslib.NodeConf{
+		Name:          "goroslib_diag",
+		MasterAddress: "127.0.0.1:11311",
+	})
+	if err != nil {
+		panic(err)
+	}
+	defer n.Close()
+
+	// create a diagnostic updater, that publishes to /diagnostics
+	du, err := goroslib.NewDiagnosticUpdater(goroslib.DiagnosticUpdaterConf{
+		Node:       n,
+		HardwareID: "myhardware",
+	})
+	if err != nil {
+		panic(err)
+	}
+	defer du.Close()
+
+	// add a task
+	err = du.Add("battery", func(st *goroslib.DiagnosticStatus) {
+		st.Summary(diagnostic_msgs.DiagnosticStatus_OK, "battery is charged")
+		st.Add("voltage", 12.4)
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	// monitor the frequency of a topic
+	tm, err := goroslib.NewDiagnosticTopicMonitor(goroslib.DiagnosticTopicMonitorConf{
+		Updater: du,
+		Topic:   "test_topic",
+		Frequency: goroslib.DiagnosticFrequencyStatusConf{
+			MinFreq: 9,
+			MaxFreq: 11,
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+	defer tm.Close()
+
+	// simulate the publishing of a message every 100ms
+	r := n.TimeRate(100 * time.Millisecond)
+
+	for {
+		tm.Tick(n.TimeNow())
+		r.Sleep()
+	}
+}
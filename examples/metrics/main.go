@@ -0,0 +1,53 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/aler9/goroslib"
+	"github.com/aler9/goroslib/pkg/msgs/geometry_msgs"
+	"github.com/aler9/goroslib/pkg/msgs/sensor_msgs"
+)
+
+func main() {
+	// create a node and connect to the master
+	n, err := goroslib.NewNode(goroslib.NodeConf{
+		Name:          "goroslib_metrics",
+		MasterAddress: "127.0.0.1:11311",
+	})
+	if err != nil {
+		panic(err)
+	}
+	defer n.Close()
+
+	// create a publisher
+	pub, err := goroslib.NewPublisher(goroslib.PublisherConf{
+		Node:  n,
+		Topic: "test_topic",
+		Msg:   &sensor_msgs.Imu{},
+	})
+	if err != nil {
+		panic(err)
+	}
+	defer pub.Close()
+
+	// export metrics in the Prometheus format on http://localhost:9090/metrics
+	go func() {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", n.MetricsHandler())
+		log.Println(http.ListenAndServe(":9090", mux))
+	}()
+
+	r := n.TimeRate(1 * time.Second)
+
+	for {
+		r.Sleep()
+
+		pub.Write(&sensor_msgs.Imu{
+			AngularVelocity: geometry_msgs.Vector3{
+				X: 23.5,
+			},
+		})
+	}
+}
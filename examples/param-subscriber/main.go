@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/aler9/goroslib"
+)
+
+func onParam(key string, value interface{}) {
+	fmt.Println("param:", key, value)
+}
+
+func main() {
+	// create a node and connect to the master
+	n, err := goroslib.NewNode(goroslib.NodeConf{
+		Name:          "goroslib_paramsub",
+		MasterAddress: "127.0.0.1:11311",
+	})
+	if err != nil {
+		panic(err)
+	}
+	defer n.Close()
+
+	// create a parameter subscriber
+	ps, err := goroslib.NewParamSubscriber(goroslib.ParamSubscriberConf{
+		Node:     n,
+		Key:      "myparam",
+		Callback: onParam,
+	})
+	if err != nil {
+		panic(err)
+	}
+	defer ps.Close()
+
+	// freeze main loop
+	select {}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/aler9/goroslib"
+	"github.com/aler9/goroslib/pkg/msgs/sensor_msgs"
+)
+
+func onMessage(msg *sensor_msgs.Imu) {
+	fmt.Printf("Incoming: %+v\n", msg)
+}
+
+func main() {
+	// load the certificate of the node. Its common name must be
+	// equal to the absolute name of the node (/goroslib_sub).
+	cert, err := tls.LoadX509KeyPair("goroslib_sub.crt", "goroslib_sub.key")
+	if err != nil {
+		panic(err)
+	}
+
+	// load the certificate authority that signed the certificates of all nodes.
+	caPEM, err := os.ReadFile("ca.crt")
+	if err != nil {
+		panic(err)
+	}
+	cas := x509.NewCertPool()
+	if !cas.AppendCertsFromPEM(caPEM) {
+		panic("invalid CA certificate")
+	}
+
+	// create a node and connect to the master.
+	// connections with other nodes are encrypted, and other nodes
+	// must provide a certificate signed by the certificate authority.
+	n, err := goroslib.NewNode(goroslib.NodeConf{
+		Name:          "goroslib_sub",
+		MasterAddress: "127.0.0.1:11311",
+		TLS: &goroslib.NodeTLSConf{
+			Certificate: cert,
+			CAs:         cas,
+			// allow the master, that doesn't have a certificate,
+			// to notify the node about new publishers.
+			SlaveAPIOptionalClientAuth: true,
+		},
+		// only /goroslib_pub is allowed to publish on test_topic.
+		TopicAccessPolicies: map[string]goroslib.TopicAccessPolicy{
+			"test_topic": {
+				Publishers: []string{"/goroslib_pub"},
+			},
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+	defer n.Close()
+
+	// create a subscriber
+	sub, err := goroslib.NewSubscriber(goroslib.SubscriberConf{
+		Node:     n,
+		Topic:    "test_topic",
+		Callback: onMessage,
+	})
+	if err != nil {
+		panic(err)
+	}
+	defer sub.Close()
+
+	// freeze main loop
+	select {}
+}
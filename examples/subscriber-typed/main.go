@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/aler9/goroslib"
+	"github.com/aler9/goroslib/pkg/msgs/sensor_msgs"
+)
+
+func onMessage(msg *sensor_msgs.Imu) {
+	fmt.Printf("Incoming: %+v\n", msg)
+}
+
+func main() {
+	// create a node and connect to the master
+	n, err := goroslib.NewNode(goroslib.NodeConf{
+		Name:          "goroslib_sub",
+		MasterAddress: "127.0.0.1:11311",
+	})
+	if err != nil {
+		panic(err)
+	}
+	defer n.Close()
+
+	// create a subscriber. The type of the callback is checked at compile time.
+	sub, err := goroslib.NewSubscriberOf(goroslib.SubscriberConfOf[sensor_msgs.Imu]{
+		Node:     n,
+		Topic:    "test_topic",
+		Callback: onMessage,
+	})
+	if err != nil {
+		panic(err)
+	}
+	defer sub.Close()
+
+	// freeze main loop
+	select {}
+}
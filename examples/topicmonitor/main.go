@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aler9/goroslib"
+	"github.com/aler9/goroslib/pkg/msgs/sensor_msgs"
+)
+
+func main() {
+	// create a node and connect to the master
+	n, err := goroslib.NewNode(goroslib.NodeConf{
+		Name:          "goroslib_monitor",
+		MasterAddress: "127.0.0.1:11311",
+	})
+	if err != nil {
+		panic(err)
+	}
+	defer n.Close()
+
+	// create a topic monitor, that computes rate, bandwidth and delay
+	// of the messages of a topic
+	tm, err := goroslib.NewTopicMonitor(goroslib.TopicMonitorConf{
+		Node:  n,
+		Topic: "test_topic",
+		Msg:   &sensor_msgs.Imu{},
+	})
+	if err != nil {
+		panic(err)
+	}
+	defer tm.Close()
+
+	r := n.TimeRate(1 * time.Second)
+
+	for {
+		r.Sleep()
+
+		st := tm.Stats()
+		fmt.Printf("rate: %.2f Hz, bandwidth: %.2f B/s, delay: %v\n",
+			st.Rate, st.Bandwidth, st.Delay)
+
+		// print statistics about the connections of the node
+		for _, cs := range n.Stats() {
+			fmt.Printf("connection %d with %s: %d messages, %d bytes, %d drops\n",
+				cs.ID, cs.To, cs.Messages, cs.Bytes, cs.Drops)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package goroslib
+
+import (
+	"reflect"
+	"sync"
+)
+
+const (
+	// number of messages that can be buffered by an intra-process connection.
+	intraProcessQueueSize = 64
+)
+
+type intraProcessKey struct {
+	address string
+	topic   string
+}
+
+// intraProcessPublishers contains the publishers of all the nodes of the process,
+// indexed by the Slave API address of their node and by topic.
+var intraProcessPublishers = struct {
+	mutex sync.Mutex
+	pubs  map[intraProcessKey]*topicPublisher
+}{
+	pubs: make(map[intraProcessKey]*topicPublisher),
+}
+
+func intraProcessRegister(address string, topic string, tp *topicPublisher) {
+	intraProcessPublishers.mutex.Lock()
+	defer intraProcessPublishers.mutex.Unlock()
+
+	intraProcessPublishers.pubs[intraProcessKey{address, topic}] = tp
+}
+
+func intraProcessUnregister(address string, topic string, tp *topicPublisher) {
+	intraProcessPublishers.mutex.Lock()
+	defer intraProcessPublishers.mutex.Unlock()
+
+	key := intraProcessKey{address, topic}
+	if intraProcessPublishers.pubs[key] == tp {
+		delete(intraProcessPublishers.pubs, key)
+	}
+}
+
+func intraProcessFind(address string, topic string) *topicPublisher {
+	intraProcessPublishers.mutex.Lock()
+	defer intraProcessPublishers.mutex.Unlock()
+
+	return intraProcessPublishers.pubs[intraProcessKey{address, topic}]
+}
+
+// copyMessage returns a deep copy of a pointer to a message.
+func copyMessage(msg interface{}) interface{} {
+	src := reflect.ValueOf(msg).Elem()
+	dest := reflect.New(src.Type())
+	copyValue(dest.Elem(), src)
+	return dest.Interface()
+}
+
+func isContainer(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Struct:
+		return true
+	}
+	return false
+}
+
+func copyValue(dest reflect.Value, src reflect.Value) {
+	// copy all values, including the ones that can't be set singularly,
+	// like the unexported fields of time.Time
+	dest.Set(src)
+
+	switch src.Kind() {
+	case reflect.Slice:
+		if src.IsNil() {
+			return
+		}
+		dest.Set(reflect.MakeSlice(src.Type(), src.Len(), src.Len()))
+		reflect.Copy(dest, src)
+		if isContainer(src.Type().Elem()) {
+			for i := 0; i < src.Len(); i++ {
+				copyValue(dest.Index(i), src.Index(i))
+			}
+		}
+
+	case reflect.Array:
+		if isContainer(src.Type().Elem()) {
+			for i := 0; i < src.Len(); i++ {
+				copyValue(dest.Index(i), src.Index(i))
+			}
+		}
+
+	case reflect.Struct:
+		for i := 0; i < src.NumField(); i++ {
+			if dest.Field(i).CanSet() {
+				copyValue(dest.Field(i), src.Field(i))
+			}
+		}
+	}
+}
+
+// intraProcessConn is an intra-process connection between a publisher and a subscriber.
+type intraProcessConn struct {
+	messages chan interface{}
+
+	// closed by the subscriber when it stops reading messages.
+	done <-chan struct{}
+}
+
+type subscriberIntraNewRes struct {
+	ps  *publisherSubscriber
+	err error
+}
+
+type subscriberIntraNewReq struct {
+	callerID string
+	md5      string
+	conn     *intraProcessConn
+	res      chan subscriberIntraNewRes
+}
@@ -0,0 +1,41 @@
+package goroslib
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyMessage(t *testing.T) {
+	src := &TestMessage{
+		A: 1,
+		B: []TestParent{
+			{
+				A: "other test",
+				B: time.Unix(1500, 1345).UTC(),
+				C: true,
+				D: 27,
+				E: 23,
+				F: 2345500 * time.Millisecond,
+			},
+		},
+		C: [2]TestParent{
+			{
+				A: "AA",
+			},
+			{
+				A: "BB",
+			},
+		},
+		D: [2]uint32{222, 333},
+	}
+
+	dest := copyMessage(src).(*TestMessage)
+	require.Equal(t, src, dest)
+	require.Equal(t, false, src == dest)
+	require.Equal(t, false, &src.B[0] == &dest.B[0])
+
+	dest.B[0].A = "modified"
+	require.Equal(t, "other test", src.B[0].A)
+}
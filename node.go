@@ -44,18 +44,24 @@ package goroslib
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"reflect"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/aler9/goroslib/pkg/apimaster"
 	"github.com/aler9/goroslib/pkg/apiparam"
 	"github.com/aler9/goroslib/pkg/apislave"
 	"github.com/aler9/goroslib/pkg/msgs/rosgraph_msgs"
+	"github.com/aler9/goroslib/pkg/protoshm"
 	"github.com/aler9/goroslib/pkg/prototcp"
 	"github.com/aler9/goroslib/pkg/protoudp"
 	"github.com/aler9/goroslib/pkg/xmlrpc"
@@ -65,6 +71,10 @@ type getPublicationsReq struct {
 	res chan [][]string
 }
 
+type getSubscriptionsReq struct {
+	res chan [][]string
+}
+
 type getBusInfoReq struct {
 	res chan apislave.ResponseGetBusInfo
 }
@@ -92,6 +102,12 @@ type udpSubPublisherCloseReq struct {
 type udpFrameReq struct {
 	frame  *protoudp.Frame
 	source *net.UDPAddr
+	group  *net.UDPAddr
+}
+
+type udpMulticastJoinReq struct {
+	group *net.UDPAddr
+	res   chan error
 }
 
 type subscriberPubUpdateReq struct {
@@ -109,16 +125,36 @@ type subscriberNewReq struct {
 	err chan error
 }
 
+type subscriberCloseReq struct {
+	sub *Subscriber
+	res chan *topicSubscriber
+}
+
 type publisherNewReq struct {
 	pub *Publisher
 	err chan error
 }
 
+type publisherCloseReq struct {
+	pub *Publisher
+	res chan *topicPublisher
+}
+
 type serviceProviderNewReq struct {
 	sp  *ServiceProvider
 	err chan error
 }
 
+type paramSubscriberNewReq struct {
+	ps  *ParamSubscriber
+	err chan error
+}
+
+type paramUpdateReq struct {
+	key string
+	res chan []*ParamSubscriber
+}
+
 type simtimeSleep struct {
 	value time.Time
 	done  chan struct{}
@@ -153,6 +189,79 @@ type NodeConf struct {
 	// (optional) port of the UDPROS server of this node.
 	// if not provided, it will be chosen automatically.
 	UdprosPort int
+
+	// (optional) maximum size of UDPROS datagrams. Publishers and subscribers
+	// agree on the minimum of their values. It should not exceed the MTU of the network.
+	// It defaults to 1500.
+	UdprosMaxDatagramSize int
+
+	// (optional) maximum time between the first and the last datagram of a UDPROS message.
+	// When it expires, the message is discarded.
+	// It defaults to 1 second.
+	UdprosReassemblyTimeout time.Duration
+
+	// (optional) period of the pings sent by UDPROS subscribers to publishers.
+	// Publishers close connections with subscribers that stopped sending pings
+	// for 3 periods; nodes should therefore use the same period.
+	// It defaults to 5 seconds.
+	UdprosPingPeriod time.Duration
+
+	// (optional) size of the receive and send buffers of the UDPROS socket.
+	// Large buffers reduce losses when large messages are exchanged.
+	// It defaults to the operating system default.
+	UdprosSocketBufferSize int
+
+	// (optional) name of the network interface used to send and receive
+	// UDPROS multicast datagrams (see PublisherConf.UdpMulticastGroup).
+	// It defaults to the interface chosen by the operating system.
+	UdprosMulticastInterface string
+
+	// (optional) enables the shared-memory transport, that allows publishers and
+	// subscribers of different processes of the same host to exchange messages
+	// through a memory-mapped ring buffer instead of TCPROS.
+	// It is used by TCP subscribers when both nodes enable it, otherwise TCPROS is used.
+	// It can't be used when TLS is enabled.
+	// It defaults to false.
+	EnableSharedMemory bool
+
+	// (optional) size of the ring buffer of each shared-memory connection.
+	// It must be greater than the size of the largest message; when the buffer is full,
+	// messages are discarded.
+	// It defaults to 16 MiB.
+	SharedMemoryBufferSize int
+
+	// (optional) disables the intra-process transport, that allows publishers
+	// and subscribers of the same process to exchange messages without
+	// serialization. If true, messages are exchanged through TCPROS or UDPROS.
+	// It defaults to false.
+	DisableIntraProcess bool
+
+	// (optional) period of the checks of the master, that are used to detect
+	// master restarts. When the master restarts, publishers, subscribers,
+	// service providers and parameter subscribers are registered again.
+	// It defaults to 2 seconds.
+	MasterCheckPeriod time.Duration
+
+	// (optional) timeout of requests to the master. Requests fail when
+	// connecting, waiting for a response or reading a response takes more than this.
+	// It defaults to 10 seconds.
+	MasterTimeout time.Duration
+
+	// (optional) TLS configuration. If provided, TCPROS connections and requests
+	// to the Slave API are encrypted, and other nodes are authenticated with certificates.
+	// UDPROS can't be used when TLS is enabled, since it is not encrypted.
+	// The Slave API requires client certificates, unless TLS.SlaveAPIOptionalClientAuth is set.
+	TLS *NodeTLSConf
+
+	// (optional) access policies of topics, indexed by topic name.
+	// Policies are checked when publishers and subscribers are created and
+	// when connections are established. Node names are authenticated only when TLS is enabled.
+	// Topics without a policy can be published and subscribed by any node.
+	TopicAccessPolicies map[string]TopicAccessPolicy
+
+	// (optional) shut down the node when a SIGINT or SIGTERM signal is received.
+	// It defaults to false.
+	EnableSignalHandler bool
 }
 
 // Node is a ROS Node, an entity that can create subscribers, publishers, service providers
@@ -160,34 +269,54 @@ type NodeConf struct {
 type Node struct {
 	conf NodeConf
 
-	ctx                 context.Context
-	ctxCancel           func()
-	masterAddr          *net.TCPAddr
-	nodeAddr            *net.TCPAddr
-	apiMasterClient     *apimaster.Client
-	apiParamClient      *apiparam.Client
-	apiSlaveServer      *apislave.Server
-	apiSlaveServerURL   string
-	tcprosServer        *prototcp.Server
-	tcprosServerURL     string
-	udprosServer        *protoudp.Server
-	tcprosConns         map[*prototcp.Conn]struct{}
-	udprosSubPublishers map[*subscriberPublisher]struct{}
-	subscribers         map[string]*Subscriber
-	publishers          map[string]*Publisher
-	serviceProviders    map[string]*ServiceProvider
-	publisherLastID     int
-	rosoutPublisher     *Publisher
-	simtimeEnabled      bool
-	simtimeSubscriber   *Subscriber
-	simtimeMutex        sync.RWMutex
-	simtimeInitialized  bool
-	simtimeValue        time.Time
-	simtimeSleeps       []*simtimeSleep
+	ctx                   context.Context
+	ctxCancel             func()
+	masterAddr            *net.TCPAddr
+	masterURL             string
+	masterHTTPClient      *http.Client
+	slaveHTTPClient       *http.Client
+	tlsServerConf         *tls.Config
+	tlsClientConf         *tls.Config
+	topicAccessPolicies   map[string]TopicAccessPolicy
+	nodeAddr              *net.TCPAddr
+	apiMasterClient       *apimaster.Client
+	apiParamClient        *apiparam.Client
+	apiSlaveServer        *apislave.Server
+	apiSlaveServerURL     string
+	apiSlaveServerAddress string
+	tcprosServer          *prototcp.Server
+	tcprosServerURL       string
+	udprosServer          *protoudp.Server
+	udprosMulticastIface  *net.Interface
+	udprosMulticastGroups map[string]*udprosMulticastGroup
+	sharedMemoryHostID    string
+	tcprosConns           map[*prototcp.Conn]struct{}
+	udprosSubPublishers   map[*subscriberPublisher]struct{}
+	subscribers           map[string]*topicSubscriber
+	publishers            map[string]*topicPublisher
+	serviceProviders      map[string]*ServiceProvider
+	paramSubscribers      map[string]map[*ParamSubscriber]struct{}
+	publisherLastID       int
+	connectionLastID      uint32
+	metrics               *nodeMetrics
+	rosoutPublisher       *Publisher
+	statisticsConf        topicStatisticsConf
+	statisticsPublisher   *Publisher
+	simtimeEnabled        bool
+	simtimeSubscriber     *Subscriber
+	simtimeMutex          sync.RWMutex
+	simtimeInitialized    bool
+	simtimeValue          time.Time
+	simtimeSleeps         []*simtimeSleep
+	shutdownOnce          sync.Once
+	shutdownMutex         sync.Mutex
+	shutdownHooks         []func(string)
 
 	// in
 	getPublications        chan getPublicationsReq
+	getSubscriptions       chan getSubscriptionsReq
 	getBusInfo             chan getBusInfoReq
+	getStats               chan getStatsReq
 	tcpConnNew             chan *prototcp.Conn
 	tcpConnClose           chan *prototcp.Conn
 	tcpConnSubscriber      chan tcpConnSubscriberReq
@@ -195,14 +324,20 @@ type Node struct {
 	udpSubPublisherNew     chan *subscriberPublisher
 	udpSubPublisherClose   chan udpSubPublisherCloseReq
 	udpFrame               chan udpFrameReq
+	udpMulticastJoin       chan udpMulticastJoinReq
+	udpMulticastLeave      chan *net.UDPAddr
 	subscriberRequestTopic chan subscriberRequestTopicReq
 	subscriberNew          chan subscriberNewReq
-	subscriberClose        chan *Subscriber
+	subscriberClose        chan subscriberCloseReq
 	subscriberPubUpdate    chan subscriberPubUpdateReq
 	publisherNew           chan publisherNewReq
-	publisherClose         chan *Publisher
+	publisherClose         chan publisherCloseReq
 	serviceProviderNew     chan serviceProviderNewReq
 	serviceProviderClose   chan *ServiceProvider
+	paramSubscriberNew     chan paramSubscriberNewReq
+	paramSubscriberClose   chan *ParamSubscriber
+	paramUpdate            chan paramUpdateReq
+	masterReregister       chan masterReregisterReq
 
 	// out
 	done chan struct{}
@@ -233,6 +368,37 @@ func NewNode(conf NodeConf) (*Node, error) {
 	if len(conf.MasterAddress) == 0 {
 		conf.MasterAddress = "127.0.0.1:11311"
 	}
+	if conf.MasterCheckPeriod == 0 {
+		conf.MasterCheckPeriod = 2 * time.Second
+	}
+	if conf.MasterTimeout == 0 {
+		conf.MasterTimeout = 10 * time.Second
+	}
+	if conf.UdprosMaxDatagramSize == 0 {
+		conf.UdprosMaxDatagramSize = protoudp.DefaultMaxDatagramSize
+	}
+	if conf.UdprosMaxDatagramSize < udprosMinDatagramSize || conf.UdprosMaxDatagramSize > udprosMaxDatagramSize {
+		return nil, fmt.Errorf("UdprosMaxDatagramSize must be between %d and %d",
+			udprosMinDatagramSize, udprosMaxDatagramSize)
+	}
+	if conf.UdprosSocketBufferSize < 0 {
+		return nil, fmt.Errorf("UdprosSocketBufferSize must be positive")
+	}
+	if conf.SharedMemoryBufferSize == 0 {
+		conf.SharedMemoryBufferSize = 16 * 1024 * 1024
+	}
+	if conf.SharedMemoryBufferSize < 0 || conf.SharedMemoryBufferSize%8 != 0 {
+		return nil, fmt.Errorf("SharedMemoryBufferSize must be a positive multiple of 8")
+	}
+	if conf.EnableSharedMemory && conf.TLS != nil {
+		return nil, fmt.Errorf("shared memory can't be used since TLS is enabled")
+	}
+	if conf.UdprosReassemblyTimeout == 0 {
+		conf.UdprosReassemblyTimeout = 1 * time.Second
+	}
+	if conf.UdprosPingPeriod == 0 {
+		conf.UdprosPingPeriod = 5 * time.Second
+	}
 
 	// support ROS-style master address, in order to increase interoperability
 	conf.MasterAddress = strings.TrimPrefix(conf.MasterAddress, "http://")
@@ -284,6 +450,11 @@ func NewNode(conf NodeConf) (*Node, error) {
 		return nil, fmt.Errorf("the node IP is a stateless IPv6, which is not supported")
 	}
 
+	masterURL := xmlrpc.ServerURL(masterAddr, masterAddr.Port)
+	if conf.TLS != nil && conf.TLS.Master {
+		masterURL = xmlrpc.ServerURLWithTLS(masterAddr, masterAddr.Port)
+	}
+
 	ctx, ctxCancel := context.WithCancel(context.Background())
 
 	n := &Node{
@@ -291,15 +462,22 @@ func NewNode(conf NodeConf) (*Node, error) {
 		ctx:                    ctx,
 		ctxCancel:              ctxCancel,
 		masterAddr:             masterAddr,
+		masterURL:              masterURL,
 		nodeAddr:               nodeAddr,
 		tcprosConns:            make(map[*prototcp.Conn]struct{}),
 		udprosSubPublishers:    make(map[*subscriberPublisher]struct{}),
-		subscribers:            make(map[string]*Subscriber),
-		publishers:             make(map[string]*Publisher),
+		udprosMulticastGroups:  make(map[string]*udprosMulticastGroup),
+		subscribers:            make(map[string]*topicSubscriber),
+		publishers:             make(map[string]*topicPublisher),
 		serviceProviders:       make(map[string]*ServiceProvider),
+		paramSubscribers:       make(map[string]map[*ParamSubscriber]struct{}),
+		topicAccessPolicies:    make(map[string]TopicAccessPolicy),
 		simtimeValue:           time.Unix(0, 0),
+		metrics:                newNodeMetrics(),
 		getPublications:        make(chan getPublicationsReq),
+		getSubscriptions:       make(chan getSubscriptionsReq),
 		getBusInfo:             make(chan getBusInfoReq),
+		getStats:               make(chan getStatsReq),
 		tcpConnNew:             make(chan *prototcp.Conn),
 		tcpConnClose:           make(chan *prototcp.Conn),
 		tcpConnSubscriber:      make(chan tcpConnSubscriberReq),
@@ -307,41 +485,103 @@ func NewNode(conf NodeConf) (*Node, error) {
 		udpSubPublisherNew:     make(chan *subscriberPublisher),
 		udpSubPublisherClose:   make(chan udpSubPublisherCloseReq),
 		udpFrame:               make(chan udpFrameReq),
+		udpMulticastJoin:       make(chan udpMulticastJoinReq),
+		udpMulticastLeave:      make(chan *net.UDPAddr),
 		subscriberRequestTopic: make(chan subscriberRequestTopicReq),
 		subscriberNew:          make(chan subscriberNewReq),
-		subscriberClose:        make(chan *Subscriber),
+		subscriberClose:        make(chan subscriberCloseReq),
 		subscriberPubUpdate:    make(chan subscriberPubUpdateReq),
 		publisherNew:           make(chan publisherNewReq),
-		publisherClose:         make(chan *Publisher),
+		publisherClose:         make(chan publisherCloseReq),
 		serviceProviderNew:     make(chan serviceProviderNewReq),
 		serviceProviderClose:   make(chan *ServiceProvider),
+		paramSubscriberNew:     make(chan paramSubscriberNewReq),
+		paramSubscriberClose:   make(chan *ParamSubscriber),
+		paramUpdate:            make(chan paramUpdateReq),
+		masterReregister:       make(chan masterReregisterReq),
 		done:                   make(chan struct{}),
 	}
 
-	n.apiMasterClient = apimaster.NewClient(masterAddr.String(), n.absoluteName())
+	if conf.EnableSharedMemory {
+		n.sharedMemoryHostID = protoshm.HostID()
+	}
+
+	if conf.UdprosMulticastInterface != "" {
+		n.udprosMulticastIface, err = net.InterfaceByName(conf.UdprosMulticastInterface)
+		if err != nil {
+			return nil, fmt.Errorf("unable to find UdprosMulticastInterface: %s", err)
+		}
+	}
+
+	for topic, policy := range conf.TopicAccessPolicies {
+		n.topicAccessPolicies[n.absoluteTopicName(topic)] = policy
+	}
+
+	var masterTLSConf *tls.Config
+	var slaveAPITLSConf *tls.Config
+	if conf.TLS != nil {
+		masterHost, _, _ := net.SplitHostPort(conf.MasterAddress)
+
+		tlsConfs, err := conf.TLS.tlsConfigs(n.absoluteName(), masterHost)
+		if err != nil {
+			return nil, err
+		}
+
+		n.tlsServerConf = tlsConfs.server
+		n.tlsClientConf = tlsConfs.client
+		slaveAPITLSConf = tlsConfs.slaveAPIServer
 
-	n.apiParamClient = apiparam.NewClient(masterAddr.String(), n.absoluteName())
+		n.slaveHTTPClient = xmlrpc.NewHTTPClient(10*time.Second, n.tlsClientConf)
 
-	n.apiSlaveServer, err = apislave.NewServer(":" + strconv.FormatInt(int64(conf.ApislavePort), 10))
+		if conf.TLS.Master {
+			masterTLSConf = tlsConfs.master
+		}
+	}
+
+	n.masterHTTPClient = xmlrpc.NewHTTPClient(conf.MasterTimeout, masterTLSConf)
+
+	n.apiMasterClient = apimaster.NewClientWithHTTPClient(masterAddr.String(), n.absoluteName(), n.masterHTTPClient)
+
+	n.apiParamClient = apiparam.NewClientWithHTTPClient(masterAddr.String(), n.absoluteName(), n.masterHTTPClient)
+
+	n.apiSlaveServer, err = apislave.NewServerWithTLS(":"+strconv.FormatInt(int64(conf.ApislavePort), 10),
+		slaveAPITLSConf)
 	if err != nil {
 		return nil, err
 	}
-	n.apiSlaveServerURL = xmlrpc.ServerURL(nodeAddr, n.apiSlaveServer.Port())
+	if conf.TLS != nil {
+		n.apiSlaveServerURL = xmlrpc.ServerURLWithTLS(nodeAddr, n.apiSlaveServer.Port())
+	} else {
+		n.apiSlaveServerURL = xmlrpc.ServerURL(nodeAddr, n.apiSlaveServer.Port())
+	}
+	n.apiSlaveServerAddress, _ = urlToAddress(n.apiSlaveServerURL)
 
-	n.tcprosServer, err = prototcp.NewServer(":" + strconv.FormatInt(int64(conf.TcprosPort), 10))
+	n.tcprosServer, err = prototcp.NewServerWithTLS(":"+strconv.FormatInt(int64(conf.TcprosPort), 10),
+		n.tlsServerConf)
 	if err != nil {
 		n.apiSlaveServer.Close()
 		return nil, err
 	}
 	n.tcprosServerURL = prototcp.ServerURL(nodeAddr, n.tcprosServer.Port())
 
-	n.udprosServer, err = protoudp.NewServer(":" + strconv.FormatInt(int64(conf.UdprosPort), 10))
+	n.udprosServer, err = protoudp.NewServerWithBufferSize(":"+strconv.FormatInt(int64(conf.UdprosPort), 10),
+		conf.UdprosSocketBufferSize)
 	if err != nil {
 		n.tcprosServer.Close()
 		n.apiSlaveServer.Close()
 		return nil, err
 	}
 
+	if n.udprosMulticastIface != nil {
+		err = n.udprosServer.SetMulticastInterface(n.udprosMulticastIface)
+		if err != nil {
+			n.udprosServer.Close()
+			n.tcprosServer.Close()
+			n.apiSlaveServer.Close()
+			return nil, err
+		}
+	}
+
 	go n.run()
 
 	n.rosoutPublisher, err = NewPublisher(PublisherConf{
@@ -354,6 +594,12 @@ func NewNode(conf NodeConf) (*Node, error) {
 		return nil, err
 	}
 
+	err = n.initStatistics()
+	if err != nil {
+		n.Close()
+		return nil, err
+	}
+
 	isSet, err := n.ParamIsSet("/use_sim_time")
 	if err != nil {
 		n.Close()
@@ -404,16 +650,70 @@ func NewNode(conf NodeConf) (*Node, error) {
 		}
 	}
 
+	if conf.EnableSignalHandler {
+		go n.runSignalHandler()
+	}
+
 	return n, nil
 }
 
 // Close closes a Node and all its resources.
+// Shutdown hooks are called before anything is closed.
 func (n *Node) Close() error {
-	n.ctxCancel()
+	n.shutdown("node closed")
 	<-n.done
 	return nil
 }
 
+// Done returns a channel that is closed when the node has been shut down,
+// by Close(), by a shutdown request received through the Slave API
+// (i.e. rosnode kill) or by a signal.
+func (n *Node) Done() <-chan struct{} {
+	return n.done
+}
+
+// Wait waits until the node has been shut down.
+func (n *Node) Wait() {
+	<-n.done
+}
+
+// AddShutdownHook adds a function that will be called when the node is
+// shutting down, before publishers, subscribers and services are closed
+// and unregistered. The function receives the reason of the shutdown.
+// Hooks are called in the order in which they were added, and must not call Close().
+func (n *Node) AddShutdownHook(cb func(reason string)) {
+	n.shutdownMutex.Lock()
+	defer n.shutdownMutex.Unlock()
+	n.shutdownHooks = append(n.shutdownHooks, cb)
+}
+
+func (n *Node) shutdown(reason string) {
+	n.shutdownOnce.Do(func() {
+		n.shutdownMutex.Lock()
+		hooks := n.shutdownHooks
+		n.shutdownMutex.Unlock()
+
+		for _, cb := range hooks {
+			cb(reason)
+		}
+
+		n.ctxCancel()
+	})
+}
+
+func (n *Node) runSignalHandler() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	select {
+	case sig := <-sigs:
+		n.shutdown("signal received: " + sig.String())
+
+	case <-n.ctx.Done():
+	}
+}
+
 func (n *Node) absoluteTopicName(topic string) string {
 	// topic is absolute
 	if topic[0] == '/' {
@@ -427,6 +727,21 @@ func (n *Node) absoluteTopicName(topic string) string {
 	return n.conf.Namespace + "/" + topic
 }
 
+func (n *Node) absoluteParamName(key string) string {
+	// key is private
+	if key[0] == '~' {
+		key = n.absoluteName() + "/" + key[1:]
+	} else {
+		key = n.absoluteTopicName(key)
+	}
+
+	// the master removes trailing slashes from names
+	if len(key) > 1 {
+		key = strings.TrimRight(key, "/")
+	}
+	return key
+}
+
 func (n *Node) absoluteName() string {
 	if n.conf.Namespace == "/" {
 		return "/" + n.conf.Name
@@ -439,10 +754,11 @@ func (n *Node) run() {
 
 	var serversWg sync.WaitGroup
 
-	serversWg.Add(3)
+	serversWg.Add(4)
 	go n.runAPISlaveServer(&serversWg)
 	go n.runTcprosServer(&serversWg)
 	go n.runUdprosServer(&serversWg)
+	go n.runMasterWatcher(&serversWg)
 
 	var clientsWg sync.WaitGroup
 
@@ -451,8 +767,15 @@ outer:
 		select {
 		case req := <-n.getPublications:
 			res := [][]string{}
-			for _, pub := range n.publishers {
-				res = append(res, []string{pub.conf.Topic, pub.msgType})
+			for topic, pub := range n.publishers {
+				res = append(res, []string{topic, pub.msgType})
+			}
+			req.res <- res
+
+		case req := <-n.getSubscriptions:
+			res := [][]string{}
+			for topic, sub := range n.subscribers {
+				res = append(res, []string{topic, sub.msgType})
 			}
 			req.res <- res
 
@@ -483,6 +806,29 @@ outer:
 				BusInfo:       busInfo,
 			}
 
+		case req := <-n.getStats:
+			stats := []ConnectionStats{}
+
+			for _, pub := range n.publishers {
+				done := make(chan struct{})
+				select {
+				case pub.getStats <- getStatsSubReq{&stats, done}:
+					<-done
+				case <-pub.ctx.Done():
+				}
+			}
+
+			for _, sub := range n.subscribers {
+				done := make(chan struct{})
+				select {
+				case sub.getStats <- getStatsSubReq{&stats, done}:
+					<-done
+				case <-sub.ctx.Done():
+				}
+			}
+
+			req.res <- stats
+
 		case <-n.ctx.Done():
 			break outer
 
@@ -535,18 +881,77 @@ outer:
 			close(req.done)
 
 		case req := <-n.udpFrame:
-			for sp := range n.udprosSubPublishers {
-				if req.frame.ConnectionID == sp.udpID &&
-					req.source.IP.Equal(sp.udpAddr.IP) {
+			// pings are sent by subscribers to publishers, and are never answered.
+			// The publisher checks the source address of the subscriber.
+			if req.frame.Opcode == protoudp.Ping {
+				if req.group == nil {
+					for _, pub := range n.publishers {
+						if uint32(pub.id) == req.frame.ConnectionID {
+							select {
+							case pub.subscriberUDPPing <- req.source:
+							case <-pub.ctx.Done():
+							}
+							break
+						}
+					}
+				}
+				continue
+			}
 
-					select {
-					case sp.udpFrame <- req.frame:
-					case <-sp.ctx.Done():
+			// frames sent to groups are identified by the group, since their source
+			// depends on the interface that was used to send them.
+			if req.group != nil {
+				for sp := range n.udprosSubPublishers {
+					if req.frame.ConnectionID == sp.udpID &&
+						sp.udpGroup != nil && req.group.String() == sp.udpGroup.String() {
+						select {
+						case sp.udpFrame <- req.frame:
+						case <-sp.ctx.Done():
+						}
+						break
 					}
+				}
+				continue
+			}
+
+			// connection IDs are chosen by each publisher and are not unique,
+			// therefore frames are identified by the source IP too.
+			// Publishers may send frames from a port that is not the advertised one
+			// (roscpp uses an ephemeral socket), therefore the source of the first frame
+			// of a connection is recorded and used to identify the following ones.
+			var found *subscriberPublisher
+			for sp := range n.udprosSubPublishers {
+				if req.frame.ConnectionID == sp.udpID && sp.udpSource != nil &&
+					sameUDPAddr(sp.udpSource, req.source) {
+					found = sp
 					break
 				}
 			}
 
+			if found == nil {
+				for sp := range n.udprosSubPublishers {
+					if req.frame.ConnectionID == sp.udpID && sp.udpSource == nil &&
+						req.source.IP.Equal(sp.udpAddr.IP) {
+						sp.udpSource = req.source
+						found = sp
+						break
+					}
+				}
+			}
+
+			if found != nil {
+				select {
+				case found.udpFrame <- req.frame:
+				case <-found.ctx.Done():
+				}
+			}
+
+		case req := <-n.udpMulticastJoin:
+			req.res <- n.joinUdprosMulticastGroup(req.group, &serversWg)
+
+		case group := <-n.udpMulticastLeave:
+			n.leaveUdprosMulticastGroup(group)
+
 		case req := <-n.subscriberRequestTopic:
 			pub, ok := n.publishers[req.req.Topic]
 			if !ok {
@@ -567,14 +972,60 @@ outer:
 			}
 
 		case req := <-n.subscriberNew:
-			_, ok := n.subscribers[n.absoluteTopicName(req.sub.conf.Topic)]
-			if ok {
-				req.err <- fmt.Errorf("Topic %s already subscribed", req.sub.conf.Topic)
+			topic := n.absoluteTopicName(req.sub.conf.Topic)
+
+			err := n.checkTopicAccess(topic, n.absoluteName(), false)
+			if err != nil {
+				req.err <- err
+				continue
+			}
+
+			// topic is already subscribed: share the existing connections
+			if ts, ok := n.subscribers[topic]; ok {
+				if ts.msgType != req.sub.msgType || ts.msgMd5 != req.sub.msgMd5 {
+					req.err <- fmt.Errorf("Topic %s already subscribed with a different message type",
+						req.sub.conf.Topic)
+					continue
+				}
+
+				if ts.conf.Protocol != req.sub.conf.Protocol {
+					req.err <- fmt.Errorf("Topic %s already subscribed with a different protocol",
+						req.sub.conf.Topic)
+					continue
+				}
+
+				if !reflect.DeepEqual(ts.conf.TransportHints, req.sub.conf.TransportHints) ||
+					!reflect.DeepEqual(ts.conf.HostTransportHints, req.sub.conf.HostTransportHints) {
+					req.err <- fmt.Errorf("Topic %s already subscribed with different transport hints",
+						req.sub.conf.Topic)
+					continue
+				}
+
+				if ts.conf.EnableKeepAlive != req.sub.conf.EnableKeepAlive {
+					req.err <- fmt.Errorf("Topic %s already subscribed with a different keep-alive setting",
+						req.sub.conf.Topic)
+					continue
+				}
+
+				if ts.conf.UdpMulticast != req.sub.conf.UdpMulticast {
+					req.err <- fmt.Errorf("Topic %s already subscribed with a different multicast setting",
+						req.sub.conf.Topic)
+					continue
+				}
+
+				if ts.conf.DisableNoDelay != req.sub.conf.DisableNoDelay {
+					req.err <- fmt.Errorf("Topic %s already subscribed with a different TCP_NODELAY setting",
+						req.sub.conf.Topic)
+					continue
+				}
+
+				ts.addSubscriber(req.sub)
+				req.err <- nil
 				continue
 			}
 
 			uris, err := n.apiMasterClient.RegisterSubscriber(
-				n.absoluteTopicName(req.sub.conf.Topic),
+				topic,
 				req.sub.msgType,
 				n.apiSlaveServerURL)
 			if err != nil {
@@ -582,17 +1033,33 @@ outer:
 				continue
 			}
 
-			n.subscribers[n.absoluteTopicName(req.sub.conf.Topic)] = req.sub
+			ts := newTopicSubscriber(req.sub.conf, req.sub.msgMsg, req.sub.msgType, req.sub.msgMd5)
+			ts.addSubscriber(req.sub)
+			n.subscribers[topic] = ts
 			req.err <- nil
 
 			// send initial publishers list to subscriber
 			select {
-			case req.sub.subscriberPubUpdate <- uris:
-			case <-req.sub.ctx.Done():
+			case ts.subscriberPubUpdate <- uris:
+			case <-ts.ctx.Done():
 			}
 
-		case sub := <-n.subscriberClose:
-			delete(n.subscribers, n.absoluteTopicName(sub.conf.Topic))
+		case req := <-n.subscriberClose:
+			topic := n.absoluteTopicName(req.sub.conf.Topic)
+
+			ts, ok := n.subscribers[topic]
+			if !ok || ts.removeSubscriber(req.sub) != 0 {
+				req.res <- nil
+				continue
+			}
+
+			// the last subscriber of the topic has been closed
+			delete(n.subscribers, topic)
+			n.apiMasterClient.UnregisterSubscriber(
+				topic,
+				n.apiSlaveServerURL)
+			ts.close()
+			req.res <- ts
 
 		case req := <-n.subscriberPubUpdate:
 			sub, ok := n.subscribers[req.topic]
@@ -606,28 +1073,94 @@ outer:
 			}
 
 		case req := <-n.publisherNew:
-			_, ok := n.publishers[n.absoluteTopicName(req.pub.conf.Topic)]
-			if ok {
-				req.err <- fmt.Errorf("Topic %s already published", req.pub.conf.Topic)
+			topic := n.absoluteTopicName(req.pub.conf.Topic)
+
+			err := n.checkTopicAccess(topic, n.absoluteName(), true)
+			if err != nil {
+				req.err <- err
+				continue
+			}
+
+			// topic is already published: share the existing subscribers
+			if tp, ok := n.publishers[topic]; ok {
+				if tp.msgType != req.pub.msgType || tp.msgMd5 != req.pub.msgMd5 {
+					req.err <- fmt.Errorf("Topic %s already published with a different message type",
+						req.pub.conf.Topic)
+					continue
+				}
+
+				if tp.conf.Latch != req.pub.conf.Latch {
+					req.err <- fmt.Errorf("Topic %s already published with a different latching setting",
+						req.pub.conf.Topic)
+					continue
+				}
+
+				if tp.conf.UdpRate != req.pub.conf.UdpRate || tp.conf.UdpFrameGap != req.pub.conf.UdpFrameGap {
+					req.err <- fmt.Errorf("Topic %s already published with a different UDP pacing setting",
+						req.pub.conf.Topic)
+					continue
+				}
+
+				if tp.conf.UdpMulticastGroup != req.pub.conf.UdpMulticastGroup {
+					req.err <- fmt.Errorf("Topic %s already published with a different multicast group",
+						req.pub.conf.Topic)
+					continue
+				}
+
+				if tp.conf.TcpCompression != req.pub.conf.TcpCompression {
+					req.err <- fmt.Errorf("Topic %s already published with a different compression setting",
+						req.pub.conf.Topic)
+					continue
+				}
+
+				req.pub.tp = tp
+				tp.addPublisher(req.pub)
+				req.err <- nil
 				continue
 			}
 
-			_, err := n.apiMasterClient.RegisterPublisher(
-				n.absoluteTopicName(req.pub.conf.Topic),
+			n.publisherLastID++
+			tp := newTopicPublisher(req.pub.conf, req.pub.msgType, req.pub.msgMd5, n.publisherLastID)
+
+			// register before the master notifies subscribers
+			if !n.conf.DisableIntraProcess {
+				intraProcessRegister(n.apiSlaveServerAddress, topic, tp)
+			}
+
+			_, err = n.apiMasterClient.RegisterPublisher(
+				topic,
 				req.pub.msgType,
 				n.apiSlaveServerURL)
 			if err != nil {
+				intraProcessUnregister(n.apiSlaveServerAddress, topic, tp)
+				tp.close()
+				<-tp.done
 				req.err <- err
 				continue
 			}
 
-			n.publisherLastID++
-			req.pub.id = n.publisherLastID
-			n.publishers[n.absoluteTopicName(req.pub.conf.Topic)] = req.pub
+			req.pub.tp = tp
+			tp.addPublisher(req.pub)
+			n.publishers[topic] = tp
 			req.err <- nil
 
-		case pub := <-n.publisherClose:
-			delete(n.publishers, n.absoluteTopicName(pub.conf.Topic))
+		case req := <-n.publisherClose:
+			topic := n.absoluteTopicName(req.pub.conf.Topic)
+
+			tp, ok := n.publishers[topic]
+			if !ok || tp != req.pub.tp || tp.removePublisher(req.pub) != 0 {
+				req.res <- nil
+				continue
+			}
+
+			// the last publisher of the topic has been closed
+			delete(n.publishers, topic)
+			intraProcessUnregister(n.apiSlaveServerAddress, topic, tp)
+			n.apiMasterClient.UnregisterPublisher(
+				topic,
+				n.apiSlaveServerURL)
+			tp.close()
+			req.res <- tp
 
 		case req := <-n.serviceProviderNew:
 			_, ok := n.serviceProviders[n.absoluteTopicName(req.sp.conf.Name)]
@@ -650,6 +1183,46 @@ outer:
 
 		case sp := <-n.serviceProviderClose:
 			delete(n.serviceProviders, n.absoluteTopicName(sp.conf.Name))
+
+		case req := <-n.paramSubscriberNew:
+			// the master keeps a single subscription for each key and node,
+			// therefore the request is performed again in order to get the current value.
+			value, err := n.apiParamClient.SubscribeParam(n.apiSlaveServerURL, req.ps.key)
+			if err != nil {
+				req.err <- err
+				continue
+			}
+
+			if _, ok := n.paramSubscribers[req.ps.key]; !ok {
+				n.paramSubscribers[req.ps.key] = make(map[*ParamSubscriber]struct{})
+			}
+			n.paramSubscribers[req.ps.key][req.ps] = struct{}{}
+			req.ps.value = value
+			req.err <- nil
+
+		case ps := <-n.paramSubscriberClose:
+			delete(n.paramSubscribers[ps.key], ps)
+
+			if len(n.paramSubscribers[ps.key]) == 0 {
+				delete(n.paramSubscribers, ps.key)
+				n.apiParamClient.UnsubscribeParam(n.apiSlaveServerURL, ps.key)
+			}
+
+		case req := <-n.paramUpdate:
+			// updates regard the subscribed parameter or a parameter inside its namespace
+			var res []*ParamSubscriber
+			for key, pss := range n.paramSubscribers {
+				if req.key == key || strings.HasPrefix(req.key, strings.TrimSuffix(key, "/")+"/") {
+					for ps := range pss {
+						res = append(res, ps)
+					}
+				}
+			}
+			req.res <- res
+
+		case req := <-n.masterReregister:
+			params, err := n.reregister()
+			req.res <- masterReregisterRes{err, params}
 		}
 	}
 
@@ -658,6 +1231,9 @@ outer:
 	n.apiSlaveServer.Close()
 	n.tcprosServer.Close()
 	n.udprosServer.Close()
+	for _, g := range n.udprosMulticastGroups {
+		g.server.Close()
+	}
 	serversWg.Wait()
 
 	for c := range n.tcprosConns {
@@ -665,11 +1241,41 @@ outer:
 	}
 	clientsWg.Wait()
 
+	for topic, ts := range n.subscribers {
+		n.apiMasterClient.UnregisterSubscriber(
+			topic,
+			n.apiSlaveServerURL)
+		<-ts.done
+	}
+
+	for key := range n.paramSubscribers {
+		n.apiParamClient.UnsubscribeParam(
+			n.apiSlaveServerURL,
+			key)
+	}
+
+	for topic, tp := range n.publishers {
+		intraProcessUnregister(n.apiSlaveServerAddress, topic, tp)
+		n.apiMasterClient.UnregisterPublisher(
+			topic,
+			n.apiSlaveServerURL)
+		<-tp.done
+	}
+
 	if n.simtimeSubscriber != nil {
 		n.simtimeSubscriber.Close()
 	}
 
+	if n.statisticsPublisher != nil {
+		n.statisticsPublisher.Close()
+	}
+
 	if n.rosoutPublisher != nil {
 		n.rosoutPublisher.Close()
 	}
+
+	n.masterHTTPClient.CloseIdleConnections()
+	if n.slaveHTTPClient != nil {
+		n.slaveHTTPClient.CloseIdleConnections()
+	}
 }
@@ -11,6 +11,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/aler9/goroslib/pkg/apislave"
 	"github.com/aler9/goroslib/pkg/msgs/sensor_msgs"
 	"github.com/aler9/goroslib/pkg/msgs/std_msgs"
 )
@@ -37,7 +38,14 @@ func newContainerMaster() (*containerMaster, error) {
 		"goroslib-test-master").Output()
 	ip := string(byts[:len(byts)-1])
 
-	// wait for master
+	waitMaster(ip)
+
+	return &containerMaster{
+		ip: ip,
+	}, nil
+}
+
+func waitMaster(ip string) {
 	address := ip + ":" + strconv.FormatInt(11311, 10)
 	for {
 		time.Sleep(1 * time.Second)
@@ -48,16 +56,24 @@ func newContainerMaster() (*containerMaster, error) {
 		conn.Close()
 		break
 	}
-
-	return &containerMaster{
-		ip: ip,
-	}, nil
 }
 
 func (m *containerMaster) IP() string {
 	return m.ip
 }
 
+// restart restarts the master, that loses all registrations
+// and keeps its address.
+func (m *containerMaster) restart() error {
+	err := exec.Command("docker", "restart", "goroslib-test-master").Run()
+	if err != nil {
+		return err
+	}
+
+	waitMaster(m.ip)
+	return nil
+}
+
 func (m *containerMaster) close() {
 	exec.Command("docker", "kill", "goroslib-test-master").Run()
 	exec.Command("docker", "wait", "goroslib-test-master").Run()
@@ -138,6 +154,32 @@ func TestNodeMasterOpen(t *testing.T) {
 		})
 		require.Error(t, err)
 	})
+	t.Run("not responding", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer l.Close()
+
+		// accept connections but never reply
+		go func() {
+			for {
+				conn, err := l.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+			}
+		}()
+
+		start := time.Now()
+		_, err = NewNode(NodeConf{
+			Namespace:     "/myns",
+			Name:          "goroslib",
+			MasterAddress: l.Addr().String(),
+			MasterTimeout: 500 * time.Millisecond,
+		})
+		require.Error(t, err)
+		require.Less(t, int64(time.Since(start)), int64(5*time.Second))
+	})
 }
 
 func TestNodeNamespace(t *testing.T) {
@@ -252,3 +294,317 @@ func TestNodeRosnodeInfo(t *testing.T) {
 			"    \\* transport: TCPROS\n"+
 			"\n$"), rt.waitOutput())
 }
+
+func TestNodeShutdown(t *testing.T) {
+	m, err := newContainerMaster()
+	require.NoError(t, err)
+	defer m.close()
+
+	n1, err := NewNode(NodeConf{
+		Namespace:     "/myns",
+		Name:          "goroslib1",
+		MasterAddress: m.IP() + ":11311",
+	})
+	require.NoError(t, err)
+	defer n1.Close()
+
+	pub, err := NewPublisher(PublisherConf{
+		Node:  n1,
+		Topic: "test_topic",
+		Msg:   &std_msgs.String{},
+	})
+	require.NoError(t, err)
+	defer pub.Close()
+
+	hookReason := make(chan string, 1)
+	n1.AddShutdownHook(func(reason string) {
+		pub.Write(&std_msgs.String{Data: "bye"})
+		hookReason <- reason
+	})
+
+	n2, err := NewNode(NodeConf{
+		Namespace:     "/myns",
+		Name:          "goroslib2",
+		MasterAddress: m.IP() + ":11311",
+	})
+	require.NoError(t, err)
+	defer n2.Close()
+
+	recv := make(chan string, 10)
+	sub, err := NewSubscriber(SubscriberConf{
+		Node:  n2,
+		Topic: "test_topic",
+		Callback: func(msg *std_msgs.String) {
+			recv <- msg.Data
+		},
+	})
+	require.NoError(t, err)
+	defer sub.Close()
+
+	time.Sleep(1 * time.Second)
+
+	err = n2.NodeKill("goroslib1")
+	require.NoError(t, err)
+
+	<-n1.Done()
+	n1.Wait()
+
+	require.Equal(t, "", <-hookReason)
+	require.Equal(t, "bye", <-recv)
+
+	nodes, err := n2.MasterGetNodes()
+	require.NoError(t, err)
+	_, ok := nodes["/myns/goroslib1"]
+	require.Equal(t, false, ok)
+}
+
+func TestNodeMasterRestart(t *testing.T) {
+	m, err := newContainerMaster()
+	require.NoError(t, err)
+	defer m.close()
+
+	n1, err := NewNode(NodeConf{
+		Namespace:         "/myns",
+		Name:              "goroslib1",
+		MasterAddress:     m.IP() + ":11311",
+		MasterCheckPeriod: 500 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer n1.Close()
+
+	pub, err := NewPublisher(PublisherConf{
+		Node:  n1,
+		Topic: "test_pub",
+		Msg:   &std_msgs.String{},
+	})
+	require.NoError(t, err)
+	defer pub.Close()
+
+	subRecv := make(chan string, 10)
+	sub, err := NewSubscriber(SubscriberConf{
+		Node:  n1,
+		Topic: "test_sub",
+		Callback: func(msg *std_msgs.String) {
+			subRecv <- msg.Data
+		},
+	})
+	require.NoError(t, err)
+	defer sub.Close()
+
+	sp, err := NewServiceProvider(ServiceProviderConf{
+		Node: n1,
+		Name: "test_srv",
+		Srv:  &TestService{},
+		Callback: func(req *TestServiceReq) *TestServiceRes {
+			return &TestServiceRes{C: req.A * 2}
+		},
+	})
+	require.NoError(t, err)
+	defer sp.Close()
+
+	paramRecv := make(chan interface{}, 10)
+	ps, err := NewParamSubscriber(ParamSubscriberConf{
+		Node: n1,
+		Key:  "test_param",
+		Callback: func(key string, value interface{}) {
+			paramRecv <- value
+		},
+	})
+	require.NoError(t, err)
+	defer ps.Close()
+
+	// the master restarts with the same PID, inside the same container
+	err = m.restart()
+	require.NoError(t, err)
+
+	time.Sleep(2 * time.Second)
+
+	n2, err := NewNode(NodeConf{
+		Namespace:     "/myns",
+		Name:          "goroslib2",
+		MasterAddress: m.IP() + ":11311",
+	})
+	require.NoError(t, err)
+	defer n2.Close()
+
+	pubRecv := make(chan string, 10)
+	sub2, err := NewSubscriber(SubscriberConf{
+		Node:  n2,
+		Topic: "test_pub",
+		Callback: func(msg *std_msgs.String) {
+			pubRecv <- msg.Data
+		},
+	})
+	require.NoError(t, err)
+	defer sub2.Close()
+
+	pub2, err := NewPublisher(PublisherConf{
+		Node:  n2,
+		Topic: "test_sub",
+		Msg:   &std_msgs.String{},
+	})
+	require.NoError(t, err)
+	defer pub2.Close()
+
+	time.Sleep(1 * time.Second)
+
+	pub.Write(&std_msgs.String{Data: "from publisher"})
+	require.Equal(t, "from publisher", <-pubRecv)
+
+	pub2.Write(&std_msgs.String{Data: "to subscriber"})
+	require.Equal(t, "to subscriber", <-subRecv)
+
+	sc, err := NewServiceClient(ServiceClientConf{
+		Node: n2,
+		Name: "test_srv",
+		Srv:  &TestService{},
+	})
+	require.NoError(t, err)
+	defer sc.Close()
+
+	var res TestServiceRes
+	err = sc.Call(&TestServiceReq{A: 123}, &res)
+	require.NoError(t, err)
+	require.Equal(t, TestServiceRes{C: 246}, res)
+
+	err = n2.ParamSetInt("test_param", 123)
+	require.NoError(t, err)
+
+	for {
+		v := <-paramRecv
+		if v == 123 {
+			break
+		}
+	}
+}
+
+func TestNodeStats(t *testing.T) {
+	m, err := newContainerMaster()
+	require.NoError(t, err)
+	defer m.close()
+
+	p, err := NewNode(NodeConf{
+		Namespace:     "/myns",
+		Name:          "goroslib_pub",
+		MasterAddress: m.IP() + ":11311",
+	})
+	require.NoError(t, err)
+	defer p.Close()
+
+	pub, err := NewPublisher(PublisherConf{
+		Node:  p,
+		Topic: "test_topic",
+		Msg:   &sensor_msgs.Imu{},
+	})
+	require.NoError(t, err)
+	defer pub.Close()
+
+	n, err := NewNode(NodeConf{
+		Namespace:           "/myns",
+		Name:                "goroslib",
+		MasterAddress:       m.IP() + ":11311",
+		DisableIntraProcess: true,
+	})
+	require.NoError(t, err)
+	defer n.Close()
+
+	recv := make(chan struct{})
+	sub, err := NewSubscriber(SubscriberConf{
+		Node:  n,
+		Topic: "test_topic",
+		Callback: func(msg *sensor_msgs.Imu) {
+			recv <- struct{}{}
+		},
+	})
+	require.NoError(t, err)
+	defer sub.Close()
+
+	time.Sleep(1 * time.Second)
+
+	for i := 0; i < 3; i++ {
+		pub.Write(&sensor_msgs.Imu{
+			Header: std_msgs.Header{
+				Stamp: time.Now(),
+			},
+		})
+		<-recv
+	}
+
+	pubStats := p.Stats()
+	require.Equal(t, 1, len(pubStats))
+	require.Equal(t, "/myns/goroslib", pubStats[0].To)
+	require.Equal(t, byte('o'), pubStats[0].Direction)
+	require.Equal(t, "TCPROS", pubStats[0].Transport)
+	require.Equal(t, uint64(3), pubStats[0].Messages)
+	require.NotEqual(t, uint64(0), pubStats[0].Bytes)
+
+	subStats := n.Stats()
+	require.Equal(t, 1, len(subStats))
+	require.Equal(t, byte('i'), subStats[0].Direction)
+	require.Equal(t, "/myns/test_topic", subStats[0].Topic)
+	require.Equal(t, true, subStats[0].Connected)
+	require.Equal(t, uint64(3), subStats[0].Messages)
+	require.Equal(t, pubStats[0].Bytes, subStats[0].Bytes)
+	require.Equal(t, uint64(0), subStats[0].Drops)
+	require.Greater(t, int64(subStats[0].Latency), int64(0))
+
+	busStats, err := apislave.NewClient(n.apiSlaveServerAddress, "/myns/goroslib_pub").GetBusStats()
+	require.NoError(t, err)
+	require.Equal(t, 0, len(busStats.PublishStats))
+	require.Equal(t, []apislave.BusStatsSubscriber{{
+		Topic: "/myns/test_topic",
+		Connections: []apislave.BusStatsSubscriberConnection{{
+			ID:               subStats[0].ID,
+			BytesReceived:    int(subStats[0].Bytes),
+			MessagesReceived: 3,
+			Connected:        true,
+		}},
+	}}, busStats.SubscribeStats)
+}
+
+func TestNodeSlaveAPI(t *testing.T) {
+	m, err := newContainerMaster()
+	require.NoError(t, err)
+	defer m.close()
+
+	n, err := NewNode(NodeConf{
+		Namespace:     "/myns",
+		Name:          "goroslib",
+		MasterAddress: m.IP() + ":11311",
+	})
+	require.NoError(t, err)
+	defer n.Close()
+
+	pub, err := NewPublisher(PublisherConf{
+		Node:  n,
+		Topic: "test_pub",
+		Msg:   &std_msgs.Int64{},
+	})
+	require.NoError(t, err)
+	defer pub.Close()
+
+	sub, err := NewSubscriber(SubscriberConf{
+		Node:     n,
+		Topic:    "test_sub",
+		Callback: func(msg *std_msgs.String) {},
+	})
+	require.NoError(t, err)
+	defer sub.Close()
+
+	c := apislave.NewClient(n.apiSlaveServerAddress, "/myns/goroslib2")
+
+	ur, err := c.GetMasterURI()
+	require.NoError(t, err)
+	require.Equal(t, "http://"+m.IP()+":11311", ur)
+
+	pubs, err := c.GetPublications()
+	require.NoError(t, err)
+	require.Contains(t, pubs, []string{"/myns/test_pub", "std_msgs/Int64"})
+
+	subs, err := c.GetSubscriptions()
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"/myns/test_sub", "std_msgs/String"}}, subs)
+
+	err = c.ParamUpdate("/myns/myparam", 123)
+	require.NoError(t, err)
+}
@@ -28,6 +28,30 @@ func (n *Node) handleAPISlaveServer(req apislave.Request) apislave.Response {
 			}
 		}
 
+	case *apislave.RequestGetBusStats:
+		res := make(chan []ConnectionStats)
+		select {
+		case n.getStats <- getStatsReq{res}:
+			return apislave.ResponseGetBusStats{
+				Code:          1,
+				StatusMessage: "",
+				Stats:         busStats(<-res),
+			}
+
+		case <-n.ctx.Done():
+			return apislave.ResponseGetBusStats{
+				Code:          0,
+				StatusMessage: "terminating",
+			}
+		}
+
+	case *apislave.RequestGetMasterURI:
+		return apislave.ResponseGetMasterURI{
+			Code:          1,
+			StatusMessage: "",
+			MasterURI:     n.masterURL,
+		}
+
 	case *apislave.RequestGetPid:
 		return apislave.ResponseGetPid{
 			Code:          1,
@@ -54,6 +78,52 @@ func (n *Node) handleAPISlaveServer(req apislave.Request) apislave.Response {
 			}
 		}
 
+	case *apislave.RequestGetSubscriptions:
+		res := make(chan [][]string)
+
+		select {
+		case n.getSubscriptions <- getSubscriptionsReq{res}:
+			return apislave.ResponseGetSubscriptions{
+				Code:          1,
+				StatusMessage: "",
+				TopicList:     <-res,
+			}
+
+		case <-n.ctx.Done():
+			return apislave.ResponseGetSubscriptions{
+				Code:          1,
+				StatusMessage: "",
+				TopicList:     [][]string{},
+			}
+		}
+
+	case *apislave.RequestParamUpdate:
+		key := n.absoluteParamName(reqt.Key)
+
+		res := make(chan []*ParamSubscriber)
+		select {
+		case n.paramUpdate <- paramUpdateReq{
+			key: key,
+			res: res,
+		}:
+		case <-n.ctx.Done():
+			return apislave.ResponseParamUpdate{
+				Code:          0,
+				StatusMessage: "terminating",
+			}
+		}
+
+		// subscribers are notified outside of the node routine,
+		// since callbacks can call node functions.
+		for _, ps := range <-res {
+			ps.onUpdate(key, reqt.Value)
+		}
+
+		return apislave.ResponseParamUpdate{
+			Code:          1,
+			StatusMessage: "",
+		}
+
 	case *apislave.RequestPublisherUpdate:
 		select {
 		case n.subscriberPubUpdate <- subscriberPubUpdateReq{
@@ -82,7 +152,10 @@ func (n *Node) handleAPISlaveServer(req apislave.Request) apislave.Response {
 		}
 
 	case *apislave.RequestShutdown:
-		n.ctxCancel()
+		// shut down in a separate routine, since shutdown hooks
+		// may take some time and the Slave API server is closed
+		// during shutdown.
+		go n.shutdown(reqt.Reason)
 
 		return apislave.ResponseShutdown{
 			Code:          1,
@@ -53,10 +53,20 @@ func (n *Node) MasterGetNodes() (map[string]*InfoNode, error) {
 		}
 	}
 
+	names := make([]string, 0, len(ret))
+	for nodeName := range ret {
+		names = append(names, nodeName)
+	}
+
+	urls, err := n.apiMasterClient.LookupNodes(names)
+	if err != nil {
+		return nil, fmt.Errorf("lookupNode: %v", err)
+	}
+
 	for nodeName, info := range ret {
-		ur, err := n.apiMasterClient.LookupNode(nodeName)
-		if err != nil {
-			return nil, fmt.Errorf("lookupNode: %v", err)
+		ur, ok := urls[nodeName]
+		if !ok {
+			return nil, fmt.Errorf("lookupNode: unable to find node '%s'", nodeName)
 		}
 
 		address, err := urlToAddress(ur)
@@ -167,10 +177,22 @@ func (n *Node) MasterGetServices() (map[string]*InfoService, error) {
 		for _, node := range entry.Nodes {
 			ret[entry.Name].Providers[node] = struct{}{}
 		}
+	}
 
-		ur, err := n.apiMasterClient.LookupService(entry.Name)
-		if err != nil {
-			return nil, fmt.Errorf("lookupService: %v", err)
+	names := make([]string, 0, len(ret))
+	for name := range ret {
+		names = append(names, name)
+	}
+
+	urls, err := n.apiMasterClient.LookupServices(names)
+	if err != nil {
+		return nil, fmt.Errorf("lookupService: %v", err)
+	}
+
+	for name, info := range ret {
+		ur, ok := urls[name]
+		if !ok {
+			return nil, fmt.Errorf("lookupService: unable to find service '%s'", name)
 		}
 
 		address, err := urlToAddress(ur)
@@ -178,7 +200,7 @@ func (n *Node) MasterGetServices() (map[string]*InfoService, error) {
 			return nil, err
 		}
 
-		ret[entry.Name].Address = address
+		info.Address = address
 	}
 
 	return ret, nil
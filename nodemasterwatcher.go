@@ -0,0 +1,138 @@
+package goroslib
+
+import (
+	"sync"
+	"time"
+)
+
+type masterReregisterRes struct {
+	err    error
+	params map[*ParamSubscriber]interface{}
+}
+
+type masterReregisterReq struct {
+	res chan masterReregisterRes
+}
+
+// runMasterWatcher periodically checks the master, in order to detect restarts.
+// When the master restarts, it loses all registrations, therefore they are
+// performed again.
+// Restarts are detected by a change of the PID of the master, or by the fact that
+// the master doesn't know this node anymore, since a supervised or containerized master
+// can restart with the same PID.
+func (n *Node) runMasterWatcher(wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	t := time.NewTicker(n.conf.MasterCheckPeriod)
+	defer t.Stop()
+
+	// pid of the master at the time of the last successful check
+	lastPid := 0
+
+	// whether the last check or re-registration failed
+	failed := false
+
+	for {
+		select {
+		case <-t.C:
+			pid, err := n.apiMasterClient.GetPid()
+			if err != nil {
+				failed = true
+				continue
+			}
+
+			// the node is always registered, since it publishes on /rosout
+			_, err = n.apiMasterClient.LookupNode(n.absoluteName())
+			lost := (err != nil)
+
+			if pid == lastPid && !failed && !lost {
+				continue
+			}
+
+			// the first check is performed after the initial registrations
+			if lastPid == 0 && !failed && !lost {
+				lastPid = pid
+				continue
+			}
+
+			resc := make(chan masterReregisterRes)
+			var res masterReregisterRes
+			select {
+			case n.masterReregister <- masterReregisterReq{resc}:
+				res = <-resc
+			case <-n.ctx.Done():
+				return
+			}
+
+			// parameters may have changed while the master was unavailable.
+			// Subscribers are notified outside of the node routine,
+			// since callbacks can call node functions.
+			for ps, value := range res.params {
+				ps.onUpdate(ps.key, value)
+			}
+
+			failed = (res.err != nil)
+			lastPid = pid
+
+		case <-n.ctx.Done():
+			return
+		}
+	}
+}
+
+// reregister performs again all registrations with the master.
+// It returns the current values of subscribed parameters.
+// It must be called by the node routine.
+func (n *Node) reregister() (map[*ParamSubscriber]interface{}, error) {
+	for topic, tp := range n.publishers {
+		_, err := n.apiMasterClient.RegisterPublisher(
+			topic,
+			tp.msgType,
+			n.apiSlaveServerURL)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for topic, ts := range n.subscribers {
+		uris, err := n.apiMasterClient.RegisterSubscriber(
+			topic,
+			ts.msgType,
+			n.apiSlaveServerURL)
+		if err != nil {
+			return nil, err
+		}
+
+		select {
+		case ts.subscriberPubUpdate <- uris:
+		case <-ts.ctx.Done():
+		}
+	}
+
+	for name := range n.serviceProviders {
+		err := n.apiMasterClient.RegisterService(
+			name,
+			n.tcprosServerURL,
+			n.apiSlaveServerURL)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	params := make(map[*ParamSubscriber]interface{})
+
+	for key, pss := range n.paramSubscribers {
+		value, err := n.apiParamClient.SubscribeParam(
+			n.apiSlaveServerURL,
+			key)
+		if err != nil {
+			return nil, err
+		}
+
+		for ps := range pss {
+			params[ps] = value
+		}
+	}
+
+	return params, nil
+}
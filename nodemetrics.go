@@ -0,0 +1,337 @@
+package goroslib
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// metricsBuckets are the upper bounds of the buckets of duration histograms, in seconds.
+var metricsBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type metricsType string
+
+const (
+	metricsTypeCounter   metricsType = "counter"
+	metricsTypeGauge     metricsType = "gauge"
+	metricsTypeHistogram metricsType = "histogram"
+)
+
+type metricsDesc struct {
+	name string
+	typ  metricsType
+	help string
+}
+
+var (
+	metricsTopicMessages = metricsDesc{"goroslib_topic_messages_total", metricsTypeCounter,
+		"Messages sent or received on a topic."}
+	metricsTopicBytes = metricsDesc{"goroslib_topic_bytes_total", metricsTypeCounter,
+		"Bytes sent or received on a topic."}
+	metricsTopicDrops = metricsDesc{"goroslib_topic_drops_total", metricsTypeCounter,
+		"Messages lost or discarded on a topic."}
+	metricsTopicReconnects = metricsDesc{"goroslib_topic_reconnects_total", metricsTypeCounter,
+		"Connections to publishers that were established again after an error."}
+	metricsConnMessages = metricsDesc{"goroslib_connection_messages_total", metricsTypeCounter,
+		"Messages sent or received on a connection."}
+	metricsConnBytes = metricsDesc{"goroslib_connection_bytes_total", metricsTypeCounter,
+		"Bytes sent or received on a connection."}
+	metricsConnDrops = metricsDesc{"goroslib_connection_drops_total", metricsTypeCounter,
+		"Messages lost or discarded on a connection."}
+	metricsConnConnected = metricsDesc{"goroslib_connection_connected", metricsTypeGauge,
+		"Whether a connection is established."}
+	metricsServiceCalls = metricsDesc{"goroslib_service_calls_total", metricsTypeCounter,
+		"Service calls performed by clients or handled by providers."}
+	metricsServiceDuration = metricsDesc{"goroslib_service_call_duration_seconds", metricsTypeHistogram,
+		"Duration of service calls."}
+	metricsServiceReconnects = metricsDesc{"goroslib_service_reconnects_total", metricsTypeCounter,
+		"Connections to service providers that were established again after an error."}
+	metricsActionGoals = metricsDesc{"goroslib_action_goals_total", metricsTypeCounter,
+		"Action goals that reached a terminal state."}
+	metricsMasterErrors = metricsDesc{"goroslib_master_errors_total", metricsTypeCounter,
+		"XML-RPC requests to the master that failed."}
+)
+
+// metricsLabels encodes label names and values, passed in pairs.
+func metricsLabels(pairs ...string) string {
+	var b strings.Builder
+	for i := 0; i < len(pairs); i += 2 {
+		if i != 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(pairs[i])
+		b.WriteString(`="`)
+		b.WriteString(metricsLabelEscaper.Replace(pairs[i+1]))
+		b.WriteByte('"')
+	}
+	return b.String()
+}
+
+var metricsLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// metricsTopic contains the counters of a topic, that are shared
+// between its connections and outlive them.
+type metricsTopic struct {
+	messages uint64
+	bytes    uint64
+	drops    uint64
+}
+
+func (mt *metricsTopic) onMessage(bytes uint64) {
+	atomic.AddUint64(&mt.messages, 1)
+	atomic.AddUint64(&mt.bytes, bytes)
+}
+
+func (mt *metricsTopic) onDrop() {
+	mt.onDrops(1)
+}
+
+func (mt *metricsTopic) onDrops(n uint64) {
+	atomic.AddUint64(&mt.drops, n)
+}
+
+type metricsHistogram struct {
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+// nodeMetrics contains the counters that are exported by Node.MetricsHandler.
+type nodeMetrics struct {
+	mutex      sync.Mutex
+	topics     map[string]*metricsTopic
+	counters   map[string]map[string]uint64
+	histograms map[string]map[string]*metricsHistogram
+}
+
+func newNodeMetrics() *nodeMetrics {
+	return &nodeMetrics{
+		topics:     make(map[string]*metricsTopic),
+		counters:   make(map[string]map[string]uint64),
+		histograms: make(map[string]map[string]*metricsHistogram),
+	}
+}
+
+// topic returns the counters of a topic in a direction ('i' or 'o').
+func (m *nodeMetrics) topic(topic string, direction byte) *metricsTopic {
+	labels := metricsLabels("topic", topic, "direction", metricsDirection(direction))
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	mt, ok := m.topics[labels]
+	if !ok {
+		mt = &metricsTopic{}
+		m.topics[labels] = mt
+	}
+	return mt
+}
+
+func (m *nodeMetrics) inc(desc metricsDesc, labels string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	vals, ok := m.counters[desc.name]
+	if !ok {
+		vals = make(map[string]uint64)
+		m.counters[desc.name] = vals
+	}
+	vals[labels]++
+}
+
+func (m *nodeMetrics) observe(desc metricsDesc, labels string, d time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	vals, ok := m.histograms[desc.name]
+	if !ok {
+		vals = make(map[string]*metricsHistogram)
+		m.histograms[desc.name] = vals
+	}
+
+	h, ok := vals[labels]
+	if !ok {
+		h = &metricsHistogram{
+			buckets: make([]uint64, len(metricsBuckets)),
+		}
+		vals[labels] = h
+	}
+
+	secs := d.Seconds()
+	for i, le := range metricsBuckets {
+		if secs <= le {
+			h.buckets[i]++
+		}
+	}
+	h.count++
+	h.sum += secs
+}
+
+func (m *nodeMetrics) onServiceCall(service string, role string, start time.Time, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+
+	m.inc(metricsServiceCalls, metricsLabels("service", service, "role", role, "result", result))
+	m.observe(metricsServiceDuration, metricsLabels("service", service, "role", role),
+		time.Since(start))
+}
+
+func (m *nodeMetrics) onGoalDone(action string, role string, outcome string) {
+	m.inc(metricsActionGoals, metricsLabels("action", action, "role", role, "outcome", outcome))
+}
+
+func metricsDirection(direction byte) string {
+	if direction == 'o' {
+		return "out"
+	}
+	return "in"
+}
+
+type metricsWriter struct {
+	*bufio.Writer
+}
+
+func (w metricsWriter) header(desc metricsDesc) {
+	w.WriteString("# HELP " + desc.name + " " + desc.help + "\n")
+	w.WriteString("# TYPE " + desc.name + " " + string(desc.typ) + "\n")
+}
+
+func (w metricsWriter) sample(name string, labels string, val string) {
+	w.WriteString(name)
+	if labels != "" {
+		w.WriteString("{" + labels + "}")
+	}
+	w.WriteString(" " + val + "\n")
+}
+
+func (w metricsWriter) counters(desc metricsDesc, vals map[string]uint64) {
+	w.header(desc)
+	for _, labels := range sortedKeys(vals) {
+		w.sample(desc.name, labels, strconv.FormatUint(vals[labels], 10))
+	}
+}
+
+func (w metricsWriter) histograms(desc metricsDesc, vals map[string]*metricsHistogram) {
+	w.header(desc)
+
+	keys := make([]string, 0, len(vals))
+	for k := range vals {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, labels := range keys {
+		h := vals[labels]
+		prefix := labels
+		if prefix != "" {
+			prefix += ","
+		}
+
+		for i, le := range metricsBuckets {
+			w.sample(desc.name+"_bucket", prefix+`le="`+formatFloat(le)+`"`,
+				strconv.FormatUint(h.buckets[i], 10))
+		}
+		w.sample(desc.name+"_bucket", prefix+`le="+Inf"`, strconv.FormatUint(h.count, 10))
+		w.sample(desc.name+"_sum", labels, formatFloat(h.sum))
+		w.sample(desc.name+"_count", labels, strconv.FormatUint(h.count, 10))
+	}
+}
+
+func sortedKeys(m map[string]uint64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// write writes the metrics in the Prometheus text format.
+func (m *nodeMetrics) write(dest io.Writer, conns []ConnectionStats, masterErrors map[string]uint64) error {
+	w := metricsWriter{bufio.NewWriter(dest)}
+
+	m.mutex.Lock()
+
+	topicMessages := make(map[string]uint64)
+	topicBytes := make(map[string]uint64)
+	topicDrops := make(map[string]uint64)
+	for labels, mt := range m.topics {
+		topicMessages[labels] = atomic.LoadUint64(&mt.messages)
+		topicBytes[labels] = atomic.LoadUint64(&mt.bytes)
+		topicDrops[labels] = atomic.LoadUint64(&mt.drops)
+	}
+
+	w.counters(metricsTopicMessages, topicMessages)
+	w.counters(metricsTopicBytes, topicBytes)
+	w.counters(metricsTopicDrops, topicDrops)
+	w.counters(metricsTopicReconnects, m.counters[metricsTopicReconnects.name])
+
+	m.mutex.Unlock()
+
+	connMessages := make(map[string]uint64)
+	connBytes := make(map[string]uint64)
+	connDrops := make(map[string]uint64)
+	connConnected := make(map[string]uint64)
+	for _, cs := range conns {
+		labels := metricsLabels(
+			"topic", cs.Topic,
+			"direction", metricsDirection(cs.Direction),
+			"transport", cs.Transport,
+			"to", cs.To,
+			"id", strconv.FormatInt(int64(cs.ID), 10))
+
+		connMessages[labels] = cs.Messages
+		connBytes[labels] = cs.Bytes
+		connDrops[labels] = cs.Drops
+		connConnected[labels] = 0
+		if cs.Connected {
+			connConnected[labels] = 1
+		}
+	}
+
+	w.counters(metricsConnMessages, connMessages)
+	w.counters(metricsConnBytes, connBytes)
+	w.counters(metricsConnDrops, connDrops)
+	w.counters(metricsConnConnected, connConnected)
+
+	m.mutex.Lock()
+	w.counters(metricsServiceCalls, m.counters[metricsServiceCalls.name])
+	w.histograms(metricsServiceDuration, m.histograms[metricsServiceDuration.name])
+	w.counters(metricsServiceReconnects, m.counters[metricsServiceReconnects.name])
+	w.counters(metricsActionGoals, m.counters[metricsActionGoals.name])
+	m.mutex.Unlock()
+
+	masterErrorsByLabels := make(map[string]uint64)
+	for api, v := range masterErrors {
+		masterErrorsByLabels[metricsLabels("api", api)] = v
+	}
+	w.counters(metricsMasterErrors, masterErrorsByLabels)
+
+	return w.Flush()
+}
+
+// MetricsHandler returns a HTTP handler that exports metrics about topics,
+// connections, services, actions and requests to the master,
+// in the Prometheus text format.
+func (n *Node) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+		n.metrics.write(w, n.Stats(), map[string]uint64{
+			"master": n.apiMasterClient.ErrorCount(),
+			"param":  n.apiParamClient.ErrorCount(),
+		})
+	})
+}
@@ -0,0 +1,154 @@
+package goroslib
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/aler9/goroslib/pkg/msgs/std_msgs"
+	"github.com/aler9/goroslib/pkg/msgs/std_srvs"
+)
+
+func TestNodeMetricsWrite(t *testing.T) {
+	m := newNodeMetrics()
+
+	mt := m.topic("/my\"topic", 'o')
+	mt.onMessage(10)
+	mt.onMessage(20)
+	mt.onDrop()
+	require.Equal(t, mt, m.topic("/my\"topic", 'o'))
+
+	m.inc(metricsTopicReconnects, metricsLabels("topic", "/mytopic"))
+	m.onServiceCall("/mysrv", "client", time.Now().Add(-30*time.Millisecond), nil)
+	m.onServiceCall("/mysrv", "client", time.Now(), fmt.Errorf("err"))
+	m.onGoalDone("/myaction", "server", "succeeded")
+
+	var buf bytes.Buffer
+	err := m.write(&buf, []ConnectionStats{{
+		InfoConnection: InfoConnection{
+			ID:        3,
+			To:        "/mynode",
+			Direction: 'i',
+			Transport: "TCPROS",
+			Topic:     "/mytopic",
+			Connected: true,
+		},
+		Messages: 4,
+		Bytes:    100,
+		Drops:    1,
+	}}, map[string]uint64{"master": 2})
+	require.NoError(t, err)
+
+	out := buf.String()
+
+	for _, line := range []string{
+		"# TYPE goroslib_topic_messages_total counter",
+		`goroslib_topic_messages_total{topic="/my\"topic",direction="out"} 2`,
+		`goroslib_topic_bytes_total{topic="/my\"topic",direction="out"} 30`,
+		`goroslib_topic_drops_total{topic="/my\"topic",direction="out"} 1`,
+		`goroslib_topic_reconnects_total{topic="/mytopic"} 1`,
+		`goroslib_connection_messages_total{topic="/mytopic",direction="in",` +
+			`transport="TCPROS",to="/mynode",id="3"} 4`,
+		`goroslib_connection_bytes_total{topic="/mytopic",direction="in",` +
+			`transport="TCPROS",to="/mynode",id="3"} 100`,
+		"# TYPE goroslib_connection_connected gauge",
+		`goroslib_connection_connected{topic="/mytopic",direction="in",` +
+			`transport="TCPROS",to="/mynode",id="3"} 1`,
+		`goroslib_service_calls_total{service="/mysrv",role="client",result="error"} 1`,
+		`goroslib_service_calls_total{service="/mysrv",role="client",result="success"} 1`,
+		"# TYPE goroslib_service_call_duration_seconds histogram",
+		`goroslib_service_call_duration_seconds_bucket{service="/mysrv",role="client",le="0.005"} 1`,
+		`goroslib_service_call_duration_seconds_bucket{service="/mysrv",role="client",le="0.05"} 2`,
+		`goroslib_service_call_duration_seconds_bucket{service="/mysrv",role="client",le="+Inf"} 2`,
+		`goroslib_service_call_duration_seconds_count{service="/mysrv",role="client"} 2`,
+		"# TYPE goroslib_service_reconnects_total counter",
+		`goroslib_action_goals_total{action="/myaction",role="server",outcome="succeeded"} 1`,
+		`goroslib_master_errors_total{api="master"} 2`,
+	} {
+		require.Contains(t, strings.Split(out, "\n"), line)
+	}
+}
+
+func TestNodeMetricsHandler(t *testing.T) {
+	m, err := newContainerMaster()
+	require.NoError(t, err)
+	defer m.close()
+
+	n, err := NewNode(NodeConf{
+		Namespace:     "/myns",
+		Name:          "goroslib",
+		MasterAddress: m.IP() + ":11311",
+	})
+	require.NoError(t, err)
+	defer n.Close()
+
+	pub, err := NewPublisher(PublisherConf{
+		Node:  n,
+		Topic: "test_topic",
+		Msg:   &std_msgs.Int64{},
+	})
+	require.NoError(t, err)
+	defer pub.Close()
+
+	recv := make(chan struct{})
+	sub, err := NewSubscriber(SubscriberConf{
+		Node:  n,
+		Topic: "test_topic",
+		Callback: func(msg *std_msgs.Int64) {
+			recv <- struct{}{}
+		},
+	})
+	require.NoError(t, err)
+	defer sub.Close()
+
+	sp, err := NewServiceProvider(ServiceProviderConf{
+		Node: n,
+		Name: "test_srv",
+		Srv:  &std_srvs.Trigger{},
+		Callback: func(req *std_srvs.TriggerReq) *std_srvs.TriggerRes {
+			return &std_srvs.TriggerRes{Success: true}
+		},
+	})
+	require.NoError(t, err)
+	defer sp.Close()
+
+	sc, err := NewServiceClient(ServiceClientConf{
+		Node: n,
+		Name: "test_srv",
+		Srv:  &std_srvs.Trigger{},
+	})
+	require.NoError(t, err)
+	defer sc.Close()
+
+	time.Sleep(1 * time.Second)
+
+	pub.Write(&std_msgs.Int64{Data: 5})
+	<-recv
+
+	err = sc.Call(&std_srvs.TriggerReq{}, &std_srvs.TriggerRes{})
+	require.NoError(t, err)
+
+	hs := httptest.NewServer(n.MetricsHandler())
+	defer hs.Close()
+
+	res, err := hs.Client().Get(hs.URL)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	require.Equal(t, "text/plain; version=0.0.4; charset=utf-8", res.Header.Get("Content-Type"))
+
+	byts, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	lines := strings.Split(string(byts), "\n")
+
+	require.Contains(t, lines, `goroslib_topic_messages_total{topic="/myns/test_topic",direction="in"} 1`)
+	require.Contains(t, lines, `goroslib_topic_messages_total{topic="/myns/test_topic",direction="out"} 1`)
+	require.Contains(t, lines, `goroslib_service_calls_total{service="/myns/test_srv",role="client",result="success"} 1`)
+	require.Contains(t, lines, `goroslib_master_errors_total{api="master"} 0`)
+}
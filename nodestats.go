@@ -0,0 +1,281 @@
+package goroslib
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aler9/goroslib/pkg/apislave"
+	"github.com/aler9/goroslib/pkg/msgs/std_msgs"
+)
+
+type getStatsReq struct {
+	res chan []ConnectionStats
+}
+
+type getStatsSubReq struct {
+	pstats *[]ConnectionStats
+	done   chan struct{}
+}
+
+// ConnectionStats contains statistics about a connection.
+type ConnectionStats struct {
+	InfoConnection
+
+	// number of messages that were sent or received.
+	Messages uint64
+
+	// number of bytes that were sent or received.
+	// Intra-process connections do not serialize messages and always report zero.
+	Bytes uint64
+
+	// number of messages that were lost or discarded.
+	Drops uint64
+
+	// number of messages that were lost in transit, because they were never received
+	// or never completed. It is only filled by UDPROS subscribers, and is included in Drops.
+	Lost uint64
+
+	// time of the last message.
+	LastMessage time.Time
+
+	// latency of the last message, computed from the stamp of its header.
+	// It is zero if messages do not have a header.
+	Latency time.Duration
+
+	// compression algorithm used by the connection, if any.
+	Compression string
+
+	// number of bytes that were sent or received, before compression.
+	// It is only filled when Compression is set.
+	UncompressedBytes uint64
+}
+
+// connectionStats contains the counters of a connection.
+type connectionStats struct {
+	id    int
+	topic *metricsTopic
+
+	mutex       sync.Mutex
+	connected   bool
+	messages    uint64
+	bytes       uint64
+	drops       uint64
+	lost        uint64
+	lastMessage time.Time
+	latency     time.Duration
+
+	compression       string
+	uncompressedBytes uint64
+}
+
+func (cs *connectionStats) setConnected(v bool) {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+	cs.connected = v
+}
+
+func (cs *connectionStats) isConnected() bool {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+	return cs.connected
+}
+
+func (cs *connectionStats) onMessage(bytes uint64, latency time.Duration) {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	cs.messages++
+	cs.bytes += bytes
+	cs.lastMessage = time.Now()
+	cs.latency = latency
+
+	cs.topic.onMessage(bytes)
+}
+
+func (cs *connectionStats) setCompression(algo string) {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+	cs.compression = algo
+}
+
+func (cs *connectionStats) onUncompressedBytes(bytes uint64) {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+	cs.uncompressedBytes += bytes
+}
+
+func (cs *connectionStats) onDrop() {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+	cs.drops++
+
+	cs.topic.onDrop()
+}
+
+func (cs *connectionStats) onLost(n uint64) {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+	cs.drops += n
+	cs.lost += n
+
+	cs.topic.onDrops(n)
+}
+
+func (cs *connectionStats) get(info InfoConnection) ConnectionStats {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	info.ID = cs.id
+
+	return ConnectionStats{
+		InfoConnection:    info,
+		Messages:          cs.messages,
+		Bytes:             cs.bytes,
+		Drops:             cs.drops,
+		Lost:              cs.lost,
+		LastMessage:       cs.lastMessage,
+		Latency:           cs.latency,
+		Compression:       cs.compression,
+		UncompressedBytes: cs.uncompressedBytes,
+	}
+}
+
+// busInfo returns the row of the connection in the bus info.
+// The last field describes the transport, like roscpp does.
+func (cs *connectionStats) busInfo(info InfoConnection) []interface{} {
+	s := cs.get(info)
+
+	var desc string
+	if s.Compression != "" {
+		ratio := 1.0
+		if s.Bytes != 0 {
+			ratio = float64(s.UncompressedBytes) / float64(s.Bytes)
+		}
+		desc = fmt.Sprintf("compression %s, ratio %.2f", s.Compression, ratio)
+	}
+
+	return []interface{}{
+		s.ID, s.To, string(s.Direction), s.Transport, s.Topic, s.Connected, desc,
+	}
+}
+
+// messageHeader returns the header of a message, if there's one.
+func messageHeader(msg interface{}) (std_msgs.Header, bool) {
+	rv := reflect.ValueOf(msg)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return std_msgs.Header{}, false
+	}
+
+	f := rv.Elem().FieldByName("Header")
+	if !f.IsValid() {
+		return std_msgs.Header{}, false
+	}
+
+	header, ok := f.Interface().(std_msgs.Header)
+	return header, ok
+}
+
+// messageStamp returns the stamp of the header of a message, if there's one.
+func messageStamp(msg interface{}) (time.Time, bool) {
+	header, ok := messageHeader(msg)
+	if !ok || header.Stamp.IsZero() {
+		return time.Time{}, false
+	}
+
+	return header.Stamp, true
+}
+
+func (n *Node) messageLatency(msg interface{}) time.Duration {
+	stamp, ok := messageStamp(msg)
+	if !ok {
+		return 0
+	}
+	return n.TimeNow().Sub(stamp)
+}
+
+func (n *Node) newConnectionID() int {
+	return int(atomic.AddUint32(&n.connectionLastID, 1))
+}
+
+// Stats returns statistics about the connections of the node
+// with publishers and subscribers.
+func (n *Node) Stats() []ConnectionStats {
+	res := make(chan []ConnectionStats)
+
+	select {
+	case n.getStats <- getStatsReq{res}:
+		return <-res
+
+	case <-n.ctx.Done():
+		return nil
+	}
+}
+
+// clampInt32 converts a counter into a value that can be encoded as a XML-RPC i4,
+// that is limited to 32 bits, without wrapping around.
+func clampInt32(v uint64) int {
+	if v > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return int(v)
+}
+
+func busStats(stats []ConnectionStats) apislave.BusStats {
+	ret := apislave.BusStats{
+		PublishStats:   []apislave.BusStatsPublisher{},
+		SubscribeStats: []apislave.BusStatsSubscriber{},
+	}
+
+	pubs := make(map[string]int)
+	subs := make(map[string]int)
+
+	for _, cs := range stats {
+		if cs.Direction == 'o' {
+			i, ok := pubs[cs.Topic]
+			if !ok {
+				i = len(ret.PublishStats)
+				pubs[cs.Topic] = i
+				ret.PublishStats = append(ret.PublishStats, apislave.BusStatsPublisher{
+					Topic:       cs.Topic,
+					Connections: []apislave.BusStatsPublisherConnection{},
+				})
+			}
+
+			ret.PublishStats[i].MessageDataSent = clampInt32(
+				uint64(ret.PublishStats[i].MessageDataSent) + cs.Bytes)
+			ret.PublishStats[i].Connections = append(ret.PublishStats[i].Connections,
+				apislave.BusStatsPublisherConnection{
+					ID:           cs.ID,
+					BytesSent:    clampInt32(cs.Bytes),
+					MessagesSent: clampInt32(cs.Messages),
+					Drops:        clampInt32(cs.Drops),
+					Connected:    cs.Connected,
+				})
+		} else {
+			i, ok := subs[cs.Topic]
+			if !ok {
+				i = len(ret.SubscribeStats)
+				subs[cs.Topic] = i
+				ret.SubscribeStats = append(ret.SubscribeStats, apislave.BusStatsSubscriber{
+					Topic:       cs.Topic,
+					Connections: []apislave.BusStatsSubscriberConnection{},
+				})
+			}
+
+			ret.SubscribeStats[i].Connections = append(ret.SubscribeStats[i].Connections,
+				apislave.BusStatsSubscriberConnection{
+					ID:               cs.ID,
+					BytesReceived:    clampInt32(cs.Bytes),
+					MessagesReceived: clampInt32(cs.Messages),
+					Drops:            clampInt32(cs.Drops),
+					Connected:        cs.Connected,
+				})
+		}
+	}
+
+	return ret
+}
@@ -0,0 +1,63 @@
+package goroslib
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/aler9/goroslib/pkg/apislave"
+)
+
+func TestBusStats(t *testing.T) {
+	bs := busStats([]ConnectionStats{
+		{
+			InfoConnection: InfoConnection{
+				ID:        1,
+				Topic:     "/mytopic",
+				Direction: 'o',
+				Connected: true,
+			},
+			Messages: 10,
+			Bytes:    math.MaxInt32,
+			Drops:    2,
+		},
+		{
+			InfoConnection: InfoConnection{
+				ID:        2,
+				Topic:     "/mytopic",
+				Direction: 'o',
+			},
+			Messages: 20,
+			Bytes:    100,
+		},
+		{
+			InfoConnection: InfoConnection{
+				ID:        3,
+				Topic:     "/mytopic",
+				Direction: 'i',
+				Connected: true,
+			},
+			Messages: 1 << 40,
+			Bytes:    1 << 40,
+			Drops:    3,
+		},
+	})
+
+	require.Equal(t, apislave.BusStats{
+		PublishStats: []apislave.BusStatsPublisher{{
+			Topic:           "/mytopic",
+			MessageDataSent: math.MaxInt32,
+			Connections: []apislave.BusStatsPublisherConnection{
+				{ID: 1, BytesSent: math.MaxInt32, MessagesSent: 10, Drops: 2, Connected: true},
+				{ID: 2, BytesSent: 100, MessagesSent: 20},
+			},
+		}},
+		SubscribeStats: []apislave.BusStatsSubscriber{{
+			Topic: "/mytopic",
+			Connections: []apislave.BusStatsSubscriberConnection{
+				{ID: 3, BytesReceived: math.MaxInt32, MessagesReceived: math.MaxInt32, Drops: 3, Connected: true},
+			},
+		}},
+	}, bs)
+}
@@ -40,6 +40,14 @@ func (n *Node) runTcprosServerConn(wg *sync.WaitGroup, conn *prototcp.Conn) {
 				return false
 			}
 
+			err = n.checkPeerIdentity(conn, header.Callerid)
+			if err != nil {
+				conn.WriteHeader(&prototcp.HeaderError{
+					Error: err.Error(),
+				})
+				return false
+			}
+
 			select {
 			case n.tcpConnSubscriber <- tcpConnSubscriberReq{
 				conn:   conn,
@@ -56,6 +64,14 @@ func (n *Node) runTcprosServerConn(wg *sync.WaitGroup, conn *prototcp.Conn) {
 				return false
 			}
 
+			err = n.checkPeerIdentity(conn, header.Callerid)
+			if err != nil {
+				conn.WriteHeader(&prototcp.HeaderError{
+					Error: err.Error(),
+				})
+				return false
+			}
+
 			select {
 			case n.tcpConnServiceClient <- tcpConnServiceClientReq{
 				conn:   conn,
@@ -0,0 +1,218 @@
+package goroslib
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+
+	"github.com/aler9/goroslib/pkg/prototcp"
+)
+
+// NodeTLSConf is the TLS configuration of a Node.
+type NodeTLSConf struct {
+	// certificate of the node, with its private key.
+	// The common name of the certificate is the identity of the node,
+	// and must be equal to the absolute name of the node (i.e. /myns/mynode).
+	Certificate tls.Certificate
+
+	// certificate authorities that are used to verify the certificates of other nodes.
+	CAs *x509.CertPool
+
+	// (optional) use TLS also to communicate with the master,
+	// that must support HTTPS and must have a certificate signed by CAs.
+	// It defaults to false.
+	Master bool
+
+	// (optional) name that the certificate of the master must contain,
+	// as a DNS name or IP address in its subject alternative names,
+	// or as common name if the certificate has no subject alternative names.
+	// It is used only when Master is true.
+	// It defaults to the host of the master address.
+	MasterName string
+
+	// (optional) allow clients without a certificate to call the Slave API.
+	// By default, the Slave API requires a client certificate signed by CAs,
+	// therefore a master without a client certificate (like the stock rosmaster)
+	// can't notify the node about new publishers and parameter changes.
+	// Certificates provided by clients are still verified.
+	// It defaults to false.
+	SlaveAPIOptionalClientAuth bool
+}
+
+type nodeTLSConfigs struct {
+	server         *tls.Config
+	slaveAPIServer *tls.Config
+	client         *tls.Config
+	master         *tls.Config
+}
+
+// TopicAccessPolicy is the access policy of a topic.
+type TopicAccessPolicy struct {
+	// (optional) absolute names of the nodes that are allowed to publish on the topic.
+	// It defaults to all nodes.
+	Publishers []string
+
+	// (optional) absolute names of the nodes that are allowed to subscribe to the topic.
+	// It defaults to all nodes.
+	Subscribers []string
+}
+
+// tlsConfigs returns the TLS configurations used by servers and clients of a node.
+func (c *NodeTLSConf) tlsConfigs(nodeName string, masterHost string) (*nodeTLSConfigs, error) {
+	if len(c.Certificate.Certificate) == 0 {
+		return nil, fmt.Errorf("TLS.Certificate not provided")
+	}
+
+	if c.CAs == nil {
+		return nil, fmt.Errorf("TLS.CAs not provided")
+	}
+
+	leaf, err := x509.ParseCertificate(c.Certificate.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS.Certificate: %s", err)
+	}
+
+	if leaf.Subject.CommonName != nodeName {
+		return nil, fmt.Errorf("the common name of TLS.Certificate (%s) must be equal to the node name (%s)",
+			leaf.Subject.CommonName, nodeName)
+	}
+
+	masterName := c.MasterName
+	if masterName == "" {
+		masterName = masterHost
+	}
+
+	// peers are verified against CAs only, since nodes are usually reached through their IPs
+	// and are identified by the common name of their certificate.
+	verify := func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return fmt.Errorf("peer did not provide a certificate")
+		}
+
+		opts := x509.VerifyOptions{
+			Roots:         c.CAs,
+			Intermediates: x509.NewCertPool(),
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		}
+		for _, cert := range cs.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+
+		_, err := cs.PeerCertificates[0].Verify(opts)
+		return err
+	}
+
+	// the master is reached through its address, therefore its certificate
+	// must also contain the master name, otherwise any node could impersonate it.
+	verifyMaster := func(cs tls.ConnectionState) error {
+		err := verify(cs)
+		if err != nil {
+			return err
+		}
+
+		return verifyCertificateName(cs.PeerCertificates[0], masterName)
+	}
+
+	confs := &nodeTLSConfigs{
+		server: &tls.Config{
+			MinVersion:       tls.VersionTLS12,
+			Certificates:     []tls.Certificate{c.Certificate},
+			ClientAuth:       tls.RequireAnyClientCert,
+			VerifyConnection: verify,
+		},
+		client: &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			Certificates:       []tls.Certificate{c.Certificate},
+			InsecureSkipVerify: true,
+			VerifyConnection:   verify,
+		},
+		master: &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			Certificates:       []tls.Certificate{c.Certificate},
+			InsecureSkipVerify: true,
+			VerifyConnection:   verifyMaster,
+		},
+	}
+
+	confs.slaveAPIServer = confs.server
+	if c.SlaveAPIOptionalClientAuth {
+		confs.slaveAPIServer = &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			Certificates: []tls.Certificate{c.Certificate},
+			ClientAuth:   tls.RequestClientCert,
+			VerifyConnection: func(cs tls.ConnectionState) error {
+				if len(cs.PeerCertificates) == 0 {
+					return nil
+				}
+				return verify(cs)
+			},
+		}
+	}
+
+	return confs, nil
+}
+
+// verifyCertificateName checks that a certificate contains a given name.
+func verifyCertificateName(cert *x509.Certificate, name string) error {
+	if len(cert.DNSNames) == 0 && len(cert.IPAddresses) == 0 {
+		if cert.Subject.CommonName != name {
+			return fmt.Errorf("the certificate of the master (%s) does not match the master name (%s)",
+				cert.Subject.CommonName, name)
+		}
+		return nil
+	}
+
+	err := cert.VerifyHostname(name)
+	if err != nil {
+		return fmt.Errorf("the certificate of the master does not match the master name: %s", err)
+	}
+
+	return nil
+}
+
+// checkPeerIdentity checks that the caller ID sent by a peer
+// is equal to the identity contained in its certificate.
+func (n *Node) checkPeerIdentity(conn *prototcp.Conn, callerID string) error {
+	if n.conf.TLS == nil {
+		return nil
+	}
+
+	cert := conn.PeerCertificate()
+	if cert == nil {
+		return fmt.Errorf("peer did not provide a certificate")
+	}
+
+	if cert.Subject.CommonName != callerID {
+		return fmt.Errorf("caller ID '%s' does not match the certificate identity '%s'",
+			callerID, cert.Subject.CommonName)
+	}
+
+	return nil
+}
+
+// checkTopicAccess checks whether a node is allowed to publish or subscribe to a topic.
+func (n *Node) checkTopicAccess(topic string, nodeName string, publish bool) error {
+	policy, ok := n.topicAccessPolicies[topic]
+	if !ok {
+		return nil
+	}
+
+	allowed := policy.Subscribers
+	action := "subscribe to"
+	if publish {
+		allowed = policy.Publishers
+		action = "publish on"
+	}
+
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	for _, name := range allowed {
+		if name == nodeName {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("node '%s' is not allowed to %s topic '%s'", nodeName, action, topic)
+}
@@ -0,0 +1,521 @@
+package goroslib
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/aler9/goroslib/pkg/msgs/std_msgs"
+	"github.com/aler9/goroslib/pkg/msgs/std_srvs"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "myca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return &testCA{
+		cert: cert,
+		key:  key,
+		pool: pool,
+	}
+}
+
+func (ca *testCA) newCertificate(t *testing.T, commonName string, hosts ...string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+	}
+}
+
+func TestNodeTLSConfErrors(t *testing.T) {
+	auth := newTestCA(t)
+
+	for _, ca := range []struct {
+		name string
+		conf NodeTLSConf
+		err  string
+	}{
+		{
+			"missing certificate",
+			NodeTLSConf{
+				CAs: auth.pool,
+			},
+			"TLS.Certificate not provided",
+		},
+		{
+			"missing cas",
+			NodeTLSConf{
+				Certificate: auth.newCertificate(t, "/mynode"),
+			},
+			"TLS.CAs not provided",
+		},
+		{
+			"wrong common name",
+			NodeTLSConf{
+				Certificate: auth.newCertificate(t, "/othernode"),
+				CAs:         auth.pool,
+			},
+			"the common name of TLS.Certificate (/othernode) must be equal to the node name (/mynode)",
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			_, err := ca.conf.tlsConfigs("/mynode", "127.0.0.1")
+			require.EqualError(t, err, ca.err)
+		})
+	}
+}
+
+func TestNodeTLSMasterName(t *testing.T) {
+	auth := newTestCA(t)
+
+	connState := func(cert tls.Certificate) tls.ConnectionState {
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		require.NoError(t, err)
+		return tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf}}
+	}
+
+	for _, ca := range []struct {
+		name       string
+		masterName string
+		cert       tls.Certificate
+		err        string
+	}{
+		{
+			"ip",
+			"",
+			auth.newCertificate(t, "/master", "127.0.0.1"),
+			"",
+		},
+		{
+			"dns name",
+			"mymaster",
+			auth.newCertificate(t, "/master", "mymaster"),
+			"",
+		},
+		{
+			"common name",
+			"mymaster",
+			auth.newCertificate(t, "mymaster"),
+			"",
+		},
+		{
+			"node certificate",
+			"",
+			auth.newCertificate(t, "/othernode"),
+			"the certificate of the master (/othernode) does not match the master name (127.0.0.1)",
+		},
+		{
+			"wrong name",
+			"mymaster",
+			auth.newCertificate(t, "/master", "othermaster"),
+			"the certificate of the master does not match the master name: " +
+				"x509: certificate is valid for othermaster, not mymaster",
+		},
+		{
+			"wrong ca",
+			"",
+			newTestCA(t).newCertificate(t, "/master", "127.0.0.1"),
+			"x509: certificate signed by unknown authority",
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			conf := NodeTLSConf{
+				Certificate: auth.newCertificate(t, "/mynode"),
+				CAs:         auth.pool,
+				Master:      true,
+				MasterName:  ca.masterName,
+			}
+
+			confs, err := conf.tlsConfigs("/mynode", "127.0.0.1")
+			require.NoError(t, err)
+
+			err = confs.master.VerifyConnection(connState(ca.cert))
+			if ca.err == "" {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), ca.err)
+			}
+		})
+	}
+}
+
+func TestNodeTLSSlaveAPIClientAuth(t *testing.T) {
+	auth := newTestCA(t)
+
+	for _, optional := range []bool{false, true} {
+		conf := NodeTLSConf{
+			Certificate:                auth.newCertificate(t, "/mynode"),
+			CAs:                        auth.pool,
+			SlaveAPIOptionalClientAuth: optional,
+		}
+
+		confs, err := conf.tlsConfigs("/mynode", "127.0.0.1")
+		require.NoError(t, err)
+
+		err = confs.slaveAPIServer.VerifyConnection(tls.ConnectionState{})
+		if optional {
+			require.Equal(t, tls.RequestClientCert, confs.slaveAPIServer.ClientAuth)
+			require.NoError(t, err)
+		} else {
+			require.Equal(t, tls.RequireAnyClientCert, confs.slaveAPIServer.ClientAuth)
+			require.EqualError(t, err, "peer did not provide a certificate")
+		}
+
+		leaf, err := x509.ParseCertificate(newTestCA(t).newCertificate(t, "/other").Certificate[0])
+		require.NoError(t, err)
+		err = confs.slaveAPIServer.VerifyConnection(tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{leaf},
+		})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "x509: certificate signed by unknown authority")
+	}
+}
+
+func TestNodeCheckTopicAccess(t *testing.T) {
+	n := &Node{
+		topicAccessPolicies: map[string]TopicAccessPolicy{
+			"/mytopic": {
+				Publishers:  []string{"/mypub"},
+				Subscribers: []string{"/mysub1", "/mysub2"},
+			},
+			"/mytopic2": {
+				Publishers: []string{"/mypub"},
+			},
+		},
+	}
+
+	require.NoError(t, n.checkTopicAccess("/mytopic", "/mypub", true))
+	require.EqualError(t, n.checkTopicAccess("/mytopic", "/mysub1", true),
+		"node '/mysub1' is not allowed to publish on topic '/mytopic'")
+	require.NoError(t, n.checkTopicAccess("/mytopic", "/mysub2", false))
+	require.EqualError(t, n.checkTopicAccess("/mytopic", "/mypub", false),
+		"node '/mypub' is not allowed to subscribe to topic '/mytopic'")
+	require.NoError(t, n.checkTopicAccess("/mytopic2", "/other", false))
+	require.NoError(t, n.checkTopicAccess("/othertopic", "/other", true))
+}
+
+func TestNodeTLS(t *testing.T) {
+	m, err := newContainerMaster()
+	require.NoError(t, err)
+	defer m.close()
+
+	ca := newTestCA(t)
+
+	policies := map[string]TopicAccessPolicy{
+		"/test_topic": {
+			Publishers:  []string{"/goroslib_pub"},
+			Subscribers: []string{"/goroslib_sub"},
+		},
+	}
+
+	npub, err := NewNode(NodeConf{
+		Name:          "goroslib_pub",
+		MasterAddress: m.IP() + ":11311",
+		TLS: &NodeTLSConf{
+			Certificate: ca.newCertificate(t, "/goroslib_pub"),
+			CAs:         ca.pool,
+		},
+		TopicAccessPolicies: policies,
+		DisableIntraProcess: true,
+	})
+	require.NoError(t, err)
+	defer npub.Close()
+
+	pub, err := NewPublisher(PublisherConf{
+		Node:  npub,
+		Topic: "test_topic",
+		Msg:   &std_msgs.Int64{},
+		Latch: true,
+	})
+	require.NoError(t, err)
+	defer pub.Close()
+
+	pub.Write(&std_msgs.Int64{Data: 5})
+
+	sp, err := NewServiceProvider(ServiceProviderConf{
+		Node: npub,
+		Name: "test_srv",
+		Srv:  &std_srvs.Trigger{},
+		Callback: func(req *std_srvs.TriggerReq) *std_srvs.TriggerRes {
+			return &std_srvs.TriggerRes{Success: true}
+		},
+	})
+	require.NoError(t, err)
+	defer sp.Close()
+
+	t.Run("allowed", func(t *testing.T) {
+		nsub, err := NewNode(NodeConf{
+			Name:          "goroslib_sub",
+			MasterAddress: m.IP() + ":11311",
+			TLS: &NodeTLSConf{
+				Certificate: ca.newCertificate(t, "/goroslib_sub"),
+				CAs:         ca.pool,
+			},
+			TopicAccessPolicies: policies,
+			DisableIntraProcess: true,
+		})
+		require.NoError(t, err)
+		defer nsub.Close()
+
+		recv := make(chan *std_msgs.Int64)
+		sub, err := NewSubscriber(SubscriberConf{
+			Node:  nsub,
+			Topic: "test_topic",
+			Callback: func(msg *std_msgs.Int64) {
+				recv <- msg
+			},
+		})
+		require.NoError(t, err)
+		defer sub.Close()
+
+		select {
+		case msg := <-recv:
+			require.Equal(t, &std_msgs.Int64{Data: 5}, msg)
+		case <-time.After(5 * time.Second):
+			t.Errorf("message not received")
+		}
+
+		_, err = NewPublisher(PublisherConf{
+			Node:  nsub,
+			Topic: "test_topic",
+			Msg:   &std_msgs.Int64{},
+		})
+		require.EqualError(t, err, "node '/goroslib_sub' is not allowed to publish on topic '/test_topic'")
+
+		_, err = NewSubscriber(SubscriberConf{
+			Node:     nsub,
+			Topic:    "test_topic",
+			Protocol: UDP,
+			Callback: func(msg *std_msgs.Int64) {},
+		})
+		require.EqualError(t, err, "UDP can't be used since TLS is enabled")
+
+		_, err = NewSubscriber(SubscriberConf{
+			Node:  nsub,
+			Topic: "test_topic",
+			TransportHints: &TransportHints{
+				Protocols: []Protocol{UDP, TCP},
+			},
+			Callback: func(msg *std_msgs.Int64) {},
+		})
+		require.EqualError(t, err, "invalid TransportHints: UDP can't be used since TLS is enabled")
+
+		sc, err := NewServiceClient(ServiceClientConf{
+			Node: nsub,
+			Name: "test_srv",
+			Srv:  &std_srvs.Trigger{},
+		})
+		require.NoError(t, err)
+		defer sc.Close()
+
+		res := std_srvs.TriggerRes{}
+		err = sc.Call(&std_srvs.TriggerReq{}, &res)
+		require.NoError(t, err)
+		require.Equal(t, true, res.Success)
+	})
+
+	t.Run("not allowed", func(t *testing.T) {
+		nsub, err := NewNode(NodeConf{
+			Name:          "goroslib_sub2",
+			MasterAddress: m.IP() + ":11311",
+			TLS: &NodeTLSConf{
+				Certificate: ca.newCertificate(t, "/goroslib_sub2"),
+				CAs:         ca.pool,
+			},
+			DisableIntraProcess: true,
+		})
+		require.NoError(t, err)
+		defer nsub.Close()
+
+		recv := make(chan struct{})
+		sub, err := NewSubscriber(SubscriberConf{
+			Node:  nsub,
+			Topic: "test_topic",
+			Callback: func(msg *std_msgs.Int64) {
+				close(recv)
+			},
+		})
+		require.NoError(t, err)
+		defer sub.Close()
+
+		select {
+		case <-recv:
+			t.Errorf("message received")
+		case <-time.After(1 * time.Second):
+		}
+	})
+
+	t.Run("untrusted certificate", func(t *testing.T) {
+		ca2 := newTestCA(t)
+
+		nsub, err := NewNode(NodeConf{
+			Name:          "goroslib_sub",
+			MasterAddress: m.IP() + ":11311",
+			TLS: &NodeTLSConf{
+				Certificate: ca2.newCertificate(t, "/goroslib_sub"),
+				CAs:         ca2.pool,
+			},
+			DisableIntraProcess: true,
+		})
+		require.NoError(t, err)
+		defer nsub.Close()
+
+		recv := make(chan struct{})
+		sub, err := NewSubscriber(SubscriberConf{
+			Node:  nsub,
+			Topic: "test_topic",
+			Callback: func(msg *std_msgs.Int64) {
+				close(recv)
+			},
+		})
+		require.NoError(t, err)
+		defer sub.Close()
+
+		select {
+		case <-recv:
+			t.Errorf("message received")
+		case <-time.After(1 * time.Second):
+		}
+	})
+}
+
+func TestNodeTopicAccessIntraProcess(t *testing.T) {
+	for _, ca := range []string{
+		"allowed",
+		"subscriber not allowed",
+		"publisher not allowed",
+	} {
+		t.Run(ca, func(t *testing.T) {
+			m, err := newContainerMaster()
+			require.NoError(t, err)
+			defer m.close()
+
+			npub, err := NewNode(NodeConf{
+				Name:          "goroslib_pub",
+				MasterAddress: m.IP() + ":11311",
+				TopicAccessPolicies: map[string]TopicAccessPolicy{
+					"/test_topic": {
+						Subscribers: []string{"/goroslib_sub"},
+					},
+				},
+			})
+			require.NoError(t, err)
+			defer npub.Close()
+
+			pub, err := NewPublisher(PublisherConf{
+				Node:  npub,
+				Topic: "test_topic",
+				Msg:   &std_msgs.Int64{},
+				Latch: true,
+			})
+			require.NoError(t, err)
+			defer pub.Close()
+
+			pub.Write(&std_msgs.Int64{Data: 5})
+
+			subConf := NodeConf{
+				Name:          "goroslib_sub",
+				MasterAddress: m.IP() + ":11311",
+			}
+
+			switch ca {
+			case "subscriber not allowed":
+				subConf.Name = "goroslib_sub2"
+
+			case "publisher not allowed":
+				subConf.TopicAccessPolicies = map[string]TopicAccessPolicy{
+					"/test_topic": {
+						Publishers: []string{"/goroslib_pub2"},
+					},
+				}
+			}
+
+			nsub, err := NewNode(subConf)
+			require.NoError(t, err)
+			defer nsub.Close()
+
+			recv := make(chan *std_msgs.Int64, 1)
+			sub, err := NewSubscriber(SubscriberConf{
+				Node:  nsub,
+				Topic: "test_topic",
+				Callback: func(msg *std_msgs.Int64) {
+					recv <- msg
+				},
+			})
+			require.NoError(t, err)
+			defer sub.Close()
+
+			if ca == "allowed" {
+				require.Equal(t, &std_msgs.Int64{Data: 5}, <-recv)
+
+				conns, err := npub.NodeGetConns("/goroslib_sub")
+				require.NoError(t, err)
+				require.Equal(t, "INTRAPROCESS", conns[0].Transport)
+			} else {
+				select {
+				case <-recv:
+					t.Errorf("message received")
+				case <-time.After(1 * time.Second):
+				}
+			}
+		})
+	}
+}
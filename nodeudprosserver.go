@@ -1,9 +1,25 @@
 package goroslib
 
 import (
+	"net"
 	"sync"
+
+	"github.com/aler9/goroslib/pkg/protoudp"
+)
+
+const (
+	// minimum and maximum values of NodeConf.UdprosMaxDatagramSize.
+	udprosMinDatagramSize = 64
+	udprosMaxDatagramSize = 65507
 )
 
+// udprosMulticastGroup is a multicast group joined by one or more subscribers.
+type udprosMulticastGroup struct {
+	addr   *net.UDPAddr
+	server *protoudp.Server
+	refs   int
+}
+
 func (n *Node) runUdprosServer(wg *sync.WaitGroup) {
 	defer wg.Done()
 
@@ -14,8 +30,62 @@ func (n *Node) runUdprosServer(wg *sync.WaitGroup) {
 		}
 
 		select {
-		case n.udpFrame <- udpFrameReq{frame, source}:
+		case n.udpFrame <- udpFrameReq{frame, source, nil}:
+		case <-n.ctx.Done():
+		}
+	}
+}
+
+func (n *Node) runUdprosMulticastServer(g *udprosMulticastGroup, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for {
+		frame, source, err := g.server.ReadFrame()
+		if err != nil {
+			break
+		}
+
+		select {
+		case n.udpFrame <- udpFrameReq{frame, source, g.addr}:
 		case <-n.ctx.Done():
 		}
 	}
 }
+
+func (n *Node) joinUdprosMulticastGroup(addr *net.UDPAddr, wg *sync.WaitGroup) error {
+	if g, ok := n.udprosMulticastGroups[addr.String()]; ok {
+		g.refs++
+		return nil
+	}
+
+	server, err := protoudp.NewMulticastServer(addr, n.udprosMulticastIface,
+		n.conf.UdprosSocketBufferSize)
+	if err != nil {
+		return err
+	}
+
+	g := &udprosMulticastGroup{
+		addr:   addr,
+		server: server,
+		refs:   1,
+	}
+	n.udprosMulticastGroups[addr.String()] = g
+
+	wg.Add(1)
+	go n.runUdprosMulticastServer(g, wg)
+
+	return nil
+}
+
+func (n *Node) leaveUdprosMulticastGroup(addr *net.UDPAddr) {
+	g, ok := n.udprosMulticastGroups[addr.String()]
+	if !ok {
+		return
+	}
+
+	g.refs--
+	if g.refs == 0 {
+		delete(n.udprosMulticastGroups, addr.String())
+		g.server.Close()
+	}
+}
@@ -0,0 +1,130 @@
+package goroslib
+
+import (
+	"context"
+	"fmt"
+)
+
+type paramSubscriberUpdate struct {
+	key   string
+	value interface{}
+}
+
+// ParamSubscriberConf is the configuration of a ParamSubscriber.
+type ParamSubscriberConf struct {
+	// parent node.
+	Node *Node
+
+	// name of the parameter, or of a namespace of parameters.
+	Key string
+
+	// function that will be called with the current value of the parameter
+	// when the subscriber is created, and whenever the parameter, or a parameter
+	// inside its namespace, changes. It receives the absolute name of the
+	// parameter that changed and its value, that is an empty map if the parameter
+	// is not set or has been deleted.
+	// Changes performed by the parent node are not notified by the master.
+	Callback func(key string, value interface{})
+}
+
+// ParamSubscriber is a ROS parameter subscriber, an entity that is notified
+// by the master whenever a parameter changes.
+type ParamSubscriber struct {
+	conf ParamSubscriberConf
+
+	ctx       context.Context
+	ctxCancel func()
+	key       string
+	value     interface{}
+
+	// in
+	update chan paramSubscriberUpdate
+
+	// out
+	done chan struct{}
+}
+
+// NewParamSubscriber allocates a ParamSubscriber. See ParamSubscriberConf for the options.
+func NewParamSubscriber(conf ParamSubscriberConf) (*ParamSubscriber, error) {
+	if conf.Node == nil {
+		return nil, fmt.Errorf("Node is empty")
+	}
+
+	if conf.Key == "" {
+		return nil, fmt.Errorf("Key is empty")
+	}
+
+	if conf.Callback == nil {
+		return nil, fmt.Errorf("Callback is empty")
+	}
+
+	ctx, ctxCancel := context.WithCancel(conf.Node.ctx)
+
+	ps := &ParamSubscriber{
+		conf:      conf,
+		ctx:       ctx,
+		ctxCancel: ctxCancel,
+		key:       conf.Node.absoluteParamName(conf.Key),
+		update:    make(chan paramSubscriberUpdate),
+		done:      make(chan struct{}),
+	}
+
+	cerr := make(chan error)
+	select {
+	case conf.Node.paramSubscriberNew <- paramSubscriberNewReq{
+		ps:  ps,
+		err: cerr,
+	}:
+		err := <-cerr
+		if err != nil {
+			return nil, err
+		}
+
+	case <-ps.ctx.Done():
+		return nil, fmt.Errorf("terminated")
+	}
+
+	go ps.run()
+
+	return ps, nil
+}
+
+// Close closes a ParamSubscriber and shuts down all its operations.
+func (ps *ParamSubscriber) Close() error {
+	ps.ctxCancel()
+	<-ps.done
+	return nil
+}
+
+func (ps *ParamSubscriber) run() {
+	defer close(ps.done)
+
+	// the value returned by the master when subscribing is set
+	// by the node before the subscriber is started.
+	ps.conf.Callback(ps.key, ps.value)
+
+outer:
+	for {
+		select {
+		case u := <-ps.update:
+			ps.conf.Callback(u.key, u.value)
+
+		case <-ps.ctx.Done():
+			break outer
+		}
+	}
+
+	ps.ctxCancel()
+
+	select {
+	case ps.conf.Node.paramSubscriberClose <- ps:
+	case <-ps.conf.Node.ctx.Done():
+	}
+}
+
+func (ps *ParamSubscriber) onUpdate(key string, value interface{}) {
+	select {
+	case ps.update <- paramSubscriberUpdate{key, value}:
+	case <-ps.ctx.Done():
+	}
+}
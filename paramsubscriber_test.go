@@ -0,0 +1,76 @@
+package goroslib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParamSubscriber(t *testing.T) {
+	m, err := newContainerMaster()
+	require.NoError(t, err)
+	defer m.close()
+
+	n, err := NewNode(NodeConf{
+		Namespace:     "/myns",
+		Name:          "goroslib",
+		MasterAddress: m.IP() + ":11311",
+	})
+	require.NoError(t, err)
+	defer n.Close()
+
+	type update struct {
+		key   string
+		value interface{}
+	}
+
+	recv := make(chan update)
+
+	ps, err := NewParamSubscriber(ParamSubscriberConf{
+		Node: n,
+		Key:  "test_int",
+		Callback: func(key string, value interface{}) {
+			recv <- update{key, value}
+		},
+	})
+	require.NoError(t, err)
+	defer ps.Close()
+
+	require.Equal(t, update{"/myns/test_int", map[string]interface{}{}}, <-recv)
+
+	// the master does not notify the node that changed the parameter
+	n2, err := NewNode(NodeConf{
+		Namespace:     "/myns",
+		Name:          "goroslib_set",
+		MasterAddress: m.IP() + ":11311",
+	})
+	require.NoError(t, err)
+	defer n2.Close()
+
+	err = n2.ParamSetInt("test_int", 123)
+	require.NoError(t, err)
+
+	require.Equal(t, update{"/myns/test_int", 123}, <-recv)
+}
+
+func TestParamSubscriberErrors(t *testing.T) {
+	_, err := NewParamSubscriber(ParamSubscriberConf{
+		Key:      "test_int",
+		Callback: func(string, interface{}) {},
+	})
+	require.EqualError(t, err, "Node is empty")
+
+	n := &Node{}
+
+	_, err = NewParamSubscriber(ParamSubscriberConf{
+		Node:     n,
+		Callback: func(string, interface{}) {},
+	})
+	require.EqualError(t, err, "Key is empty")
+
+	_, err = NewParamSubscriber(ParamSubscriberConf{
+		Node: n,
+		Key:  "test_int",
+	})
+	require.EqualError(t, err, "Callback is empty")
+}
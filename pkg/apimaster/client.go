@@ -1,7 +1,9 @@
 package apimaster
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
 
 	"github.com/aler9/goroslib/pkg/xmlrpc"
 )
@@ -14,12 +16,45 @@ type Client struct {
 
 // NewClient allocates a Client.
 func NewClient(address string, callerID string) *Client {
+	return NewClientWithHTTPClient(address, callerID, nil)
+}
+
+// NewClientWithHTTPClient allocates a Client that uses the given HTTP client
+// to perform requests. If httpClient is nil, a shared client with a timeout
+// of 10 seconds is used.
+func NewClientWithHTTPClient(address string, callerID string, httpClient *http.Client) *Client {
 	return &Client{
-		xc:       xmlrpc.NewClient(address),
+		xc:       xmlrpc.NewClientWithHTTPClient(address, httpClient),
 		callerID: callerID,
 	}
 }
 
+// ErrorCount returns the number of requests that failed because of
+// a network error, an invalid response or a fault.
+func (c *Client) ErrorCount() uint64 {
+	return c.xc.ErrorCount()
+}
+
+// GetPid writes a getPid request.
+func (c *Client) GetPid() (int, error) {
+	req := RequestGetPid{
+		c.callerID,
+	}
+	var res ResponseGetPid
+
+	err := c.xc.Do("getPid", req, &res)
+	if err != nil {
+		return 0, err
+	}
+
+	if res.Code != 1 {
+		return 0, fmt.Errorf("server returned an error (%d): %s", res.Code,
+			res.StatusMessage)
+	}
+
+	return res.Pid, nil
+}
+
 // GetPublishedTopics writes a getPublishedTopics request.
 func (c *Client) GetPublishedTopics(subgraph string) ([][]string, error) {
 	req := RequestGetPublishedTopics{
@@ -131,6 +166,66 @@ func (c *Client) LookupService(name string) (string, error) {
 	return c.lookup("lookupService", name)
 }
 
+func (c *Client) lookupMulti(method string, names []string) (map[string]string, error) {
+	ret := make(map[string]string)
+	if len(names) == 0 {
+		return ret, nil
+	}
+
+	calls := make([]*xmlrpc.Call, len(names))
+	ress := make([]ResponseLookup, len(names))
+	for i, name := range names {
+		calls[i] = &xmlrpc.Call{
+			Method: method,
+			ParamsReq: RequestLookup{
+				CallerID: c.callerID,
+				Name:     name,
+			},
+			ParamsRes: &ress[i],
+		}
+	}
+
+	err := c.xc.Multicall(calls)
+	if err != nil {
+		// the server does not support system.multicall; perform a request for each name
+		var fe *xmlrpc.FaultError
+		if !errors.As(err, &fe) {
+			return nil, err
+		}
+
+		for i, name := range names {
+			err := c.xc.Do(method, calls[i].ParamsReq, &ress[i])
+			if err != nil {
+				return nil, err
+			}
+
+			if ress[i].Code == 1 {
+				ret[name] = ress[i].URL
+			}
+		}
+		return ret, nil
+	}
+
+	for i, name := range names {
+		if calls[i].Err == nil && ress[i].Code == 1 {
+			ret[name] = ress[i].URL
+		}
+	}
+	return ret, nil
+}
+
+// LookupNodes writes lookupNode requests for multiple nodes,
+// in a single round trip. Nodes that can't be found are not returned.
+func (c *Client) LookupNodes(names []string) (map[string]string, error) {
+	return c.lookupMulti("lookupNode", names)
+}
+
+// LookupServices writes lookupService requests for multiple services,
+// in a single round trip. Services that can't be found are not returned.
+func (c *Client) LookupServices(names []string) (map[string]string, error) {
+	return c.lookupMulti("lookupService", names)
+}
+
 func (c *Client) register(method string, topic string, topicType string,
 	callerURL string) ([]string, error) {
 	req := RequestRegister{
@@ -1,6 +1,10 @@
 package apimaster
 
 import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -15,6 +19,9 @@ func TestClient(t *testing.T) {
 
 	go s.Serve(func(raw *xmlrpc.RequestRaw) interface{} {
 		switch raw.Method {
+		case "getPid":
+			return ResponseGetPid{Code: 1, Pid: 123}
+
 		case "getPublishedTopics":
 			return ResponseGetPublishedTopics{Code: 1, Topics: [][]string{{"mytopic"}}}
 
@@ -31,6 +38,13 @@ func TestClient(t *testing.T) {
 			return ResponseGetURI{Code: 1, MasterURI: "myuri"}
 
 		case "lookupNode":
+			var req RequestLookup
+			err := raw.Decode(&req)
+			require.NoError(t, err)
+
+			if req.Name == "unknown" {
+				return ResponseLookup{Code: -1, StatusMessage: "unknown node"}
+			}
 			return ResponseLookup{Code: 1, URL: "myurl"}
 
 		case "lookupService":
@@ -59,6 +73,12 @@ func TestClient(t *testing.T) {
 
 	c := NewClient("localhost:9997", "test")
 
+	func() {
+		res, err := c.GetPid()
+		require.NoError(t, err)
+		require.Equal(t, 123, res)
+	}()
+
 	func() {
 		res, err := c.GetPublishedTopics("mysubgraph")
 		require.NoError(t, err)
@@ -95,6 +115,18 @@ func TestClient(t *testing.T) {
 		require.Equal(t, "myurl", res)
 	}()
 
+	func() {
+		res, err := c.LookupNodes([]string{"mynode1", "unknown", "mynode2"})
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"mynode1": "myurl", "mynode2": "myurl"}, res)
+	}()
+
+	func() {
+		res, err := c.LookupServices([]string{"myservice1", "myservice2"})
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"myservice1": "myurl", "myservice2": "myurl"}, res)
+	}()
+
 	func() {
 		res, err := c.RegisterSubscriber("mytopic", "mytype", "myurl")
 		require.NoError(t, err)
@@ -128,9 +160,60 @@ func TestClient(t *testing.T) {
 	}()
 }
 
+const noMulticallFault = `<?xml version="1.0"?><methodResponse><fault><value><struct>` +
+	`<member><name>faultCode</name><value><int>1</int></value></member>` +
+	`<member><name>faultString</name><value><string>method not found</string></value></member>` +
+	`</struct></value></fault></methodResponse>`
+
+func TestClientWithoutMulticall(t *testing.T) {
+	hs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		byts, _ := io.ReadAll(r.Body)
+		body := string(byts)
+
+		switch {
+		case strings.Contains(body, "system.multicall"):
+			io.WriteString(w, noMulticallFault)
+
+		case strings.Contains(body, "<value>unknown</value>"):
+			io.WriteString(w, `<?xml version="1.0"?><methodResponse><params><param><value><array><data>`+
+				`<value><int>-1</int></value><value><string>unknown node</string></value>`+
+				`<value><string></string></value>`+
+				`</data></array></value></param></params></methodResponse>`)
+
+		case strings.Contains(body, "<value>broken</value>"):
+			w.WriteHeader(http.StatusBadRequest)
+
+		default:
+			io.WriteString(w, `<?xml version="1.0"?><methodResponse><params><param><value><array><data>`+
+				`<value><int>1</int></value><value><string></string></value>`+
+				`<value><string>myurl</string></value>`+
+				`</data></array></value></param></params></methodResponse>`)
+		}
+	}))
+	defer hs.Close()
+
+	c := NewClient(strings.TrimPrefix(hs.URL, "http://"), "test")
+
+	func() {
+		res, err := c.LookupNodes([]string{"mynode1", "unknown", "mynode2"})
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"mynode1": "myurl", "mynode2": "myurl"}, res)
+	}()
+
+	func() {
+		_, err := c.LookupNodes([]string{"mynode1", "broken"})
+		require.Error(t, err)
+	}()
+}
+
 func TestClientError(t *testing.T) {
 	c := NewClient("localhost:9997", "test")
 
+	func() {
+		_, err := c.GetPid()
+		require.Error(t, err)
+	}()
+
 	func() {
 		_, err := c.GetPublishedTopics("mysubgraph")
 		require.Error(t, err)
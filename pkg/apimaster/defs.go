@@ -3,6 +3,18 @@
 // https://wiki.ros.org/ROS/Master_API
 package apimaster
 
+// RequestGetPid is a getPid request.
+type RequestGetPid struct {
+	CallerID string
+}
+
+// ResponseGetPid is the response to a getPid request.
+type ResponseGetPid struct {
+	Code          int
+	StatusMessage string
+	Pid           int
+}
+
 // RequestGetPublishedTopics is a getPublishedTopics request.
 type RequestGetPublishedTopics struct {
 	CallerID string
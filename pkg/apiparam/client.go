@@ -1,7 +1,9 @@
 package apiparam
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
 
 	"github.com/aler9/goroslib/pkg/xmlrpc"
 )
@@ -14,12 +16,25 @@ type Client struct {
 
 // NewClient allocates a Client.
 func NewClient(address string, callerID string) *Client {
+	return NewClientWithHTTPClient(address, callerID, nil)
+}
+
+// NewClientWithHTTPClient allocates a Client that uses the given HTTP client
+// to perform requests. If httpClient is nil, a shared client with a timeout
+// of 10 seconds is used.
+func NewClientWithHTTPClient(address string, callerID string, httpClient *http.Client) *Client {
 	return &Client{
-		xc:       xmlrpc.NewClient(address),
+		xc:       xmlrpc.NewClientWithHTTPClient(address, httpClient),
 		callerID: callerID,
 	}
 }
 
+// ErrorCount returns the number of requests that failed because of
+// a network error, an invalid response or a fault.
+func (c *Client) ErrorCount() uint64 {
+	return c.xc.ErrorCount()
+}
+
 // DeleteParam writes a deleteParam request.
 func (c *Client) DeleteParam(key string) error {
 	req := RequestDeleteParam{
@@ -119,6 +134,56 @@ func (c *Client) GetParamString(key string) (string, error) {
 	return res.Res, nil
 }
 
+// GetParams writes getParam requests for multiple keys, in a single round trip.
+// Values can be of any type. Keys that are not set are not returned.
+func (c *Client) GetParams(keys []string) (map[string]interface{}, error) {
+	ret := make(map[string]interface{})
+	if len(keys) == 0 {
+		return ret, nil
+	}
+
+	calls := make([]*xmlrpc.Call, len(keys))
+	ress := make([]ResponseGetParam, len(keys))
+	for i, key := range keys {
+		calls[i] = &xmlrpc.Call{
+			Method: "getParam",
+			ParamsReq: RequestGetParam{
+				CallerID: c.callerID,
+				Key:      key,
+			},
+			ParamsRes: &ress[i],
+		}
+	}
+
+	err := c.xc.Multicall(calls)
+	if err != nil {
+		// the server does not support system.multicall; perform a request for each key
+		var fe *xmlrpc.FaultError
+		if !errors.As(err, &fe) {
+			return nil, err
+		}
+
+		for i, key := range keys {
+			err := c.xc.Do("getParam", calls[i].ParamsReq, &ress[i])
+			if err != nil {
+				return nil, err
+			}
+
+			if ress[i].Code == 1 {
+				ret[key] = ress[i].Res
+			}
+		}
+		return ret, nil
+	}
+
+	for i, key := range keys {
+		if calls[i].Err == nil && ress[i].Code == 1 {
+			ret[key] = ress[i].Res
+		}
+	}
+	return ret, nil
+}
+
 // HasParam writes a hasParam request.
 func (c *Client) HasParam(key string) (bool, error) {
 	req := RequestHasParam{
@@ -163,6 +228,50 @@ func (c *Client) SearchParam(key string) (string, error) {
 	return res.FoundKey, nil
 }
 
+// SubscribeParam writes a subscribeParam request.
+// It returns the current value of the parameter, that is an empty map
+// if the parameter is not set.
+func (c *Client) SubscribeParam(callerURL string, key string) (interface{}, error) {
+	req := RequestSubscribeParam{
+		CallerID:  c.callerID,
+		CallerURL: callerURL,
+		Key:       key,
+	}
+	var res ResponseSubscribeParam
+
+	err := c.xc.Do("subscribeParam", req, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.Code != 1 {
+		return nil, fmt.Errorf("server returned an error (%d): %s", res.Code, res.StatusMessage)
+	}
+
+	return res.Value, nil
+}
+
+// UnsubscribeParam writes a unsubscribeParam request.
+func (c *Client) UnsubscribeParam(callerURL string, key string) error {
+	req := RequestUnsubscribeParam{
+		CallerID:  c.callerID,
+		CallerURL: callerURL,
+		Key:       key,
+	}
+	var res ResponseUnsubscribeParam
+
+	err := c.xc.Do("unsubscribeParam", req, &res)
+	if err != nil {
+		return err
+	}
+
+	if res.Code != 1 {
+		return fmt.Errorf("server returned an error (%d): %s", res.Code, res.StatusMessage)
+	}
+
+	return nil
+}
+
 // SetParamBool writes a setParam request.
 func (c *Client) SetParamBool(key string, val bool) error {
 	req := RequestParamSetBool{
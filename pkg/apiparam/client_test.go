@@ -1,6 +1,10 @@
 package apiparam
 
 import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -45,6 +49,20 @@ func TestClient(t *testing.T) {
 
 		case "setParam":
 			return ResponseSetParam{Code: 1}
+
+		case "subscribeParam":
+			var req RequestSubscribeParam
+			err := raw.Decode(&req)
+			require.NoError(t, err)
+			require.Equal(t, RequestSubscribeParam{
+				CallerID:  "test",
+				CallerURL: "http://myurl",
+				Key:       "mykey",
+			}, req)
+			return ResponseSubscribeParam{Code: 1, Value: 123}
+
+		case "unsubscribeParam":
+			return ResponseUnsubscribeParam{Code: 1, NumUnsubscribed: 1}
 		}
 		return xmlrpc.ErrorRes{}
 	})
@@ -80,6 +98,16 @@ func TestClient(t *testing.T) {
 		require.Equal(t, "mystring", res)
 	}()
 
+	func() {
+		res, err := c.GetParams([]string{"mykey1", "mykey2", "mykey3", "mykey4"})
+		require.NoError(t, err)
+		require.Equal(t, map[string]interface{}{
+			"mykey1": true,
+			"mykey2": 123,
+			"mykey3": "mystring",
+		}, res)
+	}()
+
 	func() {
 		res, err := c.HasParam("mykey")
 		require.NoError(t, err)
@@ -106,6 +134,63 @@ func TestClient(t *testing.T) {
 		err := c.SetParamString("mykey", "myval")
 		require.NoError(t, err)
 	}()
+
+	func() {
+		res, err := c.SubscribeParam("http://myurl", "mykey")
+		require.NoError(t, err)
+		require.Equal(t, 123, res)
+	}()
+
+	func() {
+		err := c.UnsubscribeParam("http://myurl", "mykey")
+		require.NoError(t, err)
+	}()
+}
+
+const noMulticallFault = `<?xml version="1.0"?><methodResponse><fault><value><struct>` +
+	`<member><name>faultCode</name><value><int>1</int></value></member>` +
+	`<member><name>faultString</name><value><string>method not found</string></value></member>` +
+	`</struct></value></fault></methodResponse>`
+
+func TestClientWithoutMulticall(t *testing.T) {
+	hs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		byts, _ := io.ReadAll(r.Body)
+		body := string(byts)
+
+		switch {
+		case strings.Contains(body, "system.multicall"):
+			io.WriteString(w, noMulticallFault)
+
+		case strings.Contains(body, "<value>mykey1</value>"):
+			io.WriteString(w, `<?xml version="1.0"?><methodResponse><params><param><value><array><data>`+
+				`<value><int>1</int></value><value><string></string></value>`+
+				`<value><boolean>1</boolean></value>`+
+				`</data></array></value></param></params></methodResponse>`)
+
+		case strings.Contains(body, "<value>mykey2</value>"):
+			io.WriteString(w, `<?xml version="1.0"?><methodResponse><params><param><value><array><data>`+
+				`<value><int>-1</int></value><value><string>parameter not set</string></value>`+
+				`<value><int>0</int></value>`+
+				`</data></array></value></param></params></methodResponse>`)
+
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer hs.Close()
+
+	c := NewClient(strings.TrimPrefix(hs.URL, "http://"), "test")
+
+	func() {
+		res, err := c.GetParams([]string{"mykey1", "mykey2"})
+		require.NoError(t, err)
+		require.Equal(t, map[string]interface{}{"mykey1": true}, res)
+	}()
+
+	func() {
+		_, err := c.GetParams([]string{"mykey1", "mykey3"})
+		require.Error(t, err)
+	}()
 }
 
 func TestClientError(t *testing.T) {
@@ -160,4 +245,14 @@ func TestClientError(t *testing.T) {
 		err := c.SetParamString("mykey", "myval")
 		require.Error(t, err)
 	}()
+
+	func() {
+		_, err := c.SubscribeParam("http://myurl", "mykey")
+		require.Error(t, err)
+	}()
+
+	func() {
+		err := c.UnsubscribeParam("http://myurl", "mykey")
+		require.Error(t, err)
+	}()
 }
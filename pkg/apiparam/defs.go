@@ -34,6 +34,13 @@ type RequestGetParam struct {
 	Key      string
 }
 
+// ResponseGetParam is the response to a getParam request, with a value of any type.
+type ResponseGetParam struct {
+	Code          int
+	StatusMessage string
+	Res           interface{}
+}
+
 // ResponseGetParamBool is the response to a getParam request.
 type ResponseGetParamBool struct {
 	Code          int
@@ -81,6 +88,34 @@ type ResponseSearchParam struct {
 	FoundKey      string
 }
 
+// RequestSubscribeParam is a subscribeParam request.
+type RequestSubscribeParam struct {
+	CallerID  string
+	CallerURL string
+	Key       string
+}
+
+// ResponseSubscribeParam is the response to a subscribeParam request.
+type ResponseSubscribeParam struct {
+	Code          int
+	StatusMessage string
+	Value         interface{}
+}
+
+// RequestUnsubscribeParam is a unsubscribeParam request.
+type RequestUnsubscribeParam struct {
+	CallerID  string
+	CallerURL string
+	Key       string
+}
+
+// ResponseUnsubscribeParam is the response to a unsubscribeParam request.
+type ResponseUnsubscribeParam struct {
+	Code            int
+	StatusMessage   string
+	NumUnsubscribed int
+}
+
 // RequestParamSetBool is a setParam request.
 type RequestParamSetBool struct {
 	CallerID string
@@ -2,6 +2,7 @@ package apislave
 
 import (
 	"fmt"
+	"net/http"
 
 	"github.com/aler9/goroslib/pkg/xmlrpc"
 )
@@ -14,8 +15,15 @@ type Client struct {
 
 // NewClient allocates a Client.
 func NewClient(address string, callerID string) *Client {
+	return NewClientWithHTTPClient(address, callerID, nil)
+}
+
+// NewClientWithHTTPClient allocates a Client that uses the given HTTP client
+// to perform requests. If httpClient is nil, a shared client with a timeout
+// of 10 seconds is used.
+func NewClientWithHTTPClient(address string, callerID string, httpClient *http.Client) *Client {
 	return &Client{
-		xc:       xmlrpc.NewClient(address),
+		xc:       xmlrpc.NewClientWithHTTPClient(address, httpClient),
 		callerID: callerID,
 	}
 }
@@ -99,3 +107,100 @@ func (c *Client) GetBusInfo() ([][]interface{}, error) {
 
 	return res.BusInfo, nil
 }
+
+// GetBusStats writes a getBusStats request.
+func (c *Client) GetBusStats() (*BusStats, error) {
+	req := RequestGetBusStats{
+		CallerID: c.callerID,
+	}
+	var res ResponseGetBusStats
+
+	err := c.xc.Do("getBusStats", req, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.Code != 1 {
+		return nil, fmt.Errorf("server returned an error (%d): %s", res.Code, res.StatusMessage)
+	}
+
+	return &res.Stats, nil
+}
+
+// GetMasterURI writes a getMasterUri request.
+func (c *Client) GetMasterURI() (string, error) {
+	req := RequestGetMasterURI{
+		CallerID: c.callerID,
+	}
+	var res ResponseGetMasterURI
+
+	err := c.xc.Do("getMasterUri", req, &res)
+	if err != nil {
+		return "", err
+	}
+
+	if res.Code != 1 {
+		return "", fmt.Errorf("server returned an error (%d): %s", res.Code, res.StatusMessage)
+	}
+
+	return res.MasterURI, nil
+}
+
+// GetPublications writes a getPublications request.
+func (c *Client) GetPublications() ([][]string, error) {
+	req := RequestGetPublications{
+		CallerID: c.callerID,
+	}
+	var res ResponseGetPublications
+
+	err := c.xc.Do("getPublications", req, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.Code != 1 {
+		return nil, fmt.Errorf("server returned an error (%d): %s", res.Code, res.StatusMessage)
+	}
+
+	return res.TopicList, nil
+}
+
+// GetSubscriptions writes a getSubscriptions request.
+func (c *Client) GetSubscriptions() ([][]string, error) {
+	req := RequestGetSubscriptions{
+		CallerID: c.callerID,
+	}
+	var res ResponseGetSubscriptions
+
+	err := c.xc.Do("getSubscriptions", req, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.Code != 1 {
+		return nil, fmt.Errorf("server returned an error (%d): %s", res.Code, res.StatusMessage)
+	}
+
+	return res.TopicList, nil
+}
+
+// ParamUpdate writes a paramUpdate request.
+func (c *Client) ParamUpdate(key string, value interface{}) error {
+	req := RequestParamUpdate{
+		CallerID: c.callerID,
+		Key:      key,
+		Value:    value,
+	}
+	var res ResponseParamUpdate
+
+	err := c.xc.Do("paramUpdate", req, &res)
+	if err != nil {
+		return err
+	}
+
+	if res.Code != 1 {
+		return fmt.Errorf("server returned an error (%d): %s", res.Code, res.StatusMessage)
+	}
+
+	return nil
+}
@@ -26,6 +26,31 @@ func TestClient(t *testing.T) {
 
 		case "getBusInfo":
 			return ResponseGetBusInfo{Code: 1}
+
+		case "getMasterUri":
+			return ResponseGetMasterURI{Code: 1, MasterURI: "http://localhost:11311/"}
+
+		case "getPublications":
+			return ResponseGetPublications{Code: 1, TopicList: [][]string{{"/mytopic", "mytype"}}}
+
+		case "getSubscriptions":
+			return ResponseGetSubscriptions{Code: 1, TopicList: [][]string{{"/mytopic2", "mytype2"}}}
+
+		case "paramUpdate":
+			return ResponseParamUpdate{Code: 1}
+
+		case "getBusStats":
+			return ResponseGetBusStats{Code: 1, Stats: BusStats{
+				PublishStats: []BusStatsPublisher{{
+					Topic:           "/mytopic",
+					MessageDataSent: 10,
+					Connections:     []BusStatsPublisherConnection{{1, 10, 2, 0, true}},
+				}},
+				SubscribeStats: []BusStatsSubscriber{{
+					Topic:       "/mytopic2",
+					Connections: []BusStatsSubscriberConnection{{2, 20, 4, 1, true}},
+				}},
+			}}
 		}
 		return xmlrpc.ErrorRes{}
 	})
@@ -54,6 +79,45 @@ func TestClient(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, [][]interface{}(nil), res)
 	}()
+
+	func() {
+		res, err := c.GetBusStats()
+		require.NoError(t, err)
+		require.Equal(t, &BusStats{
+			PublishStats: []BusStatsPublisher{{
+				Topic:           "/mytopic",
+				MessageDataSent: 10,
+				Connections:     []BusStatsPublisherConnection{{1, 10, 2, 0, true}},
+			}},
+			SubscribeStats: []BusStatsSubscriber{{
+				Topic:       "/mytopic2",
+				Connections: []BusStatsSubscriberConnection{{2, 20, 4, 1, true}},
+			}},
+		}, res)
+	}()
+
+	func() {
+		res, err := c.GetMasterURI()
+		require.NoError(t, err)
+		require.Equal(t, "http://localhost:11311/", res)
+	}()
+
+	func() {
+		res, err := c.GetPublications()
+		require.NoError(t, err)
+		require.Equal(t, [][]string{{"/mytopic", "mytype"}}, res)
+	}()
+
+	func() {
+		res, err := c.GetSubscriptions()
+		require.NoError(t, err)
+		require.Equal(t, [][]string{{"/mytopic2", "mytype2"}}, res)
+	}()
+
+	func() {
+		err := c.ParamUpdate("/myparam", 123)
+		require.NoError(t, err)
+	}()
 }
 
 func TestClientError(t *testing.T) {
@@ -78,4 +142,29 @@ func TestClientError(t *testing.T) {
 		_, err := c.GetBusInfo()
 		require.Error(t, err)
 	}()
+
+	func() {
+		_, err := c.GetBusStats()
+		require.Error(t, err)
+	}()
+
+	func() {
+		_, err := c.GetMasterURI()
+		require.Error(t, err)
+	}()
+
+	func() {
+		_, err := c.GetPublications()
+		require.Error(t, err)
+	}()
+
+	func() {
+		_, err := c.GetSubscriptions()
+		require.Error(t, err)
+	}()
+
+	func() {
+		err := c.ParamUpdate("/myparam", 123)
+		require.Error(t, err)
+	}()
 }
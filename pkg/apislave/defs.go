@@ -29,6 +29,86 @@ type ResponseGetBusInfo struct {
 
 func (ResponseGetBusInfo) isResponse() {}
 
+// RequestGetBusStats is a getBusStats request.
+type RequestGetBusStats struct {
+	CallerID string
+}
+
+func (RequestGetBusStats) isRequest() {}
+
+// BusStatsPublisherConnection contains statistics about an outgoing connection.
+// It is encoded as [connID, bytes, messages, drops, connected], like
+// the connections of subscribers.
+type BusStatsPublisherConnection struct {
+	ID           int
+	BytesSent    int
+	MessagesSent int
+	Drops        int
+	Connected    bool
+}
+
+// BusStatsPublisher contains statistics about a published topic.
+type BusStatsPublisher struct {
+	Topic           string
+	MessageDataSent int
+	Connections     []BusStatsPublisherConnection
+}
+
+// BusStatsSubscriberConnection contains statistics about an incoming connection.
+// It is encoded as [connID, bytes, messages, drops, connected].
+type BusStatsSubscriberConnection struct {
+	ID               int
+	BytesReceived    int
+	MessagesReceived int
+	Drops            int
+	Connected        bool
+}
+
+// BusStatsSubscriber contains statistics about a subscribed topic.
+type BusStatsSubscriber struct {
+	Topic       string
+	Connections []BusStatsSubscriberConnection
+}
+
+// BusStatsService contains statistics about services.
+type BusStatsService struct {
+	NumRequests   int
+	BytesReceived int
+	BytesSent     int
+}
+
+// BusStats contains statistics about the connections of a node.
+type BusStats struct {
+	PublishStats   []BusStatsPublisher
+	SubscribeStats []BusStatsSubscriber
+	ServiceStats   BusStatsService
+}
+
+// ResponseGetBusStats is the response to a getBusStats request.
+type ResponseGetBusStats struct {
+	Code          int
+	StatusMessage string
+	Stats         BusStats
+}
+
+func (ResponseGetBusStats) isResponse() {}
+
+// RequestGetMasterURI is a getMasterUri request.
+type RequestGetMasterURI struct {
+	CallerID string
+}
+
+func (RequestGetMasterURI) isRequest() {}
+
+// ResponseGetMasterURI is the response to a getMasterUri request.
+type ResponseGetMasterURI struct {
+	Code          int
+	StatusMessage string
+	MasterURI     string
+}
+
+func (ResponseGetMasterURI) isResponse() {}
+
 // RequestGetPid is a getPid request.
 type RequestGetPid struct {
 	CallerID string
@@ -61,6 +141,40 @@ type ResponseGetPublications struct {
 
 func (ResponseGetPublications) isResponse() {}
 
+// RequestGetSubscriptions is a getSubscriptions request.
+type RequestGetSubscriptions struct {
+	CallerID string
+}
+
+func (RequestGetSubscriptions) isRequest() {}
+
+// ResponseGetSubscriptions is the response to a getSubscriptions request.
+type ResponseGetSubscriptions struct {
+	Code          int
+	StatusMessage string
+	TopicList     [][]string
+}
+
+func (ResponseGetSubscriptions) isResponse() {}
+
+// RequestParamUpdate is a paramUpdate request.
+type RequestParamUpdate struct {
+	CallerID string
+	Key      string
+	Value    interface{}
+}
+
+func (RequestParamUpdate) isRequest() {}
+
+// ResponseParamUpdate is the response to a paramUpdate request.
+type ResponseParamUpdate struct {
+	Code          int
+	StatusMessage string
+	Ignore        int
+}
+
+func (ResponseParamUpdate) isResponse() {}
+
 // RequestPublisherUpdate is a publisherUpdate request.
 type RequestPublisherUpdate struct {
 	CallerID      string
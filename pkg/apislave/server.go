@@ -1,6 +1,8 @@
 package apislave
 
 import (
+	"crypto/tls"
+
 	"github.com/aler9/goroslib/pkg/xmlrpc"
 )
 
@@ -17,7 +19,13 @@ type Server struct {
 
 // NewServer allocates a Server.
 func NewServer(address string) (*Server, error) {
-	xs, err := xmlrpc.NewServer(address)
+	return NewServerWithTLS(address, nil)
+}
+
+// NewServerWithTLS allocates a Server.
+// If tlsConf is not nil, requests are served with HTTPS.
+func NewServerWithTLS(address string, tlsConf *tls.Config) (*Server, error) {
+	xs, err := xmlrpc.NewServerWithTLS(address, tlsConf)
 	if err != nil {
 		return nil, err
 	}
@@ -47,12 +55,24 @@ func (s *Server) Serve(handler func(req Request) Response) {
 			case "getBusInfo":
 				return &RequestGetBusInfo{}
 
+			case "getBusStats":
+				return &RequestGetBusStats{}
+
+			case "getMasterUri":
+				return &RequestGetMasterURI{}
+
 			case "getPid":
 				return &RequestGetPid{}
 
 			case "getPublications":
 				return &RequestGetPublications{}
 
+			case "getSubscriptions":
+				return &RequestGetSubscriptions{}
+
+			case "paramUpdate":
+				return &RequestParamUpdate{}
+
 			case "publisherUpdate":
 				return &RequestPublisherUpdate{}
 
@@ -19,6 +19,14 @@ func TestServer(t *testing.T) {
 			require.Equal(t, &RequestGetBusInfo{CallerID: "mycaller"}, req)
 			return ResponseGetBusInfo{Code: 1}
 
+		case *RequestGetBusStats:
+			require.Equal(t, &RequestGetBusStats{CallerID: "mycaller"}, req)
+			return ResponseGetBusStats{Code: 1}
+
+		case *RequestGetMasterURI:
+			require.Equal(t, &RequestGetMasterURI{CallerID: "mycaller"}, req)
+			return ResponseGetMasterURI{Code: 1}
+
 		case *RequestGetPid:
 			require.Equal(t, &RequestGetPid{CallerID: "mycaller"}, req)
 			return ResponseGetPid{Code: 1}
@@ -27,6 +35,14 @@ func TestServer(t *testing.T) {
 			require.Equal(t, &RequestGetPublications{CallerID: "mycaller"}, req)
 			return ResponseGetPublications{Code: 1}
 
+		case *RequestGetSubscriptions:
+			require.Equal(t, &RequestGetSubscriptions{CallerID: "mycaller"}, req)
+			return ResponseGetSubscriptions{Code: 1}
+
+		case *RequestParamUpdate:
+			require.Equal(t, &RequestParamUpdate{CallerID: "mycaller", Key: "/myparam", Value: 123}, req)
+			return ResponseParamUpdate{Code: 1}
+
 		case *RequestPublisherUpdate:
 			require.Equal(t, &RequestPublisherUpdate{CallerID: "mycaller"}, req)
 			return ResponsePublisherUpdate{Code: 1}
@@ -52,6 +68,20 @@ func TestServer(t *testing.T) {
 		require.Equal(t, ResponseGetBusInfo{Code: 1}, res)
 	}()
 
+	func() {
+		var res ResponseGetBusStats
+		err = c.Do("getBusStats", RequestGetBusStats{CallerID: "mycaller"}, &res)
+		require.NoError(t, err)
+		require.Equal(t, ResponseGetBusStats{Code: 1}, res)
+	}()
+
+	func() {
+		var res ResponseGetMasterURI
+		err = c.Do("getMasterUri", RequestGetMasterURI{CallerID: "mycaller"}, &res)
+		require.NoError(t, err)
+		require.Equal(t, ResponseGetMasterURI{Code: 1}, res)
+	}()
+
 	func() {
 		var res ResponseGetPid
 		err = c.Do("getPid", RequestGetPid{CallerID: "mycaller"}, &res)
@@ -66,6 +96,20 @@ func TestServer(t *testing.T) {
 		require.Equal(t, ResponseGetPublications{Code: 1}, res)
 	}()
 
+	func() {
+		var res ResponseGetSubscriptions
+		err = c.Do("getSubscriptions", RequestGetSubscriptions{CallerID: "mycaller"}, &res)
+		require.NoError(t, err)
+		require.Equal(t, ResponseGetSubscriptions{Code: 1}, res)
+	}()
+
+	func() {
+		var res ResponseParamUpdate
+		err = c.Do("paramUpdate", RequestParamUpdate{CallerID: "mycaller", Key: "/myparam", Value: 123}, &res)
+		require.NoError(t, err)
+		require.Equal(t, ResponseParamUpdate{Code: 1}, res)
+	}()
+
 	func() {
 		var res ResponsePublisherUpdate
 		err = c.Do("publisherUpdate", RequestPublisherUpdate{CallerID: "mycaller"}, &res)
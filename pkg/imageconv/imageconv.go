@@ -0,0 +1,339 @@
+// Package imageconv contains functions to convert images from ROS messages to Golang and vice versa.
+package imageconv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"strings"
+
+	"github.com/aler9/goroslib/pkg/msgs/sensor_msgs"
+)
+
+// standard encodings.
+const (
+	EncodingRGB8   = "rgb8"
+	EncodingBGR8   = "bgr8"
+	EncodingRGBA8  = "rgba8"
+	EncodingBGRA8  = "bgra8"
+	EncodingMono8  = "mono8"
+	EncodingMono16 = "mono16"
+	Encoding16UC1  = "16UC1"
+	Encoding32FC1  = "32FC1"
+)
+
+// standard compressed formats.
+const (
+	CompressedFormatJPEG = "jpeg"
+	CompressedFormatPNG  = "png"
+)
+
+const (
+	jpegQuality = 95
+)
+
+func bytesPerPixel(encoding string) (int, error) {
+	switch encoding {
+	case EncodingMono8:
+		return 1, nil
+
+	case EncodingMono16, Encoding16UC1:
+		return 2, nil
+
+	case EncodingRGB8, EncodingBGR8:
+		return 3, nil
+
+	case EncodingRGBA8, EncodingBGRA8, Encoding32FC1:
+		return 4, nil
+	}
+
+	return 0, fmt.Errorf("unsupported encoding '%s'", encoding)
+}
+
+// GrayFloat32 is an in-memory image whose pixels are float32 values, like
+// the ones contained in 32FC1 images.
+// When read through the image.Image interface, values in the range [0, 1]
+// are mapped to color.Gray16 values.
+type GrayFloat32 struct {
+	// Pix holds the image's pixels.
+	// The pixel at (x, y) starts at Pix[(y-Rect.Min.Y)*Stride + (x-Rect.Min.X)].
+	Pix []float32
+
+	// Stride is the Pix stride (in pixels) between vertically adjacent pixels.
+	Stride int
+
+	// Rect is the image's bounds.
+	Rect image.Rectangle
+}
+
+// NewGrayFloat32 allocates a GrayFloat32 with the given bounds.
+func NewGrayFloat32(r image.Rectangle) *GrayFloat32 {
+	return &GrayFloat32{
+		Pix:    make([]float32, r.Dx()*r.Dy()),
+		Stride: r.Dx(),
+		Rect:   r,
+	}
+}
+
+// ColorModel implements image.Image.
+func (p *GrayFloat32) ColorModel() color.Model {
+	return color.Gray16Model
+}
+
+// Bounds implements image.Image.
+func (p *GrayFloat32) Bounds() image.Rectangle {
+	return p.Rect
+}
+
+// At implements image.Image.
+func (p *GrayFloat32) At(x, y int) color.Color {
+	v := float64(p.FloatAt(x, y))
+	switch {
+	case math.IsNaN(v) || v <= 0:
+		return color.Gray16{}
+
+	case v >= 1:
+		return color.Gray16{Y: 0xffff}
+	}
+
+	return color.Gray16{Y: uint16(v*0xffff + 0.5)}
+}
+
+// PixOffset returns the index of the first element of Pix that corresponds
+// to the pixel at (x, y).
+func (p *GrayFloat32) PixOffset(x, y int) int {
+	return (y-p.Rect.Min.Y)*p.Stride + (x - p.Rect.Min.X)
+}
+
+// FloatAt returns the value of the pixel at (x, y).
+func (p *GrayFloat32) FloatAt(x, y int) float32 {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return 0
+	}
+	return p.Pix[p.PixOffset(x, y)]
+}
+
+// SetFloat sets the value of the pixel at (x, y).
+func (p *GrayFloat32) SetFloat(x, y int, v float32) {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return
+	}
+	p.Pix[p.PixOffset(x, y)] = v
+}
+
+// Decode converts a sensor_msgs.Image into an image.Image.
+// The returned image is a *image.RGBA for rgb8 and bgr8, a *image.NRGBA for rgba8
+// and bgra8, a *image.Gray for mono8, a *image.Gray16 for mono16 and 16UC1,
+// a *GrayFloat32 for 32FC1.
+func Decode(msg *sensor_msgs.Image) (image.Image, error) {
+	bpp, err := bytesPerPixel(msg.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	w := int(msg.Width)
+	h := int(msg.Height)
+	step := int(msg.Step)
+
+	if step < (w * bpp) {
+		return nil, fmt.Errorf("step is too small (%d, expected at least %d)", step, w*bpp)
+	}
+
+	if len(msg.Data) < (step * h) {
+		return nil, fmt.Errorf("data is too small (%d, expected at least %d)", len(msg.Data), step*h)
+	}
+
+	var bo binary.ByteOrder = binary.LittleEndian
+	if msg.IsBigendian != 0 {
+		bo = binary.BigEndian
+	}
+
+	rect := image.Rect(0, 0, w, h)
+
+	switch msg.Encoding {
+	case EncodingRGB8, EncodingBGR8:
+		ri, bi := 0, 2
+		if msg.Encoding == EncodingBGR8 {
+			ri, bi = 2, 0
+		}
+
+		img := image.NewRGBA(rect)
+		for y := 0; y < h; y++ {
+			src := msg.Data[y*step:]
+			dst := img.Pix[y*img.Stride:]
+			for x := 0; x < w; x++ {
+				dst[x*4] = src[x*3+ri]
+				dst[x*4+1] = src[x*3+1]
+				dst[x*4+2] = src[x*3+bi]
+				dst[x*4+3] = 0xff
+			}
+		}
+		return img, nil
+
+	case EncodingRGBA8, EncodingBGRA8:
+		ri, bi := 0, 2
+		if msg.Encoding == EncodingBGRA8 {
+			ri, bi = 2, 0
+		}
+
+		img := image.NewNRGBA(rect)
+		for y := 0; y < h; y++ {
+			src := msg.Data[y*step:]
+			dst := img.Pix[y*img.Stride:]
+			for x := 0; x < w; x++ {
+				dst[x*4] = src[x*4+ri]
+				dst[x*4+1] = src[x*4+1]
+				dst[x*4+2] = src[x*4+bi]
+				dst[x*4+3] = src[x*4+3]
+			}
+		}
+		return img, nil
+
+	case EncodingMono8:
+		img := image.NewGray(rect)
+		for y := 0; y < h; y++ {
+			copy(img.Pix[y*img.Stride:y*img.Stride+w], msg.Data[y*step:])
+		}
+		return img, nil
+
+	case EncodingMono16, Encoding16UC1:
+		img := image.NewGray16(rect)
+		for y := 0; y < h; y++ {
+			src := msg.Data[y*step:]
+			dst := img.Pix[y*img.Stride:]
+			for x := 0; x < w; x++ {
+				// image.Gray16 stores pixels in big endian order
+				binary.BigEndian.PutUint16(dst[x*2:], bo.Uint16(src[x*2:]))
+			}
+		}
+		return img, nil
+	}
+
+	// Encoding32FC1
+	img := NewGrayFloat32(rect)
+	for y := 0; y < h; y++ {
+		src := msg.Data[y*step:]
+		dst := img.Pix[y*img.Stride:]
+		for x := 0; x < w; x++ {
+			dst[x] = math.Float32frombits(bo.Uint32(src[x*4:]))
+		}
+	}
+	return img, nil
+}
+
+// Encode converts an image.Image into a sensor_msgs.Image with the given encoding.
+// Rows are not padded and multi-byte values are written in little endian order.
+// The header of the returned message is left empty.
+func Encode(img image.Image, encoding string) (*sensor_msgs.Image, error) {
+	bpp, err := bytesPerPixel(encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	b := img.Bounds()
+	w := b.Dx()
+	h := b.Dy()
+	step := w * bpp
+	data := make([]byte, step*h)
+
+	for y := 0; y < h; y++ {
+		dst := data[y*step:]
+
+		for x := 0; x < w; x++ {
+			c := img.At(b.Min.X+x, b.Min.Y+y)
+
+			switch encoding {
+			case EncodingRGB8, EncodingBGR8:
+				nc := color.NRGBAModel.Convert(c).(color.NRGBA)
+				if encoding == EncodingRGB8 {
+					dst[x*3], dst[x*3+1], dst[x*3+2] = nc.R, nc.G, nc.B
+				} else {
+					dst[x*3], dst[x*3+1], dst[x*3+2] = nc.B, nc.G, nc.R
+				}
+
+			case EncodingRGBA8, EncodingBGRA8:
+				nc := color.NRGBAModel.Convert(c).(color.NRGBA)
+				if encoding == EncodingRGBA8 {
+					dst[x*4], dst[x*4+1], dst[x*4+2], dst[x*4+3] = nc.R, nc.G, nc.B, nc.A
+				} else {
+					dst[x*4], dst[x*4+1], dst[x*4+2], dst[x*4+3] = nc.B, nc.G, nc.R, nc.A
+				}
+
+			case EncodingMono8:
+				dst[x] = color.GrayModel.Convert(c).(color.Gray).Y
+
+			case EncodingMono16, Encoding16UC1:
+				binary.LittleEndian.PutUint16(dst[x*2:], color.Gray16Model.Convert(c).(color.Gray16).Y)
+
+			default: // Encoding32FC1
+				var v float32
+				if fimg, ok := img.(*GrayFloat32); ok {
+					v = fimg.FloatAt(b.Min.X+x, b.Min.Y+y)
+				} else {
+					v = float32(color.Gray16Model.Convert(c).(color.Gray16).Y) / 0xffff
+				}
+				binary.LittleEndian.PutUint32(dst[x*4:], math.Float32bits(v))
+			}
+		}
+	}
+
+	return &sensor_msgs.Image{
+		Height:      uint32(h),
+		Width:       uint32(w),
+		Encoding:    encoding,
+		IsBigendian: 0,
+		Step:        uint32(step),
+		Data:        data,
+	}, nil
+}
+
+// DecodeCompressed converts a sensor_msgs.CompressedImage into an image.Image.
+// Both JPEG and PNG formats are supported.
+func DecodeCompressed(msg *sensor_msgs.CompressedImage) (image.Image, error) {
+	// format can be in the form "jpeg" or "bgr8; jpeg compressed bgr8"
+	format := strings.ToLower(msg.Format)
+
+	switch {
+	case strings.Contains(format, "png"):
+		return png.Decode(bytes.NewReader(msg.Data))
+
+	case strings.Contains(format, "jpeg"), strings.Contains(format, "jpg"):
+		return jpeg.Decode(bytes.NewReader(msg.Data))
+	}
+
+	return nil, fmt.Errorf("unsupported format '%s'", msg.Format)
+}
+
+// EncodeCompressed converts an image.Image into a sensor_msgs.CompressedImage
+// with the given format (jpeg or png).
+// The header of the returned message is left empty.
+func EncodeCompressed(img image.Image, format string) (*sensor_msgs.CompressedImage, error) {
+	var buf bytes.Buffer
+
+	switch format {
+	case CompressedFormatJPEG:
+		err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+		if err != nil {
+			return nil, err
+		}
+
+	case CompressedFormatPNG:
+		err := png.Encode(&buf, img)
+		if err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported format '%s'", format)
+	}
+
+	return &sensor_msgs.CompressedImage{
+		Format: format,
+		Data:   buf.Bytes(),
+	}, nil
+}
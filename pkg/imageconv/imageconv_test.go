@@ -0,0 +1,294 @@
+package imageconv
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/aler9/goroslib/pkg/msgs/sensor_msgs"
+)
+
+var casesImage = []struct {
+	name string
+	msg  *sensor_msgs.Image
+	img  image.Image
+}{
+	{
+		"rgb8",
+		&sensor_msgs.Image{
+			Height:   1,
+			Width:    2,
+			Encoding: EncodingRGB8,
+			Step:     6,
+			Data:     []byte{1, 2, 3, 4, 5, 6},
+		},
+		&image.RGBA{
+			Pix:    []byte{1, 2, 3, 255, 4, 5, 6, 255},
+			Stride: 8,
+			Rect:   image.Rect(0, 0, 2, 1),
+		},
+	},
+	{
+		"bgr8",
+		&sensor_msgs.Image{
+			Height:   1,
+			Width:    2,
+			Encoding: EncodingBGR8,
+			Step:     6,
+			Data:     []byte{3, 2, 1, 6, 5, 4},
+		},
+		&image.RGBA{
+			Pix:    []byte{1, 2, 3, 255, 4, 5, 6, 255},
+			Stride: 8,
+			Rect:   image.Rect(0, 0, 2, 1),
+		},
+	},
+	{
+		"rgba8",
+		&sensor_msgs.Image{
+			Height:   1,
+			Width:    2,
+			Encoding: EncodingRGBA8,
+			Step:     8,
+			Data:     []byte{1, 2, 3, 4, 5, 6, 7, 8},
+		},
+		&image.NRGBA{
+			Pix:    []byte{1, 2, 3, 4, 5, 6, 7, 8},
+			Stride: 8,
+			Rect:   image.Rect(0, 0, 2, 1),
+		},
+	},
+	{
+		"bgra8",
+		&sensor_msgs.Image{
+			Height:   1,
+			Width:    2,
+			Encoding: EncodingBGRA8,
+			Step:     8,
+			Data:     []byte{3, 2, 1, 4, 7, 6, 5, 8},
+		},
+		&image.NRGBA{
+			Pix:    []byte{1, 2, 3, 4, 5, 6, 7, 8},
+			Stride: 8,
+			Rect:   image.Rect(0, 0, 2, 1),
+		},
+	},
+	{
+		"mono8",
+		&sensor_msgs.Image{
+			Height:   2,
+			Width:    2,
+			Encoding: EncodingMono8,
+			Step:     2,
+			Data:     []byte{1, 2, 3, 4},
+		},
+		&image.Gray{
+			Pix:    []byte{1, 2, 3, 4},
+			Stride: 2,
+			Rect:   image.Rect(0, 0, 2, 2),
+		},
+	},
+	{
+		"mono16",
+		&sensor_msgs.Image{
+			Height:   1,
+			Width:    2,
+			Encoding: EncodingMono16,
+			Step:     4,
+			Data:     []byte{0x02, 0x01, 0x04, 0x03},
+		},
+		&image.Gray16{
+			Pix:    []byte{0x01, 0x02, 0x03, 0x04},
+			Stride: 4,
+			Rect:   image.Rect(0, 0, 2, 1),
+		},
+	},
+	{
+		"16UC1",
+		&sensor_msgs.Image{
+			Height:   1,
+			Width:    2,
+			Encoding: Encoding16UC1,
+			Step:     4,
+			Data:     []byte{0x02, 0x01, 0x04, 0x03},
+		},
+		&image.Gray16{
+			Pix:    []byte{0x01, 0x02, 0x03, 0x04},
+			Stride: 4,
+			Rect:   image.Rect(0, 0, 2, 1),
+		},
+	},
+	{
+		"32FC1",
+		&sensor_msgs.Image{
+			Height:   1,
+			Width:    2,
+			Encoding: Encoding32FC1,
+			Step:     8,
+			Data:     []byte{0x00, 0x00, 0x80, 0x3f, 0x00, 0x00, 0x00, 0xbf},
+		},
+		&GrayFloat32{
+			Pix:    []float32{1, -0.5},
+			Stride: 2,
+			Rect:   image.Rect(0, 0, 2, 1),
+		},
+	},
+}
+
+func TestDecode(t *testing.T) {
+	for _, ca := range casesImage {
+		t.Run(ca.name, func(t *testing.T) {
+			img, err := Decode(ca.msg)
+			require.NoError(t, err)
+			require.Equal(t, ca.img, img)
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	for _, ca := range casesImage {
+		t.Run(ca.name, func(t *testing.T) {
+			msg, err := Encode(ca.img, ca.msg.Encoding)
+			require.NoError(t, err)
+			require.Equal(t, ca.msg, msg)
+		})
+	}
+}
+
+func TestDecodePadding(t *testing.T) {
+	img, err := Decode(&sensor_msgs.Image{
+		Height:   2,
+		Width:    2,
+		Encoding: EncodingMono8,
+		Step:     4,
+		Data:     []byte{1, 2, 0, 0, 3, 4, 0, 0},
+	})
+	require.NoError(t, err)
+	require.Equal(t, &image.Gray{
+		Pix:    []byte{1, 2, 3, 4},
+		Stride: 2,
+		Rect:   image.Rect(0, 0, 2, 2),
+	}, img)
+}
+
+func TestDecodeBigEndian(t *testing.T) {
+	img, err := Decode(&sensor_msgs.Image{
+		Height:      1,
+		Width:       1,
+		Encoding:    Encoding32FC1,
+		IsBigendian: 1,
+		Step:        4,
+		Data:        []byte{0x3f, 0x80, 0x00, 0x00},
+	})
+	require.NoError(t, err)
+	require.Equal(t, float32(1), img.(*GrayFloat32).FloatAt(0, 0))
+
+	img, err = Decode(&sensor_msgs.Image{
+		Height:      1,
+		Width:       1,
+		Encoding:    EncodingMono16,
+		IsBigendian: 1,
+		Step:        2,
+		Data:        []byte{0x01, 0x02},
+	})
+	require.NoError(t, err)
+	require.Equal(t, color.Gray16{Y: 0x0102}, img.At(0, 0))
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for _, ca := range []struct {
+		name string
+		msg  *sensor_msgs.Image
+		err  string
+	}{
+		{
+			"encoding",
+			&sensor_msgs.Image{
+				Encoding: "yuv422",
+			},
+			"unsupported encoding 'yuv422'",
+		},
+		{
+			"step",
+			&sensor_msgs.Image{
+				Height:   1,
+				Width:    2,
+				Encoding: EncodingRGB8,
+				Step:     3,
+				Data:     []byte{1, 2, 3},
+			},
+			"step is too small (3, expected at least 6)",
+		},
+		{
+			"data",
+			&sensor_msgs.Image{
+				Height:   2,
+				Width:    2,
+				Encoding: EncodingMono8,
+				Step:     2,
+				Data:     []byte{1, 2, 3},
+			},
+			"data is too small (3, expected at least 4)",
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			_, err := Decode(ca.msg)
+			require.EqualError(t, err, ca.err)
+		})
+	}
+}
+
+func TestCompressed(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			src.Set(x, y, color.RGBA{uint8(x * 16), uint8(y * 16), 128, 255})
+		}
+	}
+
+	t.Run("png", func(t *testing.T) {
+		msg, err := EncodeCompressed(src, CompressedFormatPNG)
+		require.NoError(t, err)
+		require.Equal(t, "png", msg.Format)
+
+		img, err := DecodeCompressed(msg)
+		require.NoError(t, err)
+		require.Equal(t, src.Bounds(), img.Bounds())
+		for y := 0; y < 16; y++ {
+			for x := 0; x < 16; x++ {
+				r1, g1, b1, a1 := src.At(x, y).RGBA()
+				r2, g2, b2, a2 := img.At(x, y).RGBA()
+				require.Equal(t, []uint32{r1, g1, b1, a1}, []uint32{r2, g2, b2, a2})
+			}
+		}
+	})
+
+	t.Run("jpeg", func(t *testing.T) {
+		msg, err := EncodeCompressed(src, CompressedFormatJPEG)
+		require.NoError(t, err)
+		require.Equal(t, "jpeg", msg.Format)
+
+		img, err := DecodeCompressed(msg)
+		require.NoError(t, err)
+		require.Equal(t, src.Bounds(), img.Bounds())
+	})
+
+	t.Run("image_transport format", func(t *testing.T) {
+		msg, err := EncodeCompressed(src, CompressedFormatJPEG)
+		require.NoError(t, err)
+		msg.Format = "bgr8; jpeg compressed bgr8"
+
+		_, err = DecodeCompressed(msg)
+		require.NoError(t, err)
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		_, err := EncodeCompressed(src, "tiff")
+		require.EqualError(t, err, "unsupported format 'tiff'")
+
+		_, err = DecodeCompressed(&sensor_msgs.CompressedImage{Format: "tiff"})
+		require.EqualError(t, err, "unsupported format 'tiff'")
+	})
+}
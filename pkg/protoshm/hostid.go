@@ -0,0 +1,20 @@
+package protoshm
+
+import (
+	"os"
+	"strings"
+)
+
+// HostID returns an identifier of the host and of its boot, that is used
+// to check whether two processes can share memory.
+func HostID() string {
+	hostname, _ := os.Hostname()
+
+	// on Linux, the boot ID distinguishes hosts with the same name
+	bootID, err := os.ReadFile("/proc/sys/kernel/random/boot_id")
+	if err != nil {
+		return hostname
+	}
+
+	return hostname + "/" + strings.TrimSpace(string(bootID))
+}
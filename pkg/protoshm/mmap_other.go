@@ -0,0 +1,17 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package protoshm
+
+import (
+	"fmt"
+	"os"
+)
+
+func mmap(f *os.File, size int) ([]byte, error) {
+	return nil, fmt.Errorf("shared memory is not supported on this platform")
+}
+
+func munmap(mem []byte) error {
+	return nil
+}
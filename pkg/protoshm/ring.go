@@ -0,0 +1,254 @@
+// Package protoshm implements a shared-memory transport, that allows processes
+// of the same host to exchange messages through a memory-mapped ring buffer.
+package protoshm
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"unsafe"
+)
+
+const (
+	magic = 0x31304d4853524f47 // "GORSHM01"
+
+	// positions are placed in separate cache lines,
+	// in order to avoid false sharing between the writer and the reader.
+	offsetMagic    = 0
+	offsetCapacity = 8
+	offsetWritePos = 64
+	offsetReadPos  = 128
+	headerSize     = 192
+
+	// records are aligned to 8 bytes and start with their length.
+	recordAlign     = 8
+	recordHeaderLen = 4
+
+	// length of the record that fills the end of the buffer
+	// when the next record does not fit in it.
+	paddingLen = 0xFFFFFFFF
+)
+
+// ErrFull is returned by Write when there's no space left in the buffer,
+// because the reader is slower than the writer.
+var ErrFull = errors.New("buffer is full")
+
+// ErrEmpty is returned by Next when there are no records available.
+var ErrEmpty = errors.New("buffer is empty")
+
+// errCorrupted is returned when positions or lengths stored in the shared memory
+// are not consistent, since the memory can be modified by the other process.
+var errCorrupted = errors.New("buffer is corrupted")
+
+func align(v uint64) uint64 {
+	return (v + recordAlign - 1) &^ (recordAlign - 1)
+}
+
+// Ring is a single-producer, single-consumer ring buffer stored in shared memory.
+// Write and read positions are sequence numbers that always increase,
+// and are used to compute the available space.
+type Ring struct {
+	path     string
+	mem      []byte
+	data     []byte
+	capacity uint64
+	writePos *uint64
+	readPos  *uint64
+	pending  uint64
+}
+
+func newRing(path string, mem []byte) *Ring {
+	return &Ring{
+		path:     path,
+		mem:      mem,
+		data:     mem[headerSize:],
+		capacity: binary.LittleEndian.Uint64(mem[offsetCapacity:]),
+		writePos: (*uint64)(unsafe.Pointer(&mem[offsetWritePos])),
+		readPos:  (*uint64)(unsafe.Pointer(&mem[offsetReadPos])),
+	}
+}
+
+// Create allocates a Ring with the given capacity, in a new file of dir.
+// If dir is empty, /dev/shm is used if available, otherwise the temporary directory.
+func Create(dir string, capacity int) (*Ring, error) {
+	if capacity <= 0 || capacity%recordAlign != 0 {
+		return nil, fmt.Errorf("capacity must be a positive multiple of %d", recordAlign)
+	}
+
+	if dir == "" {
+		dir = "/dev/shm"
+		if _, err := os.Stat(dir); err != nil {
+			dir = os.TempDir()
+		}
+	}
+
+	var rnd [8]byte
+	_, err := rand.Read(rnd[:])
+	if err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(dir, "goroslib-"+hex.EncodeToString(rnd[:]))
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	err = f.Truncate(int64(headerSize + capacity))
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+
+	mem, err := mmap(f, headerSize+capacity)
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+
+	binary.LittleEndian.PutUint64(mem[offsetMagic:], magic)
+	binary.LittleEndian.PutUint64(mem[offsetCapacity:], uint64(capacity))
+
+	return newRing(path, mem), nil
+}
+
+// Open opens a Ring created by another process.
+func Open(path string) (*Ring, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if fi.Size() < headerSize {
+		return nil, fmt.Errorf("invalid size")
+	}
+
+	mem, err := mmap(f, int(fi.Size()))
+	if err != nil {
+		return nil, err
+	}
+
+	if binary.LittleEndian.Uint64(mem[offsetMagic:]) != magic ||
+		binary.LittleEndian.Uint64(mem[offsetCapacity:]) != uint64(len(mem)-headerSize) {
+		munmap(mem)
+		return nil, fmt.Errorf("invalid header")
+	}
+
+	return newRing(path, mem), nil
+}
+
+// Close unmaps the Ring.
+func (r *Ring) Close() error {
+	return munmap(r.mem)
+}
+
+// Path returns the path of the file that contains the Ring.
+func (r *Ring) Path() string {
+	return r.path
+}
+
+// Remove removes the file that contains the Ring.
+// The Ring can still be used by processes that already opened it.
+func (r *Ring) Remove() error {
+	return os.Remove(r.path)
+}
+
+// Write writes a record. It must not be called by multiple routines at once.
+func (r *Ring) Write(payload []byte) error {
+	need := align(recordHeaderLen + uint64(len(payload)))
+	if need > r.capacity {
+		return fmt.Errorf("record is too big (%d bytes, maximum is %d)",
+			len(payload), r.capacity-recordHeaderLen)
+	}
+
+	w := atomic.LoadUint64(r.writePos)
+	rd := atomic.LoadUint64(r.readPos)
+
+	if w%recordAlign != 0 || rd > w {
+		return errCorrupted
+	}
+
+	// records are never split: if the record does not fit in the end of the buffer,
+	// the end is filled with padding and the record is written at the beginning.
+	idx := w % r.capacity
+	tail := r.capacity - idx
+	total := need
+	if need > tail {
+		total += tail
+	}
+
+	if w+total-rd > r.capacity {
+		return ErrFull
+	}
+
+	if need > tail {
+		binary.LittleEndian.PutUint32(r.data[idx:], paddingLen)
+		w += tail
+		idx = 0
+	}
+
+	binary.LittleEndian.PutUint32(r.data[idx:], uint32(len(payload)))
+	copy(r.data[idx+recordHeaderLen:], payload)
+
+	// publish the record
+	atomic.StoreUint64(r.writePos, w+need)
+
+	return nil
+}
+
+// Next returns the next record, if available, without copying it.
+// The record remains valid until Release is called.
+// If there are no records available, ErrEmpty is returned.
+// It must not be called by multiple routines at once.
+func (r *Ring) Next() ([]byte, error) {
+	rd := atomic.LoadUint64(r.readPos)
+	w := atomic.LoadUint64(r.writePos)
+
+	for rd != w {
+		if rd > w || w-rd > r.capacity {
+			return nil, errCorrupted
+		}
+
+		idx := rd % r.capacity
+		if idx%recordAlign != 0 {
+			return nil, errCorrupted
+		}
+
+		l := binary.LittleEndian.Uint32(r.data[idx:])
+
+		if l == paddingLen {
+			rd += r.capacity - idx
+			atomic.StoreUint64(r.readPos, rd)
+			continue
+		}
+
+		need := align(recordHeaderLen + uint64(l))
+		if idx+recordHeaderLen+uint64(l) > r.capacity || need > w-rd {
+			return nil, errCorrupted
+		}
+
+		r.pending = need
+		return r.data[idx+recordHeaderLen : idx+recordHeaderLen+uint64(l)], nil
+	}
+
+	return nil, ErrEmpty
+}
+
+// Release releases the record returned by Next, making its space available to the writer.
+func (r *Ring) Release() {
+	atomic.AddUint64(r.readPos, r.pending)
+	r.pending = 0
+}
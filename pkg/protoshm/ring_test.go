@@ -0,0 +1,121 @@
+package protoshm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRing(t *testing.T) {
+	w, err := Create(t.TempDir(), 64)
+	require.NoError(t, err)
+	defer w.Close()
+
+	r, err := Open(w.Path())
+	require.NoError(t, err)
+	defer r.Close()
+
+	err = w.Remove()
+	require.NoError(t, err)
+
+	_, err = r.Next()
+	require.Equal(t, ErrEmpty, err)
+
+	// 3 records of 24 bytes do not fit
+	require.NoError(t, w.Write(bytes.Repeat([]byte{1}, 20)))
+	require.NoError(t, w.Write(bytes.Repeat([]byte{2}, 20)))
+	require.Equal(t, ErrFull, w.Write(bytes.Repeat([]byte{3}, 20)))
+
+	byts, err := r.Next()
+	require.NoError(t, err)
+	require.Equal(t, bytes.Repeat([]byte{1}, 20), byts)
+	r.Release()
+
+	// the record does not fit in the end of the buffer and is written at the beginning
+	require.NoError(t, w.Write(bytes.Repeat([]byte{3}, 20)))
+
+	byts, err = r.Next()
+	require.NoError(t, err)
+	require.Equal(t, bytes.Repeat([]byte{2}, 20), byts)
+	r.Release()
+
+	byts, err = r.Next()
+	require.NoError(t, err)
+	require.Equal(t, bytes.Repeat([]byte{3}, 20), byts)
+	r.Release()
+
+	_, err = r.Next()
+	require.Equal(t, ErrEmpty, err)
+
+	require.EqualError(t, w.Write(make([]byte, 61)), "record is too big (61 bytes, maximum is 60)")
+}
+
+func TestRingConcurrent(t *testing.T) {
+	w, err := Create(t.TempDir(), 1024)
+	require.NoError(t, err)
+	defer w.Close()
+
+	r, err := Open(w.Path())
+	require.NoError(t, err)
+	defer r.Close()
+
+	const count = 2000
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		for i := 0; i < count; {
+			err := w.Write(bytes.Repeat([]byte{byte(i)}, i%100))
+			if err == ErrFull {
+				runtime.Gosched()
+				continue
+			}
+			require.NoError(t, err)
+			i++
+		}
+	}()
+
+	for i := 0; i < count; {
+		byts, err := r.Next()
+		if err == ErrEmpty {
+			runtime.Gosched()
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, bytes.Repeat([]byte{byte(i)}, i%100), byts)
+		r.Release()
+		i++
+	}
+
+	<-done
+}
+
+func TestRingCorrupted(t *testing.T) {
+	w, err := Create(t.TempDir(), 64)
+	require.NoError(t, err)
+	defer w.Close()
+
+	r, err := Open(w.Path())
+	require.NoError(t, err)
+	defer r.Close()
+
+	require.NoError(t, w.Write(bytes.Repeat([]byte{1}, 20)))
+
+	// overwrite the length of the record
+	binary.LittleEndian.PutUint32(w.data[0:], 1000)
+
+	_, err = r.Next()
+	require.EqualError(t, err, "buffer is corrupted")
+}
+
+func TestOpenErrors(t *testing.T) {
+	_, err := Create(t.TempDir(), 10)
+	require.EqualError(t, err, "capacity must be a positive multiple of 8")
+
+	_, err = Open("/nonexisting")
+	require.Error(t, err)
+}
@@ -1,6 +1,7 @@
 package prototcp
 
 import (
+	"crypto/tls"
 	"net"
 	"time"
 )
@@ -11,10 +12,31 @@ const (
 
 // NewClient connects to a TCPROS server and returns a Conn.
 func NewClient(address string) (*Conn, error) {
+	return NewClientWithTLS(address, nil)
+}
+
+// NewClientWithTLS connects to a TCPROS server and returns a Conn.
+// If tlsConf is not nil, the connection is encrypted with TLS
+// and the handshake is performed before returning.
+func NewClientWithTLS(address string, tlsConf *tls.Config) (*Conn, error) {
 	nconn, err := net.DialTimeout("tcp", address, dialTimeout)
 	if err != nil {
 		return nil, err
 	}
 
+	if tlsConf != nil {
+		tconn := tls.Client(nconn, tlsConf)
+
+		tconn.SetDeadline(time.Now().Add(dialTimeout))
+		err := tconn.Handshake()
+		if err != nil {
+			nconn.Close()
+			return nil, err
+		}
+		tconn.SetDeadline(time.Time{})
+
+		return newConn(tconn), nil
+	}
+
 	return newConn(nconn), nil
 }
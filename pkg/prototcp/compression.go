@@ -0,0 +1,101 @@
+package prototcp
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// CompressionZstd is the zstd compression algorithm.
+const CompressionZstd = "zstd"
+
+// maximum size of a compressed message, before and after decompression.
+// It is the same limit that is used by roscpp.
+const compressedMessageMaxSize = 1000000000
+
+var errCompressorClosed = errors.New("terminated")
+
+// ChooseCompression returns the first supported algorithm of a comma-separated list,
+// in the format used by the compression field of headers.
+// It returns an empty string if none of the algorithms is supported.
+func ChooseCompression(list string) string {
+	for _, algo := range strings.Split(list, ",") {
+		if strings.TrimSpace(algo) == CompressionZstd {
+			return CompressionZstd
+		}
+	}
+	return ""
+}
+
+// compressor is protected by a mutex, since it can be closed
+// while messages are being read or written by other goroutines.
+type compressor struct {
+	mutex sync.Mutex
+	enc   *zstd.Encoder
+	dec   *zstd.Decoder
+}
+
+func newCompressor(algo string) (*compressor, error) {
+	if algo != CompressionZstd {
+		return nil, fmt.Errorf("unsupported compression algorithm: %s", algo)
+	}
+
+	enc, err := zstd.NewWriter(nil,
+		zstd.WithEncoderConcurrency(1),
+		zstd.WithEncoderLevel(zstd.SpeedFastest))
+	if err != nil {
+		return nil, err
+	}
+
+	dec, err := zstd.NewReader(nil,
+		zstd.WithDecoderConcurrency(1),
+		zstd.WithDecoderMaxMemory(compressedMessageMaxSize))
+	if err != nil {
+		enc.Close()
+		return nil, err
+	}
+
+	return &compressor{
+		enc: enc,
+		dec: dec,
+	}, nil
+}
+
+func (c *compressor) close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.enc == nil {
+		return
+	}
+
+	c.enc.Close()
+	c.dec.Close()
+	c.enc = nil
+	c.dec = nil
+}
+
+func (c *compressor) encode(src []byte, dst []byte) ([]byte, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.enc == nil {
+		return nil, errCompressorClosed
+	}
+
+	return c.enc.EncodeAll(src, dst), nil
+}
+
+func (c *compressor) decode(src []byte, dst []byte) ([]byte, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.dec == nil {
+		return nil, errCompressorClosed
+	}
+
+	return c.dec.DecodeAll(src, dst)
+}
@@ -2,8 +2,14 @@ package prototcp
 
 import (
 	"bufio"
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"fmt"
 	"io"
 	"net"
+	"sync/atomic"
 
 	"github.com/aler9/goroslib/pkg/protocommon"
 )
@@ -12,31 +18,124 @@ const (
 	bufferSize = 2048
 )
 
+type countReader struct {
+	r io.Reader
+	n *uint64
+}
+
+func (r countReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	atomic.AddUint64(r.n, uint64(n))
+	return n, err
+}
+
+type countWriter struct {
+	w io.Writer
+	n *uint64
+}
+
+func (w countWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	atomic.AddUint64(w.n, uint64(n))
+	return n, err
+}
+
 // Conn is a TCPROS connection.
 type Conn struct {
-	nconn    net.Conn
-	readBuf  *bufio.Reader
-	writeBuf *bufio.Writer
+	// first fields, in order to be 64-bit aligned
+	bytesRead                uint64
+	bytesWritten             uint64
+	uncompressedBytesRead    uint64
+	uncompressedBytesWritten uint64
+
+	nconn      net.Conn
+	readBuf    io.Reader
+	writeBuf   *bufio.Writer
+	compressor *compressor
 }
 
 func newConn(nconn net.Conn) *Conn {
-	return &Conn{
-		nconn:    nconn,
-		readBuf:  bufio.NewReaderSize(nconn, bufferSize),
-		writeBuf: bufio.NewWriterSize(nconn, bufferSize),
+	c := &Conn{
+		nconn: nconn,
 	}
+	// bytes are counted after the buffer, in order to count only consumed ones
+	c.readBuf = countReader{bufio.NewReaderSize(nconn, bufferSize), &c.bytesRead}
+	c.writeBuf = bufio.NewWriterSize(countWriter{nconn, &c.bytesWritten}, bufferSize)
+	return c
 }
 
 // Close closes the connection.
 func (c *Conn) Close() error {
-	return c.nconn.Close()
+	err := c.nconn.Close()
+	if c.compressor != nil {
+		c.compressor.close()
+	}
+	return err
+}
+
+// SetCompression enables the compression of messages with the given algorithm.
+// It must be called after the exchange of headers and before reading or writing messages.
+func (c *Conn) SetCompression(algo string) error {
+	comp, err := newCompressor(algo)
+	if err != nil {
+		return err
+	}
+
+	c.compressor = comp
+	return nil
 }
 
 // NetConn returns the underlying net.Conn.
+// In case of TLS, it is a *tls.Conn.
 func (c *Conn) NetConn() net.Conn {
 	return c.nconn
 }
 
+// TCPConn returns the underlying TCP connection.
+func (c *Conn) TCPConn() *net.TCPConn {
+	if tconn, ok := c.nconn.(*tls.Conn); ok {
+		return tconn.NetConn().(*net.TCPConn)
+	}
+	return c.nconn.(*net.TCPConn)
+}
+
+// PeerCertificate returns the certificate that was presented by the other side
+// of the connection, or nil if TLS is not in use or the handshake was not performed yet.
+func (c *Conn) PeerCertificate() *x509.Certificate {
+	tconn, ok := c.nconn.(*tls.Conn)
+	if !ok {
+		return nil
+	}
+
+	certs := tconn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil
+	}
+	return certs[0]
+}
+
+// BytesRead returns the number of bytes read from the connection.
+func (c *Conn) BytesRead() uint64 {
+	return atomic.LoadUint64(&c.bytesRead)
+}
+
+// BytesWritten returns the number of bytes written to the connection.
+func (c *Conn) BytesWritten() uint64 {
+	return atomic.LoadUint64(&c.bytesWritten)
+}
+
+// UncompressedBytesRead returns the number of bytes of messages read from the connection,
+// after decompression. It is filled only when compression is enabled.
+func (c *Conn) UncompressedBytesRead() uint64 {
+	return atomic.LoadUint64(&c.uncompressedBytesRead)
+}
+
+// UncompressedBytesWritten returns the number of bytes of messages written to the connection,
+// before compression. It is filled only when compression is enabled.
+func (c *Conn) UncompressedBytesWritten() uint64 {
+	return atomic.LoadUint64(&c.uncompressedBytesWritten)
+}
+
 // ReadHeaderRaw reads an HeaderRaw.
 func (c *Conn) ReadHeaderRaw() (protocommon.HeaderRaw, error) {
 	return protocommon.HeaderRawDecode(c.readBuf)
@@ -51,6 +150,12 @@ func (c *Conn) WriteHeader(header protocommon.Header) error {
 	return c.writeBuf.Flush()
 }
 
+// Read reads raw bytes from the connection, including the ones that were buffered
+// while reading the header.
+func (c *Conn) Read(p []byte) (int, error) {
+	return c.readBuf.Read(p)
+}
+
 // ReadServiceResState reads the response of a service state request.
 func (c *Conn) ReadServiceResState() (uint8, error) {
 	byt := make([]byte, 1)
@@ -71,12 +176,74 @@ func (c *Conn) WriteServiceResState(v uint8) error {
 
 // ReadMessage reads a message.
 func (c *Conn) ReadMessage(msg interface{}) error {
-	return protocommon.MessageDecode(c.readBuf, msg)
+	if c.compressor == nil {
+		return protocommon.MessageDecode(c.readBuf, msg)
+	}
+
+	// compressed messages are framed by their length, like plain ones
+	var buf [4]byte
+	_, err := io.ReadFull(c.readBuf, buf[:])
+	if err != nil {
+		return err
+	}
+
+	size := binary.LittleEndian.Uint32(buf[:])
+	if size > compressedMessageMaxSize {
+		return fmt.Errorf("compressed message is too big (%d bytes)", size)
+	}
+
+	// the buffer grows while data is received, in order not to allocate
+	// the claimed size before receiving it
+	var compressed bytes.Buffer
+	_, err = io.CopyN(&compressed, c.readBuf, int64(size))
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+
+	// the decompressed size is limited by the decoder
+	capacity := compressed.Len() * 4
+	if capacity > compressedMessageMaxSize {
+		capacity = compressedMessageMaxSize
+	}
+
+	raw, err := c.compressor.decode(compressed.Bytes(), make([]byte, 4, 4+capacity))
+	if err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint32(raw, uint32(len(raw)-4))
+	atomic.AddUint64(&c.uncompressedBytesRead, uint64(len(raw)))
+
+	return protocommon.MessageDecode(bytes.NewReader(raw), msg)
 }
 
 // WriteMessage writes a message.
 func (c *Conn) WriteMessage(msg interface{}) error {
-	err := protocommon.MessageEncode(c.writeBuf, msg)
+	if c.compressor == nil {
+		err := protocommon.MessageEncode(c.writeBuf, msg)
+		if err != nil {
+			return err
+		}
+		return c.writeBuf.Flush()
+	}
+
+	var raw bytes.Buffer
+	err := protocommon.MessageEncode(&raw, msg)
+	if err != nil {
+		return err
+	}
+	atomic.AddUint64(&c.uncompressedBytesWritten, uint64(raw.Len()))
+
+	// the length of the message is replaced by the length of the compressed message
+	compressed, err := c.compressor.encode(raw.Bytes()[4:], make([]byte, 4, 4+raw.Len()))
+	if err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint32(compressed, uint32(len(compressed)-4))
+
+	_, err = c.writeBuf.Write(compressed)
 	if err != nil {
 		return err
 	}
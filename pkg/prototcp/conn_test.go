@@ -1,9 +1,12 @@
 package prototcp
 
 import (
+	"bytes"
 	"io"
 	"net"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -88,12 +91,16 @@ func TestConn(t *testing.T) {
 		"type":               "mytype",
 	}, raw)
 
+	written := tconn.BytesWritten()
+
 	err = tconn.WriteServiceResState(1)
 	require.NoError(t, err)
 
 	err = tconn.WriteMessage(&struct{}{})
 	require.NoError(t, err)
 
+	require.Equal(t, uint64(5), tconn.BytesWritten()-written)
+
 	state, err := tconn.ReadServiceResState()
 	require.NoError(t, err)
 	require.Equal(t, uint8(1), state)
@@ -102,6 +109,18 @@ func TestConn(t *testing.T) {
 	err = tconn.ReadMessage(&msg)
 	require.NoError(t, err)
 	require.Equal(t, struct{}{}, msg)
+
+	var buf bytes.Buffer
+	err = protocommon.HeaderEncode(&buf, &HeaderSubscriber{
+		Callerid:          "mycallerid",
+		Topic:             "mytopic",
+		Type:              "mytype",
+		Md5sum:            "mysum",
+		MessageDefinition: "mydef",
+		TcpNodelay:        1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(buf.Len()+5), tconn.BytesRead())
 }
 
 func TestConnErrors(t *testing.T) {
@@ -162,3 +181,109 @@ func TestConnErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestConnCompression(t *testing.T) {
+	require.Equal(t, CompressionZstd, ChooseCompression("lz4, zstd"))
+	require.Equal(t, "", ChooseCompression("lz4"))
+
+	nconn1, nconn2 := net.Pipe()
+
+	conn1 := newConn(nconn1)
+	defer conn1.Close()
+
+	conn2 := newConn(nconn2)
+	defer conn2.Close()
+
+	require.EqualError(t, conn1.SetCompression("lz4"), "unsupported compression algorithm: lz4")
+	require.NoError(t, conn1.SetCompression(CompressionZstd))
+	require.NoError(t, conn2.SetCompression(CompressionZstd))
+
+	type myMsg struct {
+		A string
+	}
+
+	in := myMsg{A: strings.Repeat("abcd", 1000)}
+
+	writeDone := make(chan struct{})
+	go func() {
+		defer close(writeDone)
+		err := conn1.WriteMessage(&in)
+		require.NoError(t, err)
+	}()
+
+	var out myMsg
+	err := conn2.ReadMessage(&out)
+	require.NoError(t, err)
+	require.Equal(t, in, out)
+	<-writeDone
+
+	require.Equal(t, uint64(4+4+4000), conn1.UncompressedBytesWritten())
+	require.Equal(t, uint64(4+4+4000), conn2.UncompressedBytesRead())
+	require.Equal(t, conn1.BytesWritten(), conn2.BytesRead())
+	require.True(t, conn1.BytesWritten() < conn1.UncompressedBytesWritten())
+}
+
+func TestConnCompressionErrors(t *testing.T) {
+	t.Run("too big", func(t *testing.T) {
+		nconn1, nconn2 := net.Pipe()
+		defer nconn1.Close()
+
+		conn2 := newConn(nconn2)
+		defer conn2.Close()
+		require.NoError(t, conn2.SetCompression(CompressionZstd))
+
+		go nconn1.Write([]byte{0xFF, 0xFF, 0xFF, 0xFF})
+
+		var out struct {
+			A string
+		}
+		err := conn2.ReadMessage(&out)
+		require.EqualError(t, err, "compressed message is too big (4294967295 bytes)")
+	})
+
+	t.Run("close during read and write", func(t *testing.T) {
+		nconn1, nconn2 := net.Pipe()
+
+		conn1 := newConn(nconn1)
+		require.NoError(t, conn1.SetCompression(CompressionZstd))
+
+		conn2 := newConn(nconn2)
+		require.NoError(t, conn2.SetCompression(CompressionZstd))
+
+		type myMsg struct {
+			A string
+		}
+
+		writeDone := make(chan struct{})
+		go func() {
+			defer close(writeDone)
+			for {
+				err := conn1.WriteMessage(&myMsg{A: strings.Repeat("abcd", 1000)})
+				if err != nil {
+					return
+				}
+			}
+		}()
+
+		readDone := make(chan struct{})
+		go func() {
+			defer close(readDone)
+			for {
+				var out myMsg
+				err := conn2.ReadMessage(&out)
+				if err != nil {
+					return
+				}
+			}
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		conn1.Close()
+		conn2.Close()
+		<-writeDone
+		<-readDone
+
+		err := conn1.WriteMessage(&myMsg{})
+		require.Error(t, err)
+	})
+}
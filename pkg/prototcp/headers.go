@@ -17,6 +17,8 @@ type HeaderSubscriber struct {
 	Md5sum            string
 	MessageDefinition string
 	TcpNodelay        int //nolint:golint
+	SharedMemory      *int
+	Compression       *string
 }
 
 // IsHeader implements protocommon.Header.
@@ -24,11 +26,13 @@ func (*HeaderSubscriber) IsHeader() {}
 
 // HeaderPublisher is a publisher header.
 type HeaderPublisher struct {
-	Topic    string
-	Type     string
-	Md5sum   string
-	Callerid string
-	Latching int
+	Topic        string
+	Type         string
+	Md5sum       string
+	Callerid     string
+	Latching     int
+	SharedMemory *string
+	Compression  *string
 }
 
 // IsHeader implements protocommon.Header.
@@ -1,6 +1,7 @@
 package prototcp
 
 import (
+	"crypto/tls"
 	"net"
 	"net/url"
 )
@@ -19,18 +20,26 @@ func ServerURL(address *net.TCPAddr, port int) string {
 
 // Server is a TCPROS server.
 type Server struct {
-	ln net.Listener
+	ln      net.Listener
+	tlsConf *tls.Config
 }
 
 // NewServer allocates a Server.
 func NewServer(address string) (*Server, error) {
+	return NewServerWithTLS(address, nil)
+}
+
+// NewServerWithTLS allocates a Server.
+// If tlsConf is not nil, connections are encrypted with TLS.
+func NewServerWithTLS(address string, tlsConf *tls.Config) (*Server, error) {
 	ln, err := net.Listen("tcp", address)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Server{
-		ln: ln,
+		ln:      ln,
+		tlsConf: tlsConf,
 	}, nil
 }
 
@@ -45,11 +54,17 @@ func (s *Server) Port() int {
 }
 
 // Accept accepts clients.
+// In case of TLS, the handshake is performed during the first read,
+// in order not to block the caller.
 func (s *Server) Accept() (*Conn, error) {
 	nconn, err := s.ln.Accept()
 	if err != nil {
 		return nil, err
 	}
 
-	return newConn(nconn), err
+	if s.tlsConf != nil {
+		return newConn(tls.Server(nconn, s.tlsConf)), nil
+	}
+
+	return newConn(nconn), nil
 }
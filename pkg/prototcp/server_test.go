@@ -1,8 +1,16 @@
 package prototcp
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
 	"net"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -42,3 +50,85 @@ func TestServerError(t *testing.T) {
 	_, err = NewServer("localhost:9901")
 	require.Error(t, err)
 }
+
+func newTestTLSConfigs(t *testing.T) (*tls.Config, *tls.Config) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "mynode"},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	tlsCert := tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{tlsCert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}, &tls.Config{
+		Certificates: []tls.Certificate{tlsCert},
+		RootCAs:      pool,
+		ServerName:   "localhost",
+	}
+}
+
+func TestServerTLS(t *testing.T) {
+	serverConf, clientConf := newTestTLSConfigs(t)
+
+	s, err := NewServerWithTLS("localhost:9910", serverConf)
+	require.NoError(t, err)
+	defer s.Close()
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+
+	go func() {
+		defer close(serverDone)
+
+		conn, err := s.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+
+		raw, err := conn.ReadHeaderRaw()
+		require.NoError(t, err)
+		require.Equal(t, "mycallerid", raw["callerid"])
+		require.Equal(t, "mynode", conn.PeerCertificate().Subject.CommonName)
+		require.NotNil(t, conn.TCPConn())
+
+		err = conn.WriteHeader(&HeaderError{Error: "myerror"})
+		require.NoError(t, err)
+	}()
+
+	c, err := NewClientWithTLS("localhost:9910", clientConf)
+	require.NoError(t, err)
+	defer c.Close()
+
+	require.Equal(t, "mynode", c.PeerCertificate().Subject.CommonName)
+
+	err = c.WriteHeader(&HeaderServiceClient{Callerid: "mycallerid"})
+	require.NoError(t, err)
+
+	raw, err := c.ReadHeaderRaw()
+	require.NoError(t, err)
+	require.Equal(t, "myerror", raw["error"])
+}
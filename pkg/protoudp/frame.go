@@ -6,7 +6,11 @@ import (
 )
 
 const (
-	maxPayloadSize = 1492
+	// size of the header of a frame.
+	frameHeaderSize = 8
+
+	// DefaultMaxDatagramSize is the default maximum size of a datagram.
+	DefaultMaxDatagramSize = 1500
 )
 
 // Opcode is the opcode of a Frame.
@@ -30,7 +34,7 @@ type Frame struct {
 }
 
 func (f *Frame) decode(byts []byte) error {
-	if len(byts) < 8 {
+	if len(byts) < frameHeaderSize {
 		return fmt.Errorf("invalid length")
 	}
 
@@ -38,25 +42,34 @@ func (f *Frame) decode(byts []byte) error {
 	f.Opcode = Opcode(byts[4])
 	f.MessageID = byts[5]
 	f.BlockID = binary.LittleEndian.Uint16(byts[6:8])
-	f.Payload = byts[8:]
+	f.Payload = byts[frameHeaderSize:]
 
 	return nil
 }
 
 func (f *Frame) encode() ([]byte, error) {
-	byts := make([]byte, 8+len(f.Payload))
+	byts := make([]byte, frameHeaderSize+len(f.Payload))
 
 	binary.LittleEndian.PutUint32(byts[:4], f.ConnectionID)
 	byts[4] = uint8(f.Opcode)
 	byts[5] = f.MessageID
 	binary.LittleEndian.PutUint16(byts[6:8], f.BlockID)
-	copy(byts[8:], f.Payload)
+	copy(byts[frameHeaderSize:], f.Payload)
 
 	return byts, nil
 }
 
 // FramesForPayload generates frames for the given payload.
+// Each frame, once encoded, is at most DefaultMaxDatagramSize bytes long.
 func FramesForPayload(connID uint32, messageID uint8, payload []byte) []*Frame {
+	return FramesForPayloadWithSize(connID, messageID, payload, DefaultMaxDatagramSize)
+}
+
+// FramesForPayloadWithSize generates frames for the given payload.
+// Each frame, once encoded, is at most maxDatagramSize bytes long.
+func FramesForPayloadWithSize(connID uint32, messageID uint8, payload []byte, maxDatagramSize int) []*Frame {
+	maxPayloadSize := maxDatagramSize - frameHeaderSize
+
 	var ret []*Frame
 	lbyts := len(payload)
 
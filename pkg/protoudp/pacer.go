@@ -0,0 +1,64 @@
+package protoudp
+
+import (
+	"time"
+)
+
+// Pacer spaces out frames in time, in order to avoid overflowing
+// socket buffers and network queues. It implements a token bucket.
+type Pacer struct {
+	// (optional) maximum number of bytes sent per second, including frame headers.
+	// It defaults to unlimited.
+	Rate int
+
+	// (optional) maximum number of bytes that can be sent at once
+	// after a period of inactivity.
+	// It defaults to zero, that means that every frame is paced.
+	Burst int
+
+	// (optional) minimum time between two frames.
+	// It defaults to zero.
+	FrameGap time.Duration
+
+	initialized bool
+	tokens      float64
+	last        time.Time
+	nextFrame   time.Time
+}
+
+// Reserve reserves the sending of a frame and returns the time to wait
+// before sending it.
+func (p *Pacer) Reserve(f *Frame, now time.Time) time.Duration {
+	if !p.initialized {
+		p.initialized = true
+		p.tokens = float64(p.Burst)
+		p.last = now
+		p.nextFrame = now
+	}
+
+	var wait time.Duration
+
+	if p.Rate > 0 {
+		p.tokens += now.Sub(p.last).Seconds() * float64(p.Rate)
+		if p.tokens > float64(p.Burst) {
+			p.tokens = float64(p.Burst)
+		}
+		p.last = now
+
+		// tokens can become negative, in that case the deficit
+		// is recovered before sending the frame.
+		p.tokens -= float64(frameHeaderSize + len(f.Payload))
+		if p.tokens < 0 {
+			wait = time.Duration(-p.tokens / float64(p.Rate) * float64(time.Second))
+		}
+	}
+
+	if p.FrameGap > 0 {
+		if w := p.nextFrame.Sub(now); w > wait {
+			wait = w
+		}
+		p.nextFrame = now.Add(wait + p.FrameGap)
+	}
+
+	return wait
+}
@@ -0,0 +1,51 @@
+package protoudp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPacerRate(t *testing.T) {
+	now := time.Now()
+	p := Pacer{Rate: 1000}
+
+	f := &Frame{Payload: make([]byte, 100-frameHeaderSize)}
+
+	require.Equal(t, 100*time.Millisecond, p.Reserve(f, now))
+	require.Equal(t, 200*time.Millisecond, p.Reserve(f, now))
+
+	// the deficit is recovered over time
+	now = now.Add(200 * time.Millisecond)
+	require.Equal(t, 100*time.Millisecond, p.Reserve(f, now))
+
+	// tokens are limited by Burst
+	now = now.Add(10 * time.Second)
+	require.Equal(t, 100*time.Millisecond, p.Reserve(f, now))
+}
+
+func TestPacerBurst(t *testing.T) {
+	now := time.Now()
+	p := Pacer{Rate: 1000, Burst: 200}
+
+	f := &Frame{Payload: make([]byte, 100-frameHeaderSize)}
+
+	require.Equal(t, time.Duration(0), p.Reserve(f, now))
+	require.Equal(t, time.Duration(0), p.Reserve(f, now))
+	require.Equal(t, 100*time.Millisecond, p.Reserve(f, now))
+}
+
+func TestPacerFrameGap(t *testing.T) {
+	now := time.Now()
+	p := Pacer{FrameGap: 10 * time.Millisecond}
+
+	f := &Frame{}
+
+	require.Equal(t, time.Duration(0), p.Reserve(f, now))
+	require.Equal(t, 10*time.Millisecond, p.Reserve(f, now))
+	require.Equal(t, 20*time.Millisecond, p.Reserve(f, now))
+
+	now = now.Add(1 * time.Second)
+	require.Equal(t, time.Duration(0), p.Reserve(f, now))
+}
@@ -0,0 +1,81 @@
+package protoudp
+
+import (
+	"time"
+)
+
+// Reassembler reassembles payloads that were split into frames by FramesForPayload.
+type Reassembler struct {
+	// maximum time between the first and the last frame of a payload.
+	// When it expires, the payload is discarded.
+	Timeout time.Duration
+
+	cur           []byte
+	curMessageID  uint8
+	curBlockID    int
+	curBlockCount int
+	curStart      time.Time
+	lastMessageID uint8
+	hasLast       bool
+}
+
+// Push adds a frame. It returns the payload, if the frame completes it, and the number
+// of payloads that were lost, because they were never received or never completed.
+func (r *Reassembler) Push(f *Frame, now time.Time) ([]byte, int) {
+	lost := 0
+
+	switch f.Opcode {
+	case Data0:
+		// the previous payload was not completed
+		if r.cur != nil {
+			lost++
+		}
+
+		// payloads between the previous one and this one were never received.
+		// large gaps are caused by reordering or by a restart of the publisher, and are ignored.
+		if r.hasLast {
+			gap := f.MessageID - r.lastMessageID - 1
+			if gap < 128 {
+				lost += int(gap)
+			}
+		}
+		r.lastMessageID = f.MessageID
+		r.hasLast = true
+
+		r.cur = append([]byte(nil), f.Payload...)
+		r.curMessageID = f.MessageID
+		r.curBlockID = 0
+		r.curBlockCount = int(f.BlockID)
+		r.curStart = now
+
+	case DataN:
+		if r.cur == nil || f.MessageID != r.curMessageID || int(f.BlockID) != (r.curBlockID+1) {
+			return nil, 0
+		}
+
+		r.cur = append(r.cur, f.Payload...)
+		r.curBlockID++
+
+	default:
+		return nil, 0
+	}
+
+	if (r.curBlockID + 1) == r.curBlockCount {
+		byts := r.cur
+		r.cur = nil
+		return byts, lost
+	}
+
+	return nil, lost
+}
+
+// Evict discards the current payload if it was not completed within Timeout.
+// It returns the number of payloads that were discarded.
+func (r *Reassembler) Evict(now time.Time) int {
+	if r.cur == nil || now.Sub(r.curStart) < r.Timeout {
+		return 0
+	}
+
+	r.cur = nil
+	return 1
+}
@@ -0,0 +1,69 @@
+package protoudp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFramesForPayload(t *testing.T) {
+	frames := FramesForPayloadWithSize(3, 2, []byte{0x01, 0x02, 0x03, 0x04, 0x05}, frameHeaderSize+2)
+	require.Equal(t, []*Frame{
+		{ConnectionID: 3, Opcode: Data0, MessageID: 2, BlockID: 3, Payload: []byte{0x01, 0x02}},
+		{ConnectionID: 3, Opcode: DataN, MessageID: 2, BlockID: 1, Payload: []byte{0x03, 0x04}},
+		{ConnectionID: 3, Opcode: DataN, MessageID: 2, BlockID: 2, Payload: []byte{0x05}},
+	}, frames)
+}
+
+func TestReassembler(t *testing.T) {
+	now := time.Now()
+	r := Reassembler{Timeout: 1 * time.Second}
+
+	push := func(messageID uint8, payload []byte) ([]byte, int) {
+		var ret []byte
+		lost := 0
+		for _, f := range FramesForPayloadWithSize(3, messageID, payload, frameHeaderSize+2) {
+			byts, n := r.Push(f, now)
+			if byts != nil {
+				ret = byts
+			}
+			lost += n
+		}
+		return ret, lost
+	}
+
+	byts, lost := push(1, []byte{0x01, 0x02, 0x03})
+	require.Equal(t, []byte{0x01, 0x02, 0x03}, byts)
+	require.Equal(t, 0, lost)
+
+	// messages 2 and 3 are never received
+	byts, lost = push(4, []byte{0x04})
+	require.Equal(t, []byte{0x04}, byts)
+	require.Equal(t, 2, lost)
+
+	// message 5 is not completed
+	frames := FramesForPayloadWithSize(3, 5, []byte{0x01, 0x02, 0x03}, frameHeaderSize+2)
+	byts, lost = r.Push(frames[0], now)
+	require.Equal(t, []byte(nil), byts)
+	require.Equal(t, 0, lost)
+
+	// frames of other messages are ignored
+	byts, lost = r.Push(&Frame{Opcode: DataN, MessageID: 6, BlockID: 1}, now)
+	require.Equal(t, []byte(nil), byts)
+	require.Equal(t, 0, lost)
+
+	byts, lost = push(6, []byte{0x06})
+	require.Equal(t, []byte{0x06}, byts)
+	require.Equal(t, 1, lost)
+
+	// message 7 is evicted
+	frames = FramesForPayloadWithSize(3, 7, []byte{0x01, 0x02, 0x03}, frameHeaderSize+2)
+	r.Push(frames[0], now)
+	require.Equal(t, 0, r.Evict(now.Add(500*time.Millisecond)))
+	require.Equal(t, 1, r.Evict(now.Add(1*time.Second)))
+
+	byts, lost = r.Push(frames[1], now)
+	require.Equal(t, []byte(nil), byts)
+	require.Equal(t, 0, lost)
+}
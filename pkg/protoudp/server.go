@@ -2,29 +2,81 @@ package protoudp
 
 import (
 	"net"
+
+	"golang.org/x/net/ipv4"
 )
 
 const (
-	bufferSize = 2048
+	// maximum size of a UDP datagram.
+	bufferSize = 65535
 )
 
 // Server is a UDPROS server.
 type Server struct {
-	ln net.PacketConn
+	ln      net.PacketConn
+	readBuf []byte
 }
 
 // NewServer allocates a Server.
 func NewServer(address string) (*Server, error) {
+	return NewServerWithBufferSize(address, 0)
+}
+
+// NewServerWithBufferSize allocates a Server.
+// If socketBufferSize is not zero, it is used as the size of
+// the receive and send buffers of the socket.
+func NewServerWithBufferSize(address string, socketBufferSize int) (*Server, error) {
 	ln, err := net.ListenPacket("udp", address)
 	if err != nil {
 		return nil, err
 	}
 
+	err = setSocketBufferSize(ln.(*net.UDPConn), socketBufferSize)
+	if err != nil {
+		ln.Close()
+		return nil, err
+	}
+
 	return &Server{
-		ln: ln,
+		ln:      ln,
+		readBuf: make([]byte, bufferSize),
 	}, nil
 }
 
+// NewMulticastServer allocates a Server that receives frames sent to a multicast group.
+// If iface is nil, the group is joined on the interface chosen by the operating system.
+// Multiple servers can be bound to the same group.
+func NewMulticastServer(group *net.UDPAddr, iface *net.Interface, socketBufferSize int) (*Server, error) {
+	ln, err := net.ListenMulticastUDP("udp4", iface, group)
+	if err != nil {
+		return nil, err
+	}
+
+	err = setSocketBufferSize(ln, socketBufferSize)
+	if err != nil {
+		ln.Close()
+		return nil, err
+	}
+
+	return &Server{
+		ln:      ln,
+		readBuf: make([]byte, bufferSize),
+	}, nil
+}
+
+func setSocketBufferSize(conn *net.UDPConn, size int) error {
+	if size == 0 {
+		return nil
+	}
+
+	err := conn.SetReadBuffer(size)
+	if err != nil {
+		return err
+	}
+
+	return conn.SetWriteBuffer(size)
+}
+
 // Close closes the server.
 func (s *Server) Close() error {
 	return s.ln.Close()
@@ -35,17 +87,29 @@ func (s *Server) Port() int {
 	return s.ln.LocalAddr().(*net.UDPAddr).Port
 }
 
+// SetMulticastInterface sets the interface used to send frames to multicast groups.
+// Frames are also delivered to servers of the same host that joined the group.
+func (s *Server) SetMulticastInterface(iface *net.Interface) error {
+	pc := ipv4.NewPacketConn(s.ln)
+
+	err := pc.SetMulticastInterface(iface)
+	if err != nil {
+		return err
+	}
+
+	return pc.SetMulticastLoopback(true)
+}
+
 // ReadFrame reads a frame.
+// It must not be called by multiple routines at once.
 func (s *Server) ReadFrame() (*Frame, *net.UDPAddr, error) {
-	buf := make([]byte, bufferSize)
-
-	n, source, err := s.ln.ReadFrom(buf)
+	n, source, err := s.ln.ReadFrom(s.readBuf)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	var f Frame
-	err = f.decode(buf[:n])
+	err = f.decode(append([]byte(nil), s.readBuf[:n]...))
 	if err != nil {
 		return nil, nil, err
 	}
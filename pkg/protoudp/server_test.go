@@ -41,3 +41,43 @@ func TestServer(t *testing.T) {
 		require.NoError(t, err)
 	}
 }
+
+func TestServerMulticast(t *testing.T) {
+	iface, err := net.InterfaceByName("lo")
+	if err != nil {
+		t.Skip("loopback interface not found")
+	}
+
+	group := &net.UDPAddr{IP: net.IPv4(239, 255, 0, 1), Port: 9912}
+
+	var receivers []*Server
+	for i := 0; i < 2; i++ {
+		r, err := NewMulticastServer(group, iface, 0)
+		require.NoError(t, err)
+		defer r.Close()
+		receivers = append(receivers, r)
+	}
+
+	s, err := NewServer("127.0.0.1:9913")
+	require.NoError(t, err)
+	defer s.Close()
+
+	err = s.SetMulticastInterface(iface)
+	require.NoError(t, err)
+
+	f := &Frame{
+		ConnectionID: 3,
+		MessageID:    2,
+		BlockID:      1,
+		Payload:      []byte{0x01, 0x02, 0x03, 0x04},
+	}
+
+	err = s.WriteFrame(f, group)
+	require.NoError(t, err)
+
+	for _, r := range receivers {
+		fr, _, err := r.ReadFrame()
+		require.NoError(t, err)
+		require.Equal(t, f, fr)
+	}
+}
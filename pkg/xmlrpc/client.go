@@ -3,44 +3,184 @@ package xmlrpc
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/url"
+	"sync/atomic"
+	"syscall"
+	"time"
 )
 
+const (
+	// number of times a request is repeated in case of transient errors.
+	clientMaxRetries = 2
+
+	// pause before the first repetition; it is doubled after each repetition.
+	clientRetryPause = 100 * time.Millisecond
+)
+
+var defaultHTTPClient = NewHTTPClient(10*time.Second, nil)
+
+// NewHTTPClient allocates a HTTP client fit for XML-RPC requests, that keeps
+// connections alive in order to reuse them, and that fails when connecting,
+// waiting for a response or reading a response takes more than timeout.
+// If tlsConf is not nil, Clients that use the HTTP client perform requests with HTTPS.
+func NewHTTPClient(timeout time.Duration, tlsConf *tls.Config) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   timeout,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			TLSClientConfig:       tlsConf,
+			TLSHandshakeTimeout:   timeout,
+			ResponseHeaderTimeout: timeout,
+			MaxIdleConns:          100,
+			MaxIdleConnsPerHost:   4,
+			IdleConnTimeout:       90 * time.Second,
+		},
+	}
+}
+
 // Client is a XML-RPC client.
 type Client struct {
-	url string
+	errorCount uint64
+
+	url        string
+	httpClient *http.Client
 }
 
-// NewClient allocates a Client.
+// NewClient allocates a Client, that uses a shared HTTP client with a timeout
+// of 10 seconds.
 func NewClient(address string) *Client {
+	return NewClientWithHTTPClient(address, nil)
+}
+
+// NewClientWithHTTPClient allocates a Client that uses the given HTTP client
+// to perform requests. If httpClient is nil, a shared client with a timeout
+// of 10 seconds is used. If it has been allocated by NewHTTPClient with a TLS
+// configuration, requests are performed with HTTPS.
+func NewClientWithHTTPClient(address string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = defaultHTTPClient
+	}
+
+	scheme := "http"
+	if tr, ok := httpClient.Transport.(*http.Transport); ok && tr.TLSClientConfig != nil {
+		scheme = "https"
+	}
+
 	return &Client{
 		url: (&url.URL{
-			Scheme: "http",
+			Scheme: scheme,
 			Host:   address,
 			Path:   "/RPC2",
 		}).String(),
+		httpClient: httpClient,
 	}
 }
 
 // Do writes a request and reads a response.
 func (c *Client) Do(method string, paramsReq interface{}, paramsRes interface{}) error {
+	return c.DoContext(context.Background(), method, paramsReq, paramsRes)
+}
+
+// DoContext writes a request and reads a response.
+// ctx allows to cancel the request.
+// The request is repeated, with a pause in between, when the connection
+// to the server can't be established.
+func (c *Client) DoContext(ctx context.Context, method string,
+	paramsReq interface{}, paramsRes interface{}) error {
+	err := c.doContext(ctx, method, paramsReq, paramsRes)
+	if err != nil {
+		atomic.AddUint64(&c.errorCount, 1)
+	}
+	return err
+}
+
+func (c *Client) doContext(ctx context.Context, method string,
+	paramsReq interface{}, paramsRes interface{}) error {
 	var buf bytes.Buffer
 	err := requestEncode(&buf, method, paramsReq)
 	if err != nil {
 		return err
 	}
 
-	res, err := http.Post(c.url, "text/xml", &buf)
+	return c.doWithRetries(ctx, buf.Bytes(), func(r io.Reader) error {
+		return responseDecode(r, paramsRes)
+	})
+}
+
+func (c *Client) doWithRetries(ctx context.Context, byts []byte, decode func(io.Reader) error) error {
+	pause := clientRetryPause
+
+	for i := 0; ; i++ {
+		err := c.do(ctx, byts, decode)
+		if err == nil || i >= clientMaxRetries || !isTransientError(err) {
+			return err
+		}
+
+		t := time.NewTimer(pause)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		}
+
+		pause *= 2
+	}
+}
+
+func (c *Client) do(ctx context.Context, byts []byte, decode func(io.Reader) error) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(byts))
 	if err != nil {
 		return err
 	}
-	defer res.Body.Close()
+	req.Header.Set("Content-Type", "text/xml")
 
-	err = responseDecode(res.Body, paramsRes)
+	res, err := c.httpClient.Do(req)
 	if err != nil {
 		return err
 	}
+	defer res.Body.Close()
+
+	// read the body entirely in order to allow the connection to be reused
+	defer io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("bad status code: %d", res.StatusCode)
+	}
+
+	return decode(res.Body)
+}
+
+// isTransientError checks whether a request can be repeated after an error.
+// Requests are repeated only when the connection to the server couldn't be
+// established, since in this case the server surely didn't receive them and
+// repeating them is safe even when they are not idempotent.
+func isTransientError(err error) bool {
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+
+	var oerr *net.OpError
+	if errors.As(err, &oerr) && oerr.Op == "dial" && !oerr.Timeout() {
+		return true
+	}
+
+	return false
+}
 
-	return nil
+// ErrorCount returns the number of requests that failed because of
+// a network error, an invalid response or a fault.
+func (c *Client) ErrorCount() uint64 {
+	return atomic.LoadUint64(&c.errorCount)
 }
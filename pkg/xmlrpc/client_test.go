@@ -2,9 +2,11 @@ package xmlrpc
 
 import (
 	"context"
+	"io"
 	"net"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -49,4 +51,118 @@ func TestClient(t *testing.T) {
 	err = c.Do("mymethod", myRequest{Param: "myparam"}, &res)
 	require.NoError(t, err)
 	require.Equal(t, myResponse{Param: "myparam"}, res)
+	require.Equal(t, uint64(0), c.ErrorCount())
+}
+
+func TestClientErrorCount(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:9903")
+	require.NoError(t, err)
+	l.Close()
+
+	c := NewClient("localhost:9903")
+	var res struct{}
+	err = c.Do("mymethod", struct{}{}, &res)
+	require.Error(t, err)
+	require.Equal(t, uint64(1), c.ErrorCount())
+}
+
+func TestClientRetry(t *testing.T) {
+	type myResponse struct {
+		Param string
+	}
+
+	t.Run("connection refused", func(t *testing.T) {
+		hs := &http.Server{
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				responseEncode(w, myResponse{Param: "myparam"})
+			}),
+		}
+		defer hs.Shutdown(context.Background())
+
+		// start the server after the first attempt
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			l, err := net.Listen("tcp", "localhost:9903")
+			if err != nil {
+				return
+			}
+			hs.Serve(l)
+		}()
+
+		c := NewClient("localhost:9903")
+		var res myResponse
+		err := c.Do("mymethod", struct{}{}, &res)
+		require.NoError(t, err)
+		require.Equal(t, myResponse{Param: "myparam"}, res)
+		require.Equal(t, uint64(0), c.ErrorCount())
+	})
+
+	t.Run("connection closed", func(t *testing.T) {
+		count := 0
+		hs := &http.Server{
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				count++
+
+				// close the connection without replying
+				conn, _, err := w.(http.Hijacker).Hijack()
+				require.NoError(t, err)
+				conn.Close()
+			}),
+		}
+		defer hs.Shutdown(context.Background())
+
+		l, err := net.Listen("tcp", "localhost:9903")
+		require.NoError(t, err)
+		defer l.Close()
+
+		go hs.Serve(l)
+
+		// the request may have been received by the server, therefore
+		// it is not repeated
+		c := NewClientWithHTTPClient("localhost:9903", NewHTTPClient(10*time.Second, nil))
+		var res myResponse
+		err = c.Do("mymethod", struct{}{}, &res)
+		require.Error(t, err)
+		require.Equal(t, 1, count)
+		require.Equal(t, uint64(1), c.ErrorCount())
+	})
+}
+
+func TestClientTimeout(t *testing.T) {
+	hs := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			// wait until the client disconnects
+			io.ReadAll(req.Body)
+			<-req.Context().Done()
+		}),
+	}
+	defer hs.Shutdown(context.Background())
+
+	l, err := net.Listen("tcp", "localhost:9903")
+	require.NoError(t, err)
+	defer l.Close()
+
+	go hs.Serve(l)
+
+	t.Run("timeout", func(t *testing.T) {
+		c := NewClientWithHTTPClient("localhost:9903", NewHTTPClient(200*time.Millisecond, nil))
+		start := time.Now()
+		var res struct{}
+		err = c.Do("mymethod", struct{}{}, &res)
+		require.Error(t, err)
+
+		// timeouts are not repeated
+		require.Less(t, int64(time.Since(start)), int64(1*time.Second))
+	})
+
+	t.Run("context", func(t *testing.T) {
+		c := NewClient("localhost:9903")
+		ctx, ctxCancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer ctxCancel()
+		start := time.Now()
+		var res struct{}
+		err = c.DoContext(ctx, "mymethod", struct{}{}, &res)
+		require.Error(t, err)
+		require.Less(t, int64(time.Since(start)), int64(1*time.Second))
+	})
 }
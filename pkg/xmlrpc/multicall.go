@@ -0,0 +1,229 @@
+package xmlrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"sync/atomic"
+)
+
+const multicallMethod = "system.multicall"
+
+// Call is a call that is part of a multicall.
+type Call struct {
+	// name of the method.
+	Method string
+
+	// params of the request.
+	ParamsReq interface{}
+
+	// pointer to the params of the response, that are filled
+	// when the multicall succeeds.
+	ParamsRes interface{}
+
+	// error returned by the server for this call, if any.
+	Err error
+}
+
+type multicallCall struct {
+	MethodName string      `xmlrpc:"methodName"`
+	Params     interface{} `xmlrpc:"params"`
+}
+
+type multicallCallRaw struct {
+	MethodName string   `xmlrpc:"methodName"`
+	Params     rawValue `xmlrpc:"params"`
+}
+
+type multicallReq struct {
+	Calls []multicallCall
+}
+
+func multicallResponseDecode(r io.Reader, calls []*Call) error {
+	dec := xml.NewDecoder(r)
+
+	err := responseDecodeStart(dec)
+	if err != nil {
+		return err
+	}
+
+	err = xmlGetStartElement(dec, "array")
+	if err != nil {
+		return err
+	}
+
+	err = xmlGetStartElement(dec, "data")
+	if err != nil {
+		return err
+	}
+
+	// each result is an array that contains the response, or a fault
+	for i, call := range calls {
+		err := xmlGetStartElement(dec, "value")
+		if err != nil {
+			if err == errEndElement {
+				return fmt.Errorf("received %d results, expected %d", i, len(calls))
+			}
+			return err
+		}
+
+		name, err := xmlGetAnyStartElement(dec)
+		if err != nil {
+			return err
+		}
+
+		switch name {
+		case "array":
+			err := xmlGetStartElement(dec, "data")
+			if err != nil {
+				return err
+			}
+
+			err = xmlGetStartElement(dec, "value")
+			if err != nil {
+				return err
+			}
+
+			rv := reflect.ValueOf(call.ParamsRes)
+
+			if rv.Elem().Kind() == reflect.Struct && !isTaggedStruct(rv.Elem().Type()) {
+				err = xmlGetStartElement(dec, "array")
+				if err != nil {
+					return err
+				}
+
+				err = decodeArray(dec, rv)
+				if err != nil {
+					return err
+				}
+
+				// </value>
+				err = xmlGetEndElement(dec, true)
+			} else {
+				err = valueDecode(dec, rv)
+			}
+			if err != nil {
+				return err
+			}
+
+			// </data>
+			err = xmlGetEndElement(dec, true)
+			if err != nil {
+				return err
+			}
+
+			// </array>
+			err = xmlGetEndElement(dec, true)
+			if err != nil {
+				return err
+			}
+
+		case "struct":
+			var fe FaultError
+			err := decodeStruct(dec, reflect.ValueOf(&fe))
+			if err != nil {
+				return err
+			}
+
+			call.Err = &fe
+
+		default:
+			return fmt.Errorf("unexpected result type: %s", name)
+		}
+
+		// </value>
+		err = xmlGetEndElement(dec, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	return xmlConsumeUntilEOF(dec)
+}
+
+// Multicall performs multiple calls with a single request, by using system.multicall.
+// The returned error is about the whole request; errors of single calls
+// are stored into their Err field.
+func (c *Client) Multicall(calls []*Call) error {
+	return c.MulticallContext(context.Background(), calls)
+}
+
+// MulticallContext performs multiple calls with a single request, by using system.multicall.
+// ctx allows to cancel the request.
+func (c *Client) MulticallContext(ctx context.Context, calls []*Call) error {
+	err := c.multicall(ctx, calls)
+	if err != nil {
+		atomic.AddUint64(&c.errorCount, 1)
+	}
+	return err
+}
+
+func (c *Client) multicall(ctx context.Context, calls []*Call) error {
+	req := multicallReq{
+		Calls: make([]multicallCall, len(calls)),
+	}
+	for i, call := range calls {
+		req.Calls[i] = multicallCall{
+			MethodName: call.Method,
+			Params:     call.ParamsReq,
+		}
+	}
+
+	var buf bytes.Buffer
+	err := requestEncode(&buf, multicallMethod, req)
+	if err != nil {
+		return err
+	}
+
+	return c.doWithRetries(ctx, buf.Bytes(), func(r io.Reader) error {
+		return multicallResponseDecode(r, calls)
+	})
+}
+
+// serverMulticall handles a system.multicall request, by passing each call to the handler.
+func serverMulticall(raw *RequestRaw, handler func(*RequestRaw) interface{}) interface{} {
+	var req struct {
+		Calls []multicallCallRaw
+	}
+	err := raw.Decode(&req)
+	if err != nil {
+		return ErrorRes{}
+	}
+
+	results := make([]interface{}, len(req.Calls))
+
+	for i, call := range req.Calls {
+		if call.MethodName == multicallMethod {
+			results[i] = &FaultError{
+				Code:   1,
+				String: "recursive system.multicall forbidden",
+			}
+			continue
+		}
+
+		res := handler(&RequestRaw{
+			Method:  call.MethodName,
+			dec:     xml.NewDecoder(bytes.NewReader(call.Params)),
+			isArray: true,
+		})
+
+		switch tres := res.(type) {
+		case ErrorRes:
+			results[i] = &FaultError{
+				Code:   1,
+				String: "unable to handle method " + call.MethodName,
+			}
+
+		case *FaultError:
+			results[i] = tres
+
+		default:
+			results[i] = []interface{}{res}
+		}
+	}
+
+	return results
+}
@@ -0,0 +1,105 @@
+package xmlrpc
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMulticallResponseDecode(t *testing.T) {
+	type myResponse struct {
+		Code   int
+		Status string
+		Pid    int
+	}
+
+	enc := []byte(`<?xml version="1.0"?><methodResponse><params><param><value><array><data>` +
+		`<value><array><data><value><array><data>` +
+		`<value><int>1</int></value><value><string></string></value><value><int>123</int></value>` +
+		`</data></array></value></data></array></value>` +
+		`<value><struct>` +
+		`<member><name>faultCode</name><value><int>1</int></value></member>` +
+		`<member><name>faultString</name><value><string>method not found</string></value></member>` +
+		`</struct></value>` +
+		`<value><array><data><value><string>test</string></value></data></array></value>` +
+		`</data></array></value></param></params></methodResponse>`)
+
+	var res1 myResponse
+	var res2 myResponse
+	var res3 string
+	calls := []*Call{
+		{Method: "getPid", ParamsRes: &res1},
+		{Method: "other", ParamsRes: &res2},
+		{Method: "getString", ParamsRes: &res3},
+	}
+
+	err := multicallResponseDecode(bytes.NewReader(enc), calls)
+	require.NoError(t, err)
+
+	require.NoError(t, calls[0].Err)
+	require.Equal(t, myResponse{1, "", 123}, res1)
+	require.Equal(t, &FaultError{Code: 1, String: "method not found"}, calls[1].Err)
+	require.NoError(t, calls[2].Err)
+	require.Equal(t, "test", res3)
+
+	err = multicallResponseDecode(bytes.NewReader(enc), append(calls, &Call{Method: "getPid"}))
+	require.EqualError(t, err, "received 3 results, expected 4")
+}
+
+func TestMulticall(t *testing.T) {
+	type myRequest struct {
+		Param string
+	}
+
+	type myResponse struct {
+		Param string
+	}
+
+	s, err := NewServer("localhost:9909")
+	require.NoError(t, err)
+	defer s.Close()
+
+	go s.Serve(func(raw *RequestRaw) interface{} {
+		switch raw.Method {
+		case "mymethod":
+			var req myRequest
+			err := raw.Decode(&req)
+			if err != nil {
+				return ErrorRes{}
+			}
+			return myResponse{Param: req.Param + "res"}
+
+		case "myfault":
+			return &FaultError{Code: 3, String: "myfault"}
+		}
+
+		return ErrorRes{}
+	})
+
+	c := NewClient("localhost:9909")
+
+	var res1 myResponse
+	var res2 myResponse
+	var res3 myResponse
+	var res4 myResponse
+	var res5 myResponse
+	calls := []*Call{
+		{Method: "mymethod", ParamsReq: myRequest{Param: "a"}, ParamsRes: &res1},
+		{Method: "myfault", ParamsReq: myRequest{}, ParamsRes: &res2},
+		{Method: "othermethod", ParamsReq: myRequest{}, ParamsRes: &res3},
+		{Method: "system.multicall", ParamsReq: myRequest{}, ParamsRes: &res4},
+		{Method: "mymethod", ParamsReq: myRequest{Param: "b"}, ParamsRes: &res5},
+	}
+
+	err = c.Multicall(calls)
+	require.NoError(t, err)
+
+	require.NoError(t, calls[0].Err)
+	require.Equal(t, myResponse{Param: "ares"}, res1)
+	require.Equal(t, &FaultError{Code: 3, String: "myfault"}, calls[1].Err)
+	require.Equal(t, &FaultError{Code: 1, String: "unable to handle method othermethod"}, calls[2].Err)
+	require.Equal(t, &FaultError{Code: 1, String: "recursive system.multicall forbidden"}, calls[3].Err)
+	require.NoError(t, calls[4].Err)
+	require.Equal(t, myResponse{Param: "bres"}, res5)
+}
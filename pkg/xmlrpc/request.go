@@ -11,6 +11,9 @@ import (
 type RequestRaw struct {
 	Method string
 	dec    *xml.Decoder
+
+	// whether params are contained in an array, as in calls of a multicall.
+	isArray bool
 }
 
 // Decode transforms a RequestRaw into a Request.
@@ -58,6 +61,20 @@ func requestDecodeRaw(r io.Reader) (*RequestRaw, error) {
 }
 
 func requestDecode(raw *RequestRaw, req interface{}) error {
+	if raw.isArray {
+		err := xmlGetStartElement(raw.dec, "array")
+		if err != nil {
+			return err
+		}
+
+		err = decodeArray(raw.dec, reflect.ValueOf(req))
+		if err != nil {
+			return err
+		}
+
+		return xmlConsumeUntilEOF(raw.dec)
+	}
+
 	err := xmlGetStartElement(raw.dec, "params")
 	if err != nil {
 		return err
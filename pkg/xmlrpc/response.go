@@ -2,92 +2,133 @@ package xmlrpc
 
 import (
 	"encoding/xml"
+	"fmt"
 	"io"
 	"reflect"
 )
 
-func responseDecode(r io.Reader, req interface{}) error {
+// FaultError is a fault returned by a server in place of a response.
+type FaultError struct {
+	Code   int    `xmlrpc:"faultCode"`
+	String string `xmlrpc:"faultString"`
+}
+
+// Error implements the error interface.
+func (e *FaultError) Error() string {
+	return fmt.Sprintf("server returned a fault (%d): %s", e.Code, e.String)
+}
+
+func responseDecode(r io.Reader, res interface{}) error {
 	dec := xml.NewDecoder(r)
 
-	err := xmlGetProcessingInstruction(dec)
+	err := responseDecodeStart(dec)
 	if err != nil {
 		return err
 	}
 
-	err = xmlGetStartElement(dec, "methodResponse")
-	if err != nil {
-		return err
-	}
+	rv := reflect.ValueOf(res)
 
-	err = xmlGetStartElement(dec, "params")
-	if err != nil {
-		return err
-	}
+	// ROS responses are arrays, whose values are mapped to the fields of a struct
+	if rv.Elem().Kind() == reflect.Struct && !isTaggedStruct(rv.Elem().Type()) {
+		err = xmlGetStartElement(dec, "array")
+		if err != nil {
+			return err
+		}
 
-	err = xmlGetStartElement(dec, "param")
+		err = decodeArray(dec, rv)
+	} else {
+		err = valueDecode(dec, rv)
+	}
 	if err != nil {
 		return err
 	}
 
-	err = xmlGetStartElement(dec, "value")
+	return xmlConsumeUntilEOF(dec)
+}
+
+// responseDecodeStart reads a response until the start of its value.
+// If the response is a fault, it returns a *FaultError.
+func responseDecodeStart(dec *xml.Decoder) error {
+	err := xmlGetProcessingInstruction(dec)
 	if err != nil {
 		return err
 	}
 
-	err = xmlGetStartElement(dec, "array")
+	err = xmlGetStartElement(dec, "methodResponse")
 	if err != nil {
 		return err
 	}
 
-	err = xmlGetStartElement(dec, "data")
+	name, err := xmlGetAnyStartElement(dec)
 	if err != nil {
 		return err
 	}
 
-	// read each value
-	rv := reflect.ValueOf(req).Elem()
-	nf := rv.NumField()
-	for i := 0; i < nf; i++ {
-		field := rv.Field(i).Addr()
+	switch name {
+	case "fault":
+		err = xmlGetStartElement(dec, "value")
+		if err != nil {
+			return err
+		}
 
-		err := xmlGetStartElement(dec, "value")
+		var fe FaultError
+		err = valueDecode(dec, reflect.ValueOf(&fe))
 		if err != nil {
 			return err
 		}
 
-		err = valueDecode(dec, field)
+		err = xmlConsumeUntilEOF(dec)
 		if err != nil {
 			return err
 		}
+
+		return &fe
+
+	case "params":
+
+	default:
+		return fmt.Errorf("expected xml.StartElement with name 'params', got '%s'", name)
 	}
 
-	return xmlConsumeUntilEOF(dec)
+	err = xmlGetStartElement(dec, "param")
+	if err != nil {
+		return err
+	}
+
+	return xmlGetStartElement(dec, "value")
 }
 
 func responseEncode(w io.Writer, params interface{}) error {
-	_, err := w.Write([]byte(`<?xml version="1.0"?><methodResponse><params>` +
-		`<param><value><array><data>`))
+	_, err := w.Write([]byte(`<?xml version="1.0"?><methodResponse><params><param>`))
 	if err != nil {
 		return err
 	}
 
-	// write each param
-	rv := reflect.ValueOf(params)
-	nf := rv.NumField()
-	for i := 0; i < nf; i++ {
-		field := rv.Field(i)
-
-		err = valueEncode(w, field)
-		if err != nil {
-			return err
-		}
+	// structs are encoded as arrays, whose values are the fields of the struct
+	err = valueEncode(w, reflect.ValueOf(params))
+	if err != nil {
+		return err
 	}
 
-	_, err = w.Write([]byte(`</data></array></value></param>` +
-		`</params></methodResponse>`))
+	_, err = w.Write([]byte(`</param></params></methodResponse>`))
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+func responseEncodeFault(w io.Writer, fe *FaultError) error {
+	_, err := w.Write([]byte(`<?xml version="1.0"?><methodResponse><fault>`))
+	if err != nil {
+		return err
+	}
+
+	err = valueEncode(w, reflect.ValueOf(fe))
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write([]byte(`</fault></methodResponse>`))
+	return err
+}
@@ -83,6 +83,36 @@ func TestResponseEncode(t *testing.T) {
 	}
 }
 
+func TestResponseDecodeFault(t *testing.T) {
+	enc := []byte(`<?xml version="1.0"?><methodResponse><fault><value><struct>` +
+		`<member><name>faultCode</name><value><int>4</int></value></member>` +
+		`<member><name>faultString</name><value><string>Too many parameters.</string></value></member>` +
+		`</struct></value></fault></methodResponse>`)
+
+	var res struct{}
+	err := responseDecode(bytes.NewReader(enc), &res)
+	require.Equal(t, &FaultError{Code: 4, String: "Too many parameters."}, err)
+
+	var buf bytes.Buffer
+	err = responseEncodeFault(&buf, &FaultError{Code: 4, String: "Too many parameters."})
+	require.NoError(t, err)
+	require.Equal(t, []byte(`<?xml version="1.0"?><methodResponse><fault><value><struct>`+
+		`<member><name>faultCode</name><value><i4>4</i4></value></member>`+
+		`<member><name>faultString</name><value>Too many parameters.</value></member>`+
+		`</struct></value></fault></methodResponse>`), buf.Bytes())
+}
+
+func TestResponseDecodeNonArray(t *testing.T) {
+	enc := []byte(`<?xml version="1.0"?><methodResponse><params><param>` +
+		`<value><struct><member><name>a</name><value><i4>1</i4></value></member></struct></value>` +
+		`</param></params></methodResponse>`)
+
+	var res interface{}
+	err := responseDecode(bytes.NewReader(enc), &res)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"a": 1}, res)
+}
+
 func TestResponseDecodeErrors(t *testing.T) {
 	for _, ca := range []struct {
 		name string
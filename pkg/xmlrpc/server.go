@@ -2,6 +2,7 @@ package xmlrpc
 
 import (
 	"context"
+	"crypto/tls"
 	"net"
 	"net/http"
 	"net/url"
@@ -15,8 +16,17 @@ type ErrorRes struct{}
 
 // ServerURL returns a XMLRPC server url.
 func ServerURL(address *net.TCPAddr, port int) string {
+	return serverURL("http", address, port)
+}
+
+// ServerURLWithTLS returns the url of a XMLRPC server that uses TLS.
+func ServerURLWithTLS(address *net.TCPAddr, port int) string {
+	return serverURL("https", address, port)
+}
+
+func serverURL(scheme string, address *net.TCPAddr, port int) string {
 	return (&url.URL{
-		Scheme: "http",
+		Scheme: scheme,
 		Host: (&net.TCPAddr{
 			IP:   address.IP,
 			Port: port,
@@ -38,6 +48,12 @@ type Server struct {
 
 // NewServer allocates a server.
 func NewServer(address string) (*Server, error) {
+	return NewServerWithTLS(address, nil)
+}
+
+// NewServerWithTLS allocates a server.
+// If tlsConf is not nil, requests are served with HTTPS.
+func NewServerWithTLS(address string, tlsConf *tls.Config) (*Server, error) {
 	// net.Listen and http.Server are splitted since the latter
 	// does not allow to use 0 as port
 	ln, err := net.Listen("tcp", address)
@@ -45,6 +61,10 @@ func NewServer(address string) (*Server, error) {
 		return nil, err
 	}
 
+	if tlsConf != nil {
+		ln = tls.NewListener(ln, tlsConf)
+	}
+
 	ctx, ctxCancel := context.WithCancel(context.Background())
 
 	s := &Server{
@@ -101,13 +121,23 @@ func (s *Server) run() {
 				return
 			}
 
-			res := handler(raw)
+			var res interface{}
+			if raw.Method == multicallMethod {
+				res = serverMulticall(raw, handler)
+			} else {
+				res = handler(raw)
+			}
 
 			if _, ok := res.(ErrorRes); ok {
 				w.WriteHeader(http.StatusBadRequest)
 				return
 			}
 
+			if fe, ok := res.(*FaultError); ok {
+				responseEncodeFault(w, fe)
+				return
+			}
+
 			responseEncode(w, res)
 		}),
 	}
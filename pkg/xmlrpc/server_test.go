@@ -2,8 +2,16 @@ package xmlrpc
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -44,3 +52,79 @@ func TestServer(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, myResponse{Param: "myresponse"}, xres)
 }
+
+func TestServerFault(t *testing.T) {
+	s, err := NewServer("localhost:9908")
+	require.NoError(t, err)
+	defer s.Close()
+
+	go s.Serve(func(raw *RequestRaw) interface{} {
+		return &FaultError{Code: 4, String: "unknown method"}
+	})
+
+	c := NewClient("localhost:9908")
+	var res struct{}
+	err = c.Do("mymethod", struct{}{}, &res)
+	require.Equal(t, &FaultError{Code: 4, String: "unknown method"}, err)
+	require.Equal(t, "server returned a fault (4): unknown method", err.Error())
+	require.Equal(t, uint64(1), c.ErrorCount())
+}
+
+func TestServerTLS(t *testing.T) {
+	type myRequest struct {
+		Param string
+	}
+
+	type myResponse struct {
+		Param string
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	s, err := NewServerWithTLS("localhost:9911", &tls.Config{
+		Certificates: []tls.Certificate{{
+			Certificate: [][]byte{der},
+			PrivateKey:  key,
+		}},
+	})
+	require.NoError(t, err)
+	defer s.Close()
+
+	go s.Serve(func(raw *RequestRaw) interface{} {
+		return myResponse{Param: "myresponse"}
+	})
+
+	c := NewClientWithHTTPClient("localhost:9911", NewHTTPClient(10*time.Second, &tls.Config{RootCAs: pool}))
+
+	var res myResponse
+	err = c.Do("mymethod", myRequest{Param: "myrequest"}, &res)
+	require.NoError(t, err)
+	require.Equal(t, myResponse{Param: "myresponse"}, res)
+
+	// plain HTTP requests are refused
+	c = NewClientWithHTTPClient("localhost:9911", NewHTTPClient(10*time.Second, nil))
+	err = c.Do("mymethod", myRequest{Param: "myrequest"}, &res)
+	require.Error(t, err)
+}
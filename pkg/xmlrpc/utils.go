@@ -22,11 +22,11 @@ func xmlGetProcessingInstruction(dec *xml.Decoder) error {
 	return nil
 }
 
-func xmlGetStartElement(dec *xml.Decoder, name string) error {
+func xmlGetAnyStartElement(dec *xml.Decoder) (string, error) {
 	for {
 		tok, err := dec.Token()
 		if err != nil {
-			return err
+			return "", err
 		}
 
 		switch ttok := tok.(type) {
@@ -34,20 +34,29 @@ func xmlGetStartElement(dec *xml.Decoder, name string) error {
 		case xml.CharData:
 
 		case xml.StartElement:
-			if ttok.Name.Local != name {
-				return fmt.Errorf("expected xml.StartElement with name '%s', got '%s'", name, ttok.Name.Local)
-			}
-			return nil
+			return ttok.Name.Local, nil
 
 		case xml.EndElement:
-			return errEndElement
+			return "", errEndElement
 
 		default:
-			return fmt.Errorf("unexpected element: %T", tok)
+			return "", fmt.Errorf("unexpected element: %T", tok)
 		}
 	}
 }
 
+func xmlGetStartElement(dec *xml.Decoder, name string) error {
+	got, err := xmlGetAnyStartElement(dec)
+	if err != nil {
+		return err
+	}
+
+	if got != name {
+		return fmt.Errorf("expected xml.StartElement with name '%s', got '%s'", name, got)
+	}
+	return nil
+}
+
 func xmlGetEndElement(dec *xml.Decoder, allowSpaces bool) error {
 	for {
 		tok, err := dec.Token()
@@ -1,14 +1,73 @@
 package xmlrpc
 
 import (
+	"bytes"
 	"encoding/base64"
 	"encoding/xml"
 	"fmt"
 	"io"
 	"reflect"
+	"sort"
 	"strconv"
+	"time"
 )
 
+// dateTimeFormat is the format of dateTime.iso8601 values.
+// Since the format does not include the time zone, times are encoded
+// in their location and decoded in UTC.
+const dateTimeFormat = "20060102T15:04:05"
+
+// dateTimeDecodeFormats are the formats accepted when decoding
+// dateTime.iso8601 values, since some implementations use the extended form.
+var dateTimeDecodeFormats = []string{
+	dateTimeFormat,
+	"2006-01-02T15:04:05",
+	"20060102T15:04:05Z07:00",
+	time.RFC3339,
+}
+
+// structFieldIndex returns the index of the field of a struct that corresponds
+// to a member name. The name is read from the xmlrpc tag, or from the field name.
+func structFieldIndex(typ reflect.Type, name string) (int, bool) {
+	nf := typ.NumField()
+	for i := 0; i < nf; i++ {
+		f := typ.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		fname, ok := structFieldName(f)
+		if ok && fname == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func structFieldName(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("xmlrpc")
+	switch tag {
+	case "-":
+		return "", false
+
+	case "":
+		return f.Name, true
+	}
+	return tag, true
+}
+
+// isTaggedStruct checks whether a struct must be encoded as a <struct>.
+// Structs without xmlrpc tags are encoded as arrays, as ROS does.
+func isTaggedStruct(typ reflect.Type) bool {
+	nf := typ.NumField()
+	for i := 0; i < nf; i++ {
+		if _, ok := typ.Field(i).Tag.Lookup("xmlrpc"); ok {
+			return true
+		}
+	}
+	return false
+}
+
 func decodeBool(in []byte, val reflect.Value) error {
 	if len(in) != 1 {
 		return fmt.Errorf("value is not a bool: %v", in)
@@ -111,6 +170,55 @@ func decodeBase64(in []byte, val reflect.Value) error {
 	return nil
 }
 
+func decodeDateTime(in []byte, val reflect.Value) error {
+	var v time.Time
+	var err error
+	for _, format := range dateTimeDecodeFormats {
+		v, err = time.Parse(format, string(in))
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("value is not a dateTime.iso8601: %s", in)
+	}
+
+	switch tval := val.Interface().(type) {
+	case *time.Time:
+		*tval = v
+
+	case *interface{}:
+		*tval = v
+
+	default:
+		return fmt.Errorf("cannot decode a dateTime.iso8601 into a %T", val.Interface())
+	}
+	return nil
+}
+
+func decodeArraySlice(dec *xml.Decoder, val reflect.Value) error {
+	typ := val.Elem().Type().Elem()
+
+	for {
+		err := xmlGetStartElement(dec, "value")
+		if err != nil {
+			// slice is over
+			if err == errEndElement {
+				return nil
+			}
+			return err
+		}
+
+		el := reflect.New(typ)
+		err = valueDecode(dec, el)
+		if err != nil {
+			return err
+		}
+
+		val.Elem().Set(reflect.Append(val.Elem(), el.Elem()))
+	}
+}
+
 func decodeArray(dec *xml.Decoder, val reflect.Value) error {
 	err := xmlGetStartElement(dec, "data")
 	if err != nil {
@@ -140,32 +248,167 @@ func decodeArray(dec *xml.Decoder, val reflect.Value) error {
 		}
 
 	case reflect.Slice:
-		typ := val.Elem().Type().Elem()
+		err := decodeArraySlice(dec, val)
+		if err != nil {
+			return err
+		}
 
-		for {
-			err := xmlGetStartElement(dec, "value")
+	case reflect.Interface:
+		if val.Elem().NumMethod() != 0 {
+			return fmt.Errorf("cannot decode an array into a %s", val.Elem().Type())
+		}
+
+		v := []interface{}{}
+		err := decodeArraySlice(dec, reflect.ValueOf(&v))
+		if err != nil {
+			return err
+		}
+
+		val.Elem().Set(reflect.ValueOf(v))
+
+	default:
+		return fmt.Errorf("cannot decode an array into a %s", val.Elem().Kind())
+	}
+
+	return xmlGetEndElement(dec, true)
+}
+
+// decodeStructMembers decodes the members of a struct, calling
+// target to obtain the destination of each member.
+// target returns an invalid value for members that must be skipped.
+func decodeStructMembers(dec *xml.Decoder, target func(name string) reflect.Value,
+	onDecoded func(name string, v reflect.Value)) error {
+	for {
+		err := xmlGetStartElement(dec, "member")
+		if err != nil {
+			// struct is over
+			if err == errEndElement {
+				return nil
+			}
+			return err
+		}
+
+		err = xmlGetStartElement(dec, "name")
+		if err != nil {
+			return err
+		}
+
+		name, err := xmlGetContent(dec)
+		if err != nil {
+			return err
+		}
+
+		err = xmlGetStartElement(dec, "value")
+		if err != nil {
+			return err
+		}
+
+		v := target(string(name))
+		if v.IsValid() {
+			err = valueDecode(dec, v)
 			if err != nil {
-				// slice is over
-				if err == errEndElement {
-					break
-				}
 				return err
 			}
 
-			el := reflect.New(typ)
-			err = valueDecode(dec, el)
+			if onDecoded != nil {
+				onDecoded(string(name), v)
+			}
+		} else {
+			err = dec.Skip()
 			if err != nil {
 				return err
 			}
+		}
 
-			val.Elem().Set(reflect.Append(val.Elem(), el.Elem()))
+		err = xmlGetEndElement(dec, true)
+		if err != nil {
+			return err
 		}
+	}
+}
 
-	default:
-		return fmt.Errorf("cannot decode an array into a %s", val.Elem().Kind())
+func decodeStruct(dec *xml.Decoder, val reflect.Value) error {
+	switch val.Elem().Kind() {
+	case reflect.Struct:
+		return decodeStructMembers(dec, func(name string) reflect.Value {
+			i, ok := structFieldIndex(val.Elem().Type(), name)
+			if !ok {
+				return reflect.Value{}
+			}
+			return val.Elem().Field(i).Addr()
+		}, nil)
+
+	case reflect.Map:
+		typ := val.Elem().Type()
+		if typ.Key().Kind() != reflect.String {
+			return fmt.Errorf("cannot decode a struct into a %s", typ)
+		}
+
+		if val.Elem().IsNil() {
+			val.Elem().Set(reflect.MakeMap(typ))
+		}
+
+		return decodeStructMembers(dec, func(name string) reflect.Value {
+			return reflect.New(typ.Elem())
+		}, func(name string, v reflect.Value) {
+			val.Elem().SetMapIndex(reflect.ValueOf(name).Convert(typ.Key()), v.Elem())
+		})
+
+	case reflect.Interface:
+		if val.Elem().NumMethod() != 0 {
+			return fmt.Errorf("cannot decode a struct into a %s", val.Elem().Type())
+		}
+
+		v := map[string]interface{}{}
+		err := decodeStruct(dec, reflect.ValueOf(&v))
+		if err != nil {
+			return err
+		}
+
+		val.Elem().Set(reflect.ValueOf(v))
+		return nil
 	}
 
-	return xmlGetEndElement(dec, true)
+	return fmt.Errorf("cannot decode a struct into a %s", val.Elem().Kind())
+}
+
+// rawValue is a value that is stored in XML format, in order to be decoded later.
+type rawValue []byte
+
+func decodeRaw(dec *xml.Decoder, tok xml.Token, val *rawValue) error {
+	var buf bytes.Buffer
+	enc := xml.NewEncoder(&buf)
+	depth := 0
+
+	for {
+		switch tok.(type) {
+		case xml.StartElement:
+			depth++
+
+		case xml.EndElement:
+			// end of value
+			if depth == 0 {
+				err := enc.Flush()
+				if err != nil {
+					return err
+				}
+
+				*val = buf.Bytes()
+				return nil
+			}
+			depth--
+		}
+
+		err := enc.EncodeToken(xml.CopyToken(tok))
+		if err != nil {
+			return err
+		}
+
+		tok, err = dec.Token()
+		if err != nil {
+			return err
+		}
+	}
 }
 
 func valueDecode(dec *xml.Decoder, val reflect.Value) error {
@@ -174,6 +417,29 @@ func valueDecode(dec *xml.Decoder, val reflect.Value) error {
 		return err
 	}
 
+	if rv, ok := val.Interface().(*rawValue); ok {
+		return decodeRaw(dec, tok, rv)
+	}
+
+	if ttok, ok := tok.(xml.StartElement); ok && ttok.Name.Local == "nil" {
+		err := dec.Skip()
+		if err != nil {
+			return err
+		}
+
+		val.Elem().Set(reflect.Zero(val.Elem().Type()))
+
+		return xmlGetEndElement(dec, true)
+	}
+
+	// allocate pointers
+	for val.Elem().Kind() == reflect.Ptr {
+		if val.Elem().IsNil() {
+			val.Elem().Set(reflect.New(val.Elem().Type().Elem()))
+		}
+		val = val.Elem()
+	}
+
 	switch ttok := tok.(type) {
 	// type name
 	case xml.StartElement:
@@ -233,12 +499,29 @@ func valueDecode(dec *xml.Decoder, val reflect.Value) error {
 				return err
 			}
 
+		case "dateTime.iso8601":
+			cnt, err := xmlGetContent(dec)
+			if err != nil {
+				return err
+			}
+
+			err = decodeDateTime(cnt, val)
+			if err != nil {
+				return err
+			}
+
 		case "array":
 			err = decodeArray(dec, val)
 			if err != nil {
 				return err
 			}
 
+		case "struct":
+			err = decodeStruct(dec, val)
+			if err != nil {
+				return err
+			}
+
 		default:
 			return fmt.Errorf("unhandled value type: %s", ttok.Name.Local)
 		}
@@ -274,7 +557,112 @@ func valueDecode(dec *xml.Decoder, val reflect.Value) error {
 	return nil
 }
 
+func encodeArray(w io.Writer, le int, el func(i int) reflect.Value) error {
+	_, err := w.Write([]byte(`<array><data>`))
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < le; i++ {
+		err := valueEncode(w, el(i))
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = w.Write([]byte(`</data></array>`))
+	return err
+}
+
+func encodeStructMember(w io.Writer, name string, val reflect.Value) error {
+	_, err := w.Write([]byte(`<member><name>`))
+	if err != nil {
+		return err
+	}
+
+	err = xml.EscapeText(w, []byte(name))
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write([]byte(`</name>`))
+	if err != nil {
+		return err
+	}
+
+	err = valueEncode(w, val)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write([]byte(`</member>`))
+	return err
+}
+
+func encodeStruct(w io.Writer, val reflect.Value) error {
+	_, err := w.Write([]byte(`<struct>`))
+	if err != nil {
+		return err
+	}
+
+	switch val.Kind() {
+	case reflect.Struct:
+		nf := val.NumField()
+		for i := 0; i < nf; i++ {
+			f := val.Type().Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+
+			name, ok := structFieldName(f)
+			if !ok {
+				continue
+			}
+
+			err := encodeStructMember(w, name, val.Field(i))
+			if err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		if val.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("unhandled map key type: %s", val.Type().Key())
+		}
+
+		// sort keys to obtain a deterministic output
+		keys := val.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+
+		for _, key := range keys {
+			err := encodeStructMember(w, key.String(), val.MapIndex(key))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = w.Write([]byte(`</struct>`))
+	return err
+}
+
 func valueEncode(w io.Writer, val reflect.Value) error {
+	// dereference interfaces and pointers
+	for val.Kind() == reflect.Interface || val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			val = reflect.Value{}
+			break
+		}
+		val = val.Elem()
+	}
+
+	if !val.IsValid() {
+		_, err := w.Write([]byte(`<value><nil/></value>`))
+		return err
+	}
+
 	_, err := w.Write([]byte(`<value>`))
 	if err != nil {
 		return err
@@ -304,7 +692,13 @@ func valueEncode(w io.Writer, val reflect.Value) error {
 		}
 
 	case string:
-		_, err := w.Write([]byte(tval))
+		err := xml.EscapeText(w, []byte(tval))
+		if err != nil {
+			return err
+		}
+
+	case time.Time:
+		_, err := w.Write([]byte(`<dateTime.iso8601>` + tval.Format(dateTimeFormat) + `</dateTime.iso8601>`))
 		if err != nil {
 			return err
 		}
@@ -318,43 +712,27 @@ func valueEncode(w io.Writer, val reflect.Value) error {
 	default:
 		switch val.Kind() {
 		case reflect.Struct:
-			_, err := w.Write([]byte(`<array><data>`))
-			if err != nil {
-				return err
-			}
-
-			nf := val.NumField()
-			for i := 0; i < nf; i++ {
-				field := val.Field(i)
-
-				err := valueEncode(w, field)
+			if isTaggedStruct(val.Type()) {
+				err := encodeStruct(w, val)
 				if err != nil {
 					return err
 				}
+				break
 			}
 
-			_, err = w.Write([]byte(`</data></array>`))
+			err := encodeArray(w, val.NumField(), val.Field)
 			if err != nil {
 				return err
 			}
 
-		case reflect.Slice:
-			_, err := w.Write([]byte(`<array><data>`))
+		case reflect.Map:
+			err := encodeStruct(w, val)
 			if err != nil {
 				return err
 			}
 
-			le := val.Len()
-			for i := 0; i < le; i++ {
-				el := val.Index(i)
-
-				err := valueEncode(w, el)
-				if err != nil {
-					return err
-				}
-			}
-
-			_, err = w.Write([]byte(`</data></array>`))
+		case reflect.Slice, reflect.Array:
+			err := encodeArray(w, val.Len(), val.Index)
 			if err != nil {
 				return err
 			}
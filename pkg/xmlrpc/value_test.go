@@ -6,6 +6,7 @@ import (
 	"io"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -121,6 +122,115 @@ var casesValue = []struct {
 			`</data></array></value>`),
 		[]interface{}{"test1", "test2", 123, -1.324543, []byte("\x01\x02\x03\x04")},
 	},
+	{
+		"array as slice, nested",
+		[]byte(`<value><array><data>` +
+			`<value>test1</value>` +
+			`<value><array><data><value><i4>123</i4></value></data></array></value>` +
+			`</data></array></value>`),
+		[]byte(`<value><array><data>` +
+			`<value>test1</value>` +
+			`<value><array><data><value><i4>123</i4></value></data></array></value>` +
+			`</data></array></value>`),
+		[]interface{}{"test1", []interface{}{123}},
+	},
+	{
+		"string with special characters",
+		[]byte("<value>a&lt;b&amp;c</value>"),
+		[]byte("<value>a&lt;b&amp;c</value>"),
+		"a<b&c",
+	},
+	{
+		"dateTime",
+		[]byte("<value><dateTime.iso8601>19980717T14:08:55</dateTime.iso8601></value>"),
+		[]byte("<value><dateTime.iso8601>19980717T14:08:55</dateTime.iso8601></value>"),
+		time.Date(1998, 7, 17, 14, 8, 55, 0, time.UTC),
+	},
+	{
+		"nil",
+		[]byte("<value><nil/></value>"),
+		[]byte("<value><nil/></value>"),
+		(*int)(nil),
+	},
+	{
+		"pointer",
+		[]byte("<value><i4>5</i4></value>"),
+		[]byte("<value><i4>5</i4></value>"),
+		func() *int {
+			v := 5
+			return &v
+		}(),
+	},
+	{
+		"struct as tagged struct",
+		[]byte(`<value><struct>` +
+			`<member><name>param1</name><value>test1</value></member>` +
+			`<member><name>Param2</name><value><i4>123</i4></value></member>` +
+			`</struct></value>`),
+		[]byte(`<value><struct>` +
+			`<member><name>param1</name><value>test1</value></member>` +
+			`<member><name>Param2</name><value><i4>123</i4></value></member>` +
+			`</struct></value>`),
+		TaggedStruct{
+			Param1: "test1",
+			Param2: 123,
+		},
+	},
+	{
+		"struct as map",
+		[]byte(`<value><struct>` +
+			`<member><name>a</name><value><i4>1</i4></value></member>` +
+			`<member><name>b</name><value><i4>2</i4></value></member>` +
+			`</struct></value>`),
+		[]byte(`<value><struct>` +
+			`<member><name>a</name><value><i4>1</i4></value></member>` +
+			`<member><name>b</name><value><i4>2</i4></value></member>` +
+			`</struct></value>`),
+		map[string]int{"a": 1, "b": 2},
+	},
+	{
+		"struct as map, multi type",
+		[]byte(`<value><struct>` +
+			`<member><name>a</name><value><i4>1</i4></value></member>` +
+			`<member><name>b</name><value><array><data><value>test1</value></data></array></value></member>` +
+			`<member><name>c</name><value><struct>` +
+			`<member><name>d</name><value><nil/></value></member>` +
+			`</struct></value></member>` +
+			`</struct></value>`),
+		[]byte(`<value><struct>` +
+			`<member><name>a</name><value><i4>1</i4></value></member>` +
+			`<member><name>b</name><value><array><data><value>test1</value></data></array></value></member>` +
+			`<member><name>c</name><value><struct>` +
+			`<member><name>d</name><value><nil/></value></member>` +
+			`</struct></value></member>` +
+			`</struct></value>`),
+		map[string]interface{}{
+			"a": 1,
+			"b": []interface{}{"test1"},
+			"c": map[string]interface{}{"d": nil},
+		},
+	},
+}
+
+type TaggedStruct struct {
+	Param1 string `xmlrpc:"param1"`
+	Param2 int    `xmlrpc:"Param2"`
+	Param3 int    `xmlrpc:"-"`
+}
+
+func TestValueDecodeStructUnknownMember(t *testing.T) {
+	dec := xml.NewDecoder(bytes.NewReader([]byte(`<value><struct>` +
+		`<member><name>param1</name><value>test1</value></member>` +
+		`<member><name>other</name><value><array><data><value>x</value></data></array></value></member>` +
+		`</struct></value>`)))
+
+	err := xmlGetStartElement(dec, "value")
+	require.NoError(t, err)
+
+	var v TaggedStruct
+	err = valueDecode(dec, reflect.ValueOf(&v))
+	require.NoError(t, err)
+	require.Equal(t, TaggedStruct{Param1: "test1"}, v)
 }
 
 func TestValueDecode(t *testing.T) {
@@ -1,20 +1,14 @@
 package goroslib
 
 import (
-	"bytes"
 	"context"
-	"encoding/binary"
 	"fmt"
 	"net"
 	"reflect"
-	"strconv"
-	"sync"
+	"sync/atomic"
+	"time"
 
-	"github.com/aler9/goroslib/pkg/apislave"
 	"github.com/aler9/goroslib/pkg/msgproc"
-	"github.com/aler9/goroslib/pkg/protocommon"
-	"github.com/aler9/goroslib/pkg/prototcp"
-	"github.com/aler9/goroslib/pkg/protoudp"
 )
 
 // PublisherConf is the configuration of a Publisher.
@@ -33,31 +27,50 @@ type PublisherConf struct {
 	// this publisher
 	Latch bool
 
+	// (optional) maximum number of bytes per second sent to each UDPROS subscriber.
+	// Messages are split into datagrams that are spaced out in time, in order to avoid
+	// overflowing socket buffers and network queues. Messages that are written faster
+	// than they can be sent are dropped.
+	// It defaults to unlimited.
+	UdpRate int
+
+	// (optional) minimum time between two datagrams sent to each UDPROS subscriber.
+	// It defaults to zero.
+	UdpFrameGap time.Duration
+
+	// (optional) multicast group (i.e. 239.255.0.1:9000) in which messages are sent
+	// to UDP subscribers that enabled multicast. Each message is sent once,
+	// regardless of the number of these subscribers. Each publisher should use a dedicated group.
+	// It defaults to none, that means that messages are sent to each subscriber separately.
+	UdpMulticastGroup string
+
+	// (optional) messages are passed to subscribers of the same process
+	// without being copied. If true, messages must not be modified after being written.
+	// It defaults to false, that means that a copy of each message is passed
+	// to subscribers of the same process.
+	IntraProcessShare bool
+
+	// (optional) whether to compress messages sent to TCPROS subscribers with zstd.
+	// Compression is used only with subscribers that support it (currently goroslib only);
+	// other subscribers receive plain messages.
+	// It defaults to false.
+	TcpCompression bool
+
 	onSubscriber func()
 }
 
 // Publisher is a ROS publisher, an entity that can publish messages in a named channel.
+// Publishers of the same node that write into the same topic share a single
+// registration with the master and a single set of subscribers; they must
+// have the same message type and latching setting.
 type Publisher struct {
 	conf PublisherConf
 
-	ctx           context.Context
-	ctxCancel     func()
-	msgType       string
-	msgMd5        string
-	subscribers   map[string]*publisherSubscriber
-	subscribersWg sync.WaitGroup
-	lastMessage   interface{}
-	id            int
-
-	// in
-	getBusInfo         chan getBusInfoSubReq
-	requestTopic       chan subscriberRequestTopicReq
-	subscriberTCPNew   chan tcpConnSubscriberReq
-	subscriberTCPClose chan *publisherSubscriber
-	write              chan interface{}
-
-	// out
-	done chan struct{}
+	ctx       context.Context
+	ctxCancel func()
+	msgType   string
+	msgMd5    string
+	tp        *topicPublisher
 }
 
 // NewPublisher allocates a Publisher. See PublisherConf for the options.
@@ -82,6 +95,25 @@ func NewPublisher(conf PublisherConf) (*Publisher, error) {
 		return nil, fmt.Errorf("Msg must be a pointer to a struct")
 	}
 
+	if conf.UdpRate < 0 {
+		return nil, fmt.Errorf("UdpRate must be positive")
+	}
+
+	if conf.UdpFrameGap < 0 {
+		return nil, fmt.Errorf("UdpFrameGap must be positive")
+	}
+
+	if conf.UdpMulticastGroup != "" {
+		addr, err := net.ResolveUDPAddr("udp4", conf.UdpMulticastGroup)
+		if err != nil {
+			return nil, fmt.Errorf("invalid UdpMulticastGroup: %s", err)
+		}
+
+		if !addr.IP.IsMulticast() {
+			return nil, fmt.Errorf("UdpMulticastGroup must be a multicast address")
+		}
+	}
+
 	msgType, err := msgproc.Type(conf.Msg)
 	if err != nil {
 		return nil, err
@@ -95,18 +127,11 @@ func NewPublisher(conf PublisherConf) (*Publisher, error) {
 	ctx, ctxCancel := context.WithCancel(conf.Node.ctx)
 
 	p := &Publisher{
-		conf:               conf,
-		ctx:                ctx,
-		ctxCancel:          ctxCancel,
-		msgType:            msgType,
-		msgMd5:             msgMd5,
-		subscribers:        make(map[string]*publisherSubscriber),
-		getBusInfo:         make(chan getBusInfoSubReq),
-		requestTopic:       make(chan subscriberRequestTopicReq),
-		subscriberTCPNew:   make(chan tcpConnSubscriberReq),
-		subscriberTCPClose: make(chan *publisherSubscriber),
-		write:              make(chan interface{}),
-		done:               make(chan struct{}),
+		conf:      conf,
+		ctx:       ctx,
+		ctxCancel: ctxCancel,
+		msgType:   msgType,
+		msgMd5:    msgMd5,
 	}
 
 	cerr := make(chan error)
@@ -117,290 +142,38 @@ func NewPublisher(conf PublisherConf) (*Publisher, error) {
 	}:
 		err = <-cerr
 		if err != nil {
+			ctxCancel()
 			return nil, err
 		}
 
 	case <-conf.Node.ctx.Done():
+		ctxCancel()
 		return nil, fmt.Errorf("terminated")
 	}
 
-	go p.run()
-
 	return p, nil
 }
 
 // Close closes a Publisher and shuts down all its operations.
+// The topic is unregistered when the last Publisher of the topic is closed.
 func (p *Publisher) Close() error {
 	p.ctxCancel()
-	<-p.done
-	return nil
-}
 
-func (p *Publisher) run() {
-	defer close(p.done)
-
-outer:
-	for {
-		select {
-		case req := <-p.getBusInfo:
-			for _, ps := range p.subscribers {
-				proto := func() string {
-					if ps.tcpClient != nil {
-						return "TCPROS"
-					}
-					return "UDPROS"
-				}()
-				*req.pbusInfo = append(*req.pbusInfo,
-					[]interface{}{
-						0, ps.callerID, "o", proto,
-						p.conf.Node.absoluteTopicName(p.conf.Topic), true,
-					})
-			}
-			close(req.done)
-
-		case req := <-p.requestTopic:
-			err := func() error {
-				if len(req.req.Protocols) < 1 {
-					return fmt.Errorf("invalid protocol")
-				}
-
-				proto := req.req.Protocols[0]
-
-				if len(proto) < 1 {
-					return fmt.Errorf("invalid protocol")
-				}
-
-				protoName, ok := proto[0].(string)
-				if !ok {
-					return fmt.Errorf("invalid protocol")
-				}
-
-				switch protoName {
-				case "TCPROS":
-					nodeIP, _, _ := net.SplitHostPort(p.conf.Node.nodeAddr.String())
-					req.res <- apislave.ResponseRequestTopic{
-						Code:          1,
-						StatusMessage: "",
-						Protocol: []interface{}{
-							"TCPROS",
-							nodeIP,
-							p.conf.Node.tcprosServer.Port(),
-						},
-					}
-					return nil
-
-				case "UDPROS":
-					if len(proto) < 5 {
-						return fmt.Errorf("invalid protocol")
-					}
-
-					protoDef, ok := proto[1].([]byte)
-					if !ok {
-						return fmt.Errorf("invalid protoDef")
-					}
-
-					protoHost, ok := proto[2].(string)
-					if !ok {
-						return fmt.Errorf("invalid protoHost")
-					}
-
-					protoPort, ok := proto[3].(int)
-					if !ok {
-						return fmt.Errorf("invalid protoPort")
-					}
-
-					_, ok = proto[4].(int)
-					if !ok {
-						return fmt.Errorf("invalid proto1500")
-					}
-
-					newProtoDef := make([]byte, 4)
-					binary.LittleEndian.PutUint32(newProtoDef, uint32(len(protoDef)))
-					newProtoDef = append(newProtoDef, protoDef...)
-					buf := bytes.NewBuffer(newProtoDef)
-
-					raw, err := protocommon.HeaderRawDecode(buf)
-					if err != nil {
-						return err
-					}
-
-					var header protoudp.HeaderSubscriber
-					err = protocommon.HeaderDecode(raw, &header)
-					if err != nil {
-						return err
-					}
-
-					_, ok = p.subscribers[header.Callerid]
-					if ok {
-						return fmt.Errorf("topic '%s' is already subscribed by '%s'",
-							p.conf.Topic, header.Callerid)
-					}
-
-					if header.Md5sum != p.msgMd5 {
-						return fmt.Errorf("wrong md5: expected '%s', got '%s'",
-							p.msgMd5, header.Md5sum)
-					}
-
-					udpAddr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(protoHost, strconv.FormatInt(int64(protoPort), 10)))
-					if err != nil {
-						return fmt.Errorf("unable to solve udp address)")
-					}
-
-					// if subscriber is in localhost, send packets from localhost to localhost
-					// this avoids a bug in which the source ip is randomly chosen
-					// from all available interfaces, making ip-based filtering unpractical
-					isLocalhost := func() bool {
-						ifaces, err := net.Interfaces()
-						if err != nil {
-							return false
-						}
-
-						for _, i := range ifaces {
-							addrs, err := i.Addrs()
-							if err != nil {
-								continue
-							}
-
-							for _, addr := range addrs {
-								if v, ok := addr.(*net.IPNet); ok {
-									if v.IP.Equal(udpAddr.IP) {
-										return true
-									}
-								}
-							}
-						}
-						return false
-					}()
-
-					if isLocalhost {
-						udpAddr.IP = net.IPv4(127, 0, 0, 1)
-					}
-
-					newPublisherSubscriber(p,
-						header.Callerid, nil, udpAddr)
-
-					req.res <- apislave.ResponseRequestTopic{
-						Code:          1,
-						StatusMessage: "",
-						Protocol: []interface{}{
-							"UDPROS",
-							func() string {
-								if isLocalhost {
-									return "127.0.0.1"
-								}
-								nodeIP, _, _ := net.SplitHostPort(p.conf.Node.nodeAddr.String())
-								return nodeIP
-							}(),
-							p.conf.Node.udprosServer.Port(),
-							p.id,
-							1500,
-							func() []byte {
-								var buf bytes.Buffer
-								protocommon.HeaderEncode(&buf, &protoudp.HeaderPublisher{
-									Callerid: p.conf.Node.absoluteName(),
-									Md5sum:   p.msgMd5,
-									Topic:    p.conf.Node.absoluteTopicName(p.conf.Topic),
-									Type:     p.msgType,
-								})
-								return buf.Bytes()[4:]
-							}(),
-						},
-					}
-
-					return nil
-				}
-
-				return fmt.Errorf("invalid protocol")
-			}()
-			if err != nil {
-				req.res <- apislave.ResponseRequestTopic{
-					Code:          0,
-					StatusMessage: err.Error(),
-				}
-				continue
-			}
-
-		case req := <-p.subscriberTCPNew:
-			err := func() error {
-				_, ok := p.subscribers[req.header.Callerid]
-				if ok {
-					return fmt.Errorf("topic '%s' is already subscribed by '%s'",
-						p.conf.Topic, req.header.Callerid)
-				}
-
-				// wildcard is used by rostopic hz
-				if req.header.Md5sum != "*" && req.header.Md5sum != p.msgMd5 {
-					return fmt.Errorf("wrong md5: expected '%s', got '%s'",
-						p.msgMd5, req.header.Md5sum)
-				}
-
-				err := req.conn.WriteHeader(&prototcp.HeaderPublisher{
-					Callerid: p.conf.Node.absoluteName(),
-					Md5sum:   p.msgMd5,
-					Topic:    p.conf.Node.absoluteTopicName(p.conf.Topic),
-					Type:     p.msgType,
-					Latching: func() int {
-						if p.conf.Latch {
-							return 1
-						}
-						return 0
-					}(),
-				})
-				if err != nil {
-					req.conn.Close()
-					return nil
-				}
-
-				if req.header.TcpNodelay == 0 {
-					req.conn.NetConn().(*net.TCPConn).SetNoDelay(false)
-				}
-
-				newPublisherSubscriber(p,
-					req.header.Callerid, req.conn, nil)
-
-				if p.conf.Latch && p.lastMessage != nil {
-					p.subscribers[req.header.Callerid].writeMessage(p.lastMessage)
-				}
-
-				return nil
-			}()
-			if err != nil {
-				req.conn.WriteHeader(&prototcp.HeaderError{
-					Error: err.Error(),
-				})
-				req.conn.Close()
-				continue
-			}
-
-		case sub := <-p.subscriberTCPClose:
-			sub.close()
-
-		case msg := <-p.write:
-			if p.conf.Latch {
-				p.lastMessage = msg
-			}
-
-			for _, s := range p.subscribers {
-				s.writeMessage(msg)
-			}
-
-		case <-p.ctx.Done():
-			break outer
+	res := make(chan *topicPublisher)
+	select {
+	case p.conf.Node.publisherClose <- publisherCloseReq{
+		pub: p,
+		res: res,
+	}:
+		// wait until the topic has been closed
+		if tp := <-res; tp != nil {
+			<-tp.done
 		}
-	}
-
-	p.ctxCancel()
-
-	p.conf.Node.apiMasterClient.UnregisterPublisher(
-		p.conf.Node.absoluteTopicName(p.conf.Topic),
-		p.conf.Node.apiSlaveServerURL)
 
-	p.subscribersWg.Wait()
-
-	select {
-	case p.conf.Node.publisherClose <- p:
 	case <-p.conf.Node.ctx.Done():
 	}
+
+	return nil
 }
 
 // Write writes a message into the publisher.
@@ -409,8 +182,18 @@ func (p *Publisher) Write(msg interface{}) {
 		panic("wrong message type")
 	}
 
+	// the message can be modified by the caller after Write returns,
+	// therefore subscribers of the same process receive a copy.
+	// Latched messages are always copied, since they can be passed
+	// to subscribers of the same process that connect later.
+	if !p.conf.IntraProcessShare &&
+		(p.conf.Latch || atomic.LoadInt32(&p.tp.intraSubscribers) > 0) {
+		msg = copyMessage(msg)
+	}
+
 	select {
-	case p.write <- msg:
+	case p.tp.write <- msg:
 	case <-p.ctx.Done():
+	case <-p.tp.ctx.Done():
 	}
 }
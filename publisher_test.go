@@ -417,6 +417,337 @@ func TestPublisherWriteUdp(t *testing.T) {
 	}
 }
 
+func TestPublisherUdpDeadSubscriber(t *testing.T) {
+	m, err := newContainerMaster()
+	require.NoError(t, err)
+	defer m.close()
+
+	n, err := NewNode(NodeConf{
+		Namespace:        "/myns",
+		Name:             "goroslib",
+		MasterAddress:    m.IP() + ":11311",
+		UdprosPingPeriod: 100 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer n.Close()
+
+	pub, err := NewPublisher(PublisherConf{
+		Node:  n,
+		Topic: "test_topic",
+		Msg:   &std_msgs.Int64MultiArray{},
+	})
+	require.NoError(t, err)
+	defer pub.Close()
+
+	ns, err := NewNode(NodeConf{
+		Namespace:             "/myns",
+		Name:                  "goroslibsub",
+		MasterAddress:         m.IP() + ":11311",
+		DisableIntraProcess:   true,
+		UdprosMaxDatagramSize: 200,
+		UdprosPingPeriod:      100 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	recv := make(chan *std_msgs.Int64MultiArray)
+	sub, err := NewSubscriber(SubscriberConf{
+		Node:  ns,
+		Topic: "test_topic",
+		Callback: func(msg *std_msgs.Int64MultiArray) {
+			recv <- msg
+		},
+		Protocol: UDP,
+	})
+	require.NoError(t, err)
+
+	time.Sleep(1 * time.Second)
+
+	// the message is split into datagrams of the size requested by the subscriber
+	sent := &std_msgs.Int64MultiArray{}
+	for i := int64(1); i <= 400; i++ {
+		sent.Data = append(sent.Data, i)
+	}
+	pub.Write(sent)
+	require.Equal(t, sent, <-recv)
+
+	udpSubscribers := func() int {
+		count := 0
+		for _, cs := range n.Stats() {
+			if cs.Transport == "UDPROS" && cs.Direction == 'o' {
+				count++
+			}
+		}
+		return count
+	}
+
+	// the subscriber sends pings and is kept alive
+	time.Sleep(1 * time.Second)
+	require.Equal(t, 1, udpSubscribers())
+
+	// the subscriber stops sending pings and is removed
+	sub.Close()
+	ns.Close()
+	time.Sleep(1 * time.Second)
+	require.Equal(t, 0, udpSubscribers())
+}
+
+func TestPublisherUdpPacing(t *testing.T) {
+	m, err := newContainerMaster()
+	require.NoError(t, err)
+	defer m.close()
+
+	n, err := NewNode(NodeConf{
+		Namespace:              "/myns",
+		Name:                   "goroslib",
+		MasterAddress:          m.IP() + ":11311",
+		UdprosSocketBufferSize: 1024 * 1024,
+	})
+	require.NoError(t, err)
+	defer n.Close()
+
+	pub, err := NewPublisher(PublisherConf{
+		Node:    n,
+		Topic:   "test_topic",
+		Msg:     &std_msgs.Int64MultiArray{},
+		UdpRate: 20000,
+	})
+	require.NoError(t, err)
+	defer pub.Close()
+
+	ns, err := NewNode(NodeConf{
+		Namespace:           "/myns",
+		Name:                "goroslibsub",
+		MasterAddress:       m.IP() + ":11311",
+		DisableIntraProcess: true,
+	})
+	require.NoError(t, err)
+	defer ns.Close()
+
+	recv := make(chan *std_msgs.Int64MultiArray)
+	sub, err := NewSubscriber(SubscriberConf{
+		Node:  ns,
+		Topic: "test_topic",
+		Callback: func(msg *std_msgs.Int64MultiArray) {
+			recv <- msg
+		},
+		Protocol: UDP,
+	})
+	require.NoError(t, err)
+	defer sub.Close()
+
+	time.Sleep(1 * time.Second)
+
+	// 8 KB at 20 KB/s take about 400ms
+	sent := &std_msgs.Int64MultiArray{}
+	for i := int64(1); i <= 1000; i++ {
+		sent.Data = append(sent.Data, i)
+	}
+	start := time.Now()
+	pub.Write(sent)
+	require.Equal(t, sent, <-recv)
+	require.Equal(t, true, time.Since(start) > 300*time.Millisecond)
+}
+
+func TestPublisherUdpMulticast(t *testing.T) {
+	m, err := newContainerMaster()
+	require.NoError(t, err)
+	defer m.close()
+
+	n, err := NewNode(NodeConf{
+		Namespace:     "/myns",
+		Name:          "goroslib",
+		MasterAddress: m.IP() + ":11311",
+	})
+	require.NoError(t, err)
+	defer n.Close()
+
+	_, err = NewPublisher(PublisherConf{
+		Node:              n,
+		Topic:             "test_topic",
+		Msg:               &std_msgs.Int64MultiArray{},
+		UdpMulticastGroup: "127.0.0.1:9000",
+	})
+	require.EqualError(t, err, "UdpMulticastGroup must be a multicast address")
+
+	pub, err := NewPublisher(PublisherConf{
+		Node:              n,
+		Topic:             "test_topic",
+		Msg:               &std_msgs.Int64MultiArray{},
+		UdpMulticastGroup: "239.255.0.1:9000",
+	})
+	require.NoError(t, err)
+	defer pub.Close()
+
+	var recvs []chan *std_msgs.Int64MultiArray
+
+	for i, multicast := range []bool{true, true, false} {
+		ns, err := NewNode(NodeConf{
+			Namespace:           "/myns",
+			Name:                "goroslibsub" + strconv.FormatInt(int64(i), 10),
+			MasterAddress:       m.IP() + ":11311",
+			DisableIntraProcess: true,
+		})
+		require.NoError(t, err)
+		defer ns.Close()
+
+		recv := make(chan *std_msgs.Int64MultiArray)
+		recvs = append(recvs, recv)
+
+		sub, err := NewSubscriber(SubscriberConf{
+			Node:  ns,
+			Topic: "test_topic",
+			Callback: func(msg *std_msgs.Int64MultiArray) {
+				recv <- msg
+			},
+			Protocol:     UDP,
+			UdpMulticast: multicast,
+		})
+		require.NoError(t, err)
+		defer sub.Close()
+	}
+
+	time.Sleep(1 * time.Second)
+
+	sent := &std_msgs.Int64MultiArray{}
+	for i := int64(1); i <= 400; i++ {
+		sent.Data = append(sent.Data, i)
+	}
+	pub.Write(sent)
+
+	for _, recv := range recvs {
+		require.Equal(t, sent, <-recv)
+	}
+}
+
+func TestPublisherSharedMemory(t *testing.T) {
+	m, err := newContainerMaster()
+	require.NoError(t, err)
+	defer m.close()
+
+	n, err := NewNode(NodeConf{
+		Namespace:          "/myns",
+		Name:               "goroslib",
+		MasterAddress:      m.IP() + ":11311",
+		EnableSharedMemory: true,
+	})
+	require.NoError(t, err)
+	defer n.Close()
+
+	pub, err := NewPublisher(PublisherConf{
+		Node:  n,
+		Topic: "test_topic",
+		Msg:   &std_msgs.Int64MultiArray{},
+	})
+	require.NoError(t, err)
+	defer pub.Close()
+
+	ns, err := NewNode(NodeConf{
+		Namespace:           "/myns",
+		Name:                "goroslibsub",
+		MasterAddress:       m.IP() + ":11311",
+		DisableIntraProcess: true,
+		EnableSharedMemory:  true,
+	})
+	require.NoError(t, err)
+	defer ns.Close()
+
+	recv := make(chan *std_msgs.Int64MultiArray)
+	sub, err := NewSubscriber(SubscriberConf{
+		Node:  ns,
+		Topic: "test_topic",
+		Callback: func(msg *std_msgs.Int64MultiArray) {
+			recv <- msg
+		},
+	})
+	require.NoError(t, err)
+	defer sub.Close()
+
+	time.Sleep(1 * time.Second)
+
+	sent := &std_msgs.Int64MultiArray{}
+	for i := int64(1); i <= 100000; i++ {
+		sent.Data = append(sent.Data, i)
+	}
+
+	for i := 0; i < 10; i++ {
+		pub.Write(sent)
+		require.Equal(t, sent, <-recv)
+	}
+
+	for _, node := range []*Node{n, ns} {
+		for _, cs := range node.Stats() {
+			if cs.Topic == "/myns/test_topic" {
+				require.Equal(t, "SHMROS", cs.Transport)
+				require.Equal(t, uint64(10), cs.Messages)
+			}
+		}
+	}
+}
+
+func TestPublisherTcpCompression(t *testing.T) {
+	m, err := newContainerMaster()
+	require.NoError(t, err)
+	defer m.close()
+
+	n, err := NewNode(NodeConf{
+		Namespace:     "/myns",
+		Name:          "goroslib",
+		MasterAddress: m.IP() + ":11311",
+	})
+	require.NoError(t, err)
+	defer n.Close()
+
+	pub, err := NewPublisher(PublisherConf{
+		Node:           n,
+		Topic:          "test_topic",
+		Msg:            &std_msgs.Int64MultiArray{},
+		TcpCompression: true,
+	})
+	require.NoError(t, err)
+	defer pub.Close()
+
+	ns, err := NewNode(NodeConf{
+		Namespace:           "/myns",
+		Name:                "goroslibsub",
+		MasterAddress:       m.IP() + ":11311",
+		DisableIntraProcess: true,
+	})
+	require.NoError(t, err)
+	defer ns.Close()
+
+	recv := make(chan *std_msgs.Int64MultiArray)
+	sub, err := NewSubscriber(SubscriberConf{
+		Node:  ns,
+		Topic: "test_topic",
+		Callback: func(msg *std_msgs.Int64MultiArray) {
+			recv <- msg
+		},
+	})
+	require.NoError(t, err)
+	defer sub.Close()
+
+	time.Sleep(1 * time.Second)
+
+	sent := &std_msgs.Int64MultiArray{}
+	for i := int64(1); i <= 100000; i++ {
+		sent.Data = append(sent.Data, i)
+	}
+
+	for i := 0; i < 10; i++ {
+		pub.Write(sent)
+		require.Equal(t, sent, <-recv)
+	}
+
+	for _, node := range []*Node{n, ns} {
+		for _, cs := range node.Stats() {
+			if cs.Topic == "/myns/test_topic" {
+				require.Equal(t, "zstd", cs.Compression)
+				require.True(t, cs.Bytes < cs.UncompressedBytes)
+			}
+		}
+	}
+}
+
 func TestPublisherRostopicHz(t *testing.T) {
 	m, err := newContainerMaster()
 	require.NoError(t, err)
@@ -482,3 +813,142 @@ func TestPublisherRostopicHz(t *testing.T) {
 	require.Greater(t, v, 0.195)
 	require.Less(t, v, 0.205)
 }
+
+func TestPublisherOf(t *testing.T) {
+	sent := &TestMessage{
+		A: 1,
+		B: []TestParent{
+			{
+				A: "other test",
+				B: time.Unix(1500, 1345).UTC(),
+			},
+		},
+	}
+
+	m, err := newContainerMaster()
+	require.NoError(t, err)
+	defer m.close()
+
+	n, err := NewNode(NodeConf{
+		Namespace:     "/myns",
+		Name:          "goroslib",
+		MasterAddress: m.IP() + ":11311",
+	})
+	require.NoError(t, err)
+	defer n.Close()
+
+	recv := make(chan *TestMessage)
+	sub, err := NewSubscriberOf(SubscriberConfOf[TestMessage]{
+		Node:  n,
+		Topic: "test_topic",
+		Callback: func(msg *TestMessage) {
+			recv <- msg
+		},
+	})
+	require.NoError(t, err)
+	defer sub.Close()
+
+	pub, err := NewPublisherOf(PublisherConfOf[TestMessage]{
+		Node:  n,
+		Topic: "test_topic",
+	})
+	require.NoError(t, err)
+	defer pub.Close()
+
+	time.Sleep(1 * time.Second)
+
+	pub.Write(sent)
+
+	require.Equal(t, sent, <-recv)
+}
+
+func TestPublisherSameTopic(t *testing.T) {
+	m, err := newContainerMaster()
+	require.NoError(t, err)
+	defer m.close()
+
+	n, err := NewNode(NodeConf{
+		Namespace:     "/myns",
+		Name:          "goroslib",
+		MasterAddress: m.IP() + ":11311",
+	})
+	require.NoError(t, err)
+	defer n.Close()
+
+	pub1, err := NewPublisher(PublisherConf{
+		Node:  n,
+		Topic: "test_topic",
+		Msg:   &std_msgs.Int64{},
+	})
+	require.NoError(t, err)
+	defer pub1.Close()
+
+	pub2, err := NewPublisher(PublisherConf{
+		Node:  n,
+		Topic: "test_topic",
+		Msg:   &std_msgs.Int64{},
+	})
+	require.NoError(t, err)
+	defer pub2.Close()
+
+	_, err = NewPublisher(PublisherConf{
+		Node:  n,
+		Topic: "test_topic",
+		Msg:   &std_msgs.Int64{},
+		Latch: true,
+	})
+	require.EqualError(t, err, "Topic test_topic already published with a different latching setting")
+
+	_, err = NewPublisher(PublisherConf{
+		Node:    n,
+		Topic:   "test_topic",
+		Msg:     &std_msgs.Int64{},
+		UdpRate: 100000,
+	})
+	require.EqualError(t, err, "Topic test_topic already published with a different UDP pacing setting")
+
+	_, err = NewPublisher(PublisherConf{
+		Node:           n,
+		Topic:          "test_topic",
+		Msg:            &std_msgs.Int64{},
+		TcpCompression: true,
+	})
+	require.EqualError(t, err, "Topic test_topic already published with a different compression setting")
+
+	ns, err := NewNode(NodeConf{
+		Namespace:     "/myns",
+		Name:          "goroslib_sub",
+		MasterAddress: m.IP() + ":11311",
+	})
+	require.NoError(t, err)
+	defer ns.Close()
+
+	recv := make(chan *std_msgs.Int64)
+	sub, err := NewSubscriber(SubscriberConf{
+		Node:  ns,
+		Topic: "test_topic",
+		Callback: func(msg *std_msgs.Int64) {
+			recv <- msg
+		},
+	})
+	require.NoError(t, err)
+	defer sub.Close()
+
+	time.Sleep(1 * time.Second)
+
+	pub1.Write(&std_msgs.Int64{Data: 1})
+	require.Equal(t, &std_msgs.Int64{Data: 1}, <-recv)
+
+	pub2.Write(&std_msgs.Int64{Data: 2})
+	require.Equal(t, &std_msgs.Int64{Data: 2}, <-recv)
+
+	// the topic is still published until the last publisher is closed
+	pub1.Close()
+
+	pub2.Write(&std_msgs.Int64{Data: 3})
+	require.Equal(t, &std_msgs.Int64{Data: 3}, <-recv)
+
+	res, err := n.MasterGetTopics()
+	require.NoError(t, err)
+	require.Equal(t, map[string]struct{}{"/myns/goroslib": {}}, res["/myns/test_topic"].Publishers)
+}
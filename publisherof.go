@@ -0,0 +1,86 @@
+package goroslib
+
+import (
+	"time"
+)
+
+// PublisherConfOf is the configuration of a PublisherOf.
+type PublisherConfOf[T any] struct {
+	// parent node.
+	Node *Node
+
+	// name of the topic in which messages will be written
+	Topic string
+
+	// (optional) whether to enable latching, that consists in saving the last
+	// published message and send it to any new subscriber that connects to
+	// this publisher
+	Latch bool
+
+	// (optional) maximum number of bytes per second sent to each UDPROS subscriber.
+	// Messages are split into datagrams that are spaced out in time, in order to avoid
+	// overflowing socket buffers and network queues. Messages that are written faster
+	// than they can be sent are dropped.
+	// It defaults to unlimited.
+	UdpRate int
+
+	// (optional) minimum time between two datagrams sent to each UDPROS subscriber.
+	// It defaults to zero.
+	UdpFrameGap time.Duration
+
+	// (optional) multicast group (i.e. 239.255.0.1:9000) in which messages are sent
+	// to UDP subscribers that enabled multicast. Each message is sent once,
+	// regardless of the number of these subscribers. Each publisher should use a dedicated group.
+	// It defaults to none, that means that messages are sent to each subscriber separately.
+	UdpMulticastGroup string
+
+	// (optional) messages are passed to subscribers of the same process
+	// without being copied. If true, messages must not be modified after being written.
+	// It defaults to false, that means that a copy of each message is passed
+	// to subscribers of the same process.
+	IntraProcessShare bool
+
+	// (optional) whether to compress messages sent to TCPROS subscribers with zstd.
+	// Compression is used only with subscribers that support it (currently goroslib only);
+	// other subscribers receive plain messages.
+	// It defaults to false.
+	TcpCompression bool
+}
+
+// PublisherOf is a type-safe wrapper around Publisher, where T is the type
+// of the messages.
+type PublisherOf[T any] struct {
+	p *Publisher
+}
+
+// NewPublisherOf allocates a PublisherOf. See PublisherConfOf for the options.
+func NewPublisherOf[T any](conf PublisherConfOf[T]) (*PublisherOf[T], error) {
+	p, err := NewPublisher(PublisherConf{
+		Node:              conf.Node,
+		Topic:             conf.Topic,
+		Msg:               new(T),
+		Latch:             conf.Latch,
+		UdpRate:           conf.UdpRate,
+		UdpFrameGap:       conf.UdpFrameGap,
+		UdpMulticastGroup: conf.UdpMulticastGroup,
+		IntraProcessShare: conf.IntraProcessShare,
+		TcpCompression:    conf.TcpCompression,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &PublisherOf[T]{
+		p: p,
+	}, nil
+}
+
+// Close closes a PublisherOf and shuts down all its operations.
+func (p *PublisherOf[T]) Close() error {
+	return p.p.Close()
+}
+
+// Write writes a message into the publisher.
+func (p *PublisherOf[T]) Write(msg *T) {
+	p.p.Write(msg)
+}
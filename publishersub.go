@@ -4,64 +4,111 @@ import (
 	"bytes"
 	"context"
 	"net"
+	"sync/atomic"
+	"time"
 
 	"github.com/aler9/goroslib/pkg/protocommon"
+	"github.com/aler9/goroslib/pkg/protoshm"
 	"github.com/aler9/goroslib/pkg/prototcp"
-	"github.com/aler9/goroslib/pkg/protoudp"
 )
 
 type publisherSubscriber struct {
-	pub       *Publisher
-	callerID  string
-	tcpClient *prototcp.Conn
-	udpAddr   *net.UDPAddr
-
-	ctx          context.Context
-	ctxCancel    func()
-	curMessageID uint8
+	// first field, in order to be 64-bit aligned
+	udpLastPing int64
+
+	pub                *topicPublisher
+	callerID           string
+	tcpClient          *prototcp.Conn
+	shmRing            *protoshm.Ring
+	udpAddr            *net.UDPAddr
+	udpMaxDatagramSize int
+	udpMulticast       bool
+	intraConn          *intraProcessConn
+
+	ctx       context.Context
+	ctxCancel func()
+	udpSender *publisherUDPSender
+	stats     connectionStats
 }
 
 func newPublisherSubscriber(
-	pub *Publisher,
+	pub *topicPublisher,
 	callerID string,
 	tcpClient *prototcp.Conn,
-	udpAddr *net.UDPAddr) {
+	shmRing *protoshm.Ring,
+	udpAddr *net.UDPAddr,
+	udpMaxDatagramSize int,
+	udpMulticast bool,
+	intraConn *intraProcessConn) *publisherSubscriber {
 	ctx, ctxCancel := context.WithCancel(pub.ctx)
 
 	ps := &publisherSubscriber{
-		pub:       pub,
-		callerID:  callerID,
-		tcpClient: tcpClient,
-		udpAddr:   udpAddr,
-		ctx:       ctx,
-		ctxCancel: ctxCancel,
+		pub:                pub,
+		callerID:           callerID,
+		tcpClient:          tcpClient,
+		shmRing:            shmRing,
+		udpAddr:            udpAddr,
+		udpMaxDatagramSize: udpMaxDatagramSize,
+		udpMulticast:       udpMulticast,
+		intraConn:          intraConn,
+		ctx:                ctx,
+		ctxCancel:          ctxCancel,
+		stats: connectionStats{
+			id: pub.conf.Node.newConnectionID(),
+			topic: pub.conf.Node.metrics.topic(
+				pub.conf.Node.absoluteTopicName(pub.conf.Topic), 'o'),
+		},
+	}
+
+	if udpAddr != nil {
+		ps.udpSender = newPublisherUDPSender(pub, udpAddr, udpMaxDatagramSize)
 	}
 
 	pub.subscribers[callerID] = ps
 
 	pub.subscribersWg.Add(1)
 	go ps.run()
+
+	return ps
 }
 
 func (ps *publisherSubscriber) close() {
 	delete(ps.pub.subscribers, ps.callerID)
+	if ps.intraConn != nil {
+		atomic.AddInt32(&ps.pub.intraSubscribers, -1)
+	}
 	ps.ctxCancel()
+	ps.closeSharedMemory()
+}
+
+// closeSharedMemory releases the ring buffer.
+// It must be called by the routine that writes messages.
+func (ps *publisherSubscriber) closeSharedMemory() {
+	if ps.shmRing != nil {
+		ps.shmRing.Remove()
+		ps.shmRing.Close()
+		ps.shmRing = nil
+	}
 }
 
 func (ps *publisherSubscriber) run() {
 	defer ps.pub.subscribersWg.Done()
 
-	if ps.tcpClient != nil {
+	switch {
+	case ps.tcpClient != nil:
 		ps.runTCP()
-	} else {
+
+	case ps.udpAddr != nil:
 		ps.runUDP()
+
+	default:
+		ps.runIntra()
 	}
 }
 
 func (ps *publisherSubscriber) runTCP() {
-	if ps.pub.conf.onSubscriber != nil {
-		ps.pub.conf.onSubscriber()
-	}
+	ps.pub.onSubscriberConnect()
+	defer ps.pub.onSubscriberDisconnect()
 
 	readerDone := make(chan struct{})
 	go func() {
@@ -94,33 +141,147 @@ func (ps *publisherSubscriber) runTCP() {
 }
 
 func (ps *publisherSubscriber) runUDP() {
-	if ps.pub.conf.onSubscriber != nil {
-		ps.pub.conf.onSubscriber()
+	ps.pub.onSubscriberConnect()
+	defer ps.pub.onSubscriberDisconnect()
+
+	senderDone := make(chan struct{})
+	go func() {
+		defer close(senderDone)
+		ps.udpSender.run(ps.ctx)
+	}()
+	defer func() { <-senderDone }()
+
+	pingPeriod := ps.pub.conf.Node.conf.UdprosPingPeriod
+
+	t := time.NewTicker(pingPeriod)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			// the subscriber is considered dead when it sent pings and then stopped.
+			// subscribers that never send pings are kept.
+			last := atomic.LoadInt64(&ps.udpLastPing)
+			if last != 0 && time.Since(time.Unix(0, last)) > 3*pingPeriod {
+				select {
+				case ps.pub.subscriberTCPClose <- ps:
+				case <-ps.pub.ctx.Done():
+				}
+
+				<-ps.ctx.Done()
+				return
+			}
+
+		case <-ps.ctx.Done():
+			return
+		}
 	}
+}
+
+func (ps *publisherSubscriber) onPing() {
+	atomic.StoreInt64(&ps.udpLastPing, time.Now().UnixNano())
+}
+
+func (ps *publisherSubscriber) runIntra() {
+	ps.pub.onSubscriberConnect()
+	defer ps.pub.onSubscriberDisconnect()
 
 	<-ps.ctx.Done()
 }
 
+func (ps *publisherSubscriber) proto() string {
+	switch {
+	case ps.shmRing != nil:
+		return "SHMROS"
+
+	case ps.tcpClient != nil:
+		return "TCPROS"
+
+	case ps.udpAddr != nil:
+		return "UDPROS"
+	}
+	return "INTRAPROCESS"
+}
+
+func (ps *publisherSubscriber) info() InfoConnection {
+	return InfoConnection{
+		ID:        ps.stats.id,
+		To:        ps.callerID,
+		Direction: 'o',
+		Transport: ps.proto(),
+		Topic:     ps.pub.conf.Node.absoluteTopicName(ps.pub.conf.Topic),
+		Connected: true,
+	}
+}
+
 func (ps *publisherSubscriber) writeMessage(msg interface{}) {
-	if ps.tcpClient != nil {
-		ps.tcpClient.WriteMessage(msg)
-	} else {
-		ps.curMessageID++
+	latency := ps.pub.conf.Node.messageLatency(msg)
 
-		var rawMessage bytes.Buffer
-		err := protocommon.MessageEncode(&rawMessage, msg)
+	switch {
+	// messages are written into the ring buffer,
+	// and the subscriber is notified with a byte.
+	case ps.shmRing != nil:
+		var buf bytes.Buffer
+		err := protocommon.MessageEncode(&buf, msg)
 		if err != nil {
+			ps.stats.onDrop()
 			return
 		}
-		byts := rawMessage.Bytes()
 
-		frames := protoudp.FramesForPayload(
-			uint32(ps.pub.id),
-			ps.curMessageID,
-			byts)
+		err = ps.shmRing.Write(buf.Bytes())
+		if err != nil {
+			ps.stats.onDrop()
 
-		for _, f := range frames {
-			ps.pub.conf.Node.udprosServer.WriteFrame(f, ps.udpAddr)
+			// the buffer has been corrupted by the other process
+			if err != protoshm.ErrFull {
+				ps.tcpClient.Close()
+			}
+			return
+		}
+
+		_, err = ps.tcpClient.NetConn().Write([]byte{0})
+		if err != nil {
+			ps.stats.onDrop()
+			return
+		}
+		ps.stats.onMessage(uint64(buf.Len()), latency)
+
+	case ps.tcpClient != nil:
+		written := ps.tcpClient.BytesWritten()
+		uncompressed := ps.tcpClient.UncompressedBytesWritten()
+		err := ps.tcpClient.WriteMessage(msg)
+		if err != nil {
+			ps.stats.onDrop()
+			return
+		}
+		ps.stats.onUncompressedBytes(ps.tcpClient.UncompressedBytesWritten() - uncompressed)
+		ps.stats.onMessage(ps.tcpClient.BytesWritten()-written, latency)
+
+	case ps.intraConn != nil:
+		// do not drop the message if the publisher is being closed
+		select {
+		case ps.intraConn.messages <- msg:
+			ps.stats.onMessage(0, latency)
+			return
+		default:
+		}
+
+		select {
+		case ps.intraConn.messages <- msg:
+			ps.stats.onMessage(0, latency)
+		case <-ps.intraConn.done:
+			ps.stats.onDrop()
+		case <-ps.ctx.Done():
+			ps.stats.onDrop()
+		}
+
+	// messages of multicast subscribers are sent by the topic publisher
+	case !ps.udpMulticast:
+		n, err := ps.udpSender.write(msg)
+		if err != nil {
+			ps.stats.onDrop()
+			return
 		}
+		ps.stats.onMessage(uint64(n), latency)
 	}
 }
@@ -0,0 +1,113 @@
+package goroslib
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/aler9/goroslib/pkg/protocommon"
+	"github.com/aler9/goroslib/pkg/protoudp"
+)
+
+const (
+	// maximum number of messages that wait to be sent by a paced sender.
+	udpPacedQueueSize = 8
+)
+
+var errUDPQueueFull = errors.New("queue is full")
+
+// publisherUDPSender sends messages to a UDPROS subscriber or to a multicast group.
+type publisherUDPSender struct {
+	pub             *topicPublisher
+	addr            *net.UDPAddr
+	maxDatagramSize int
+	pacer           *protoudp.Pacer
+	queue           chan []*protoudp.Frame
+	curMessageID    uint8
+}
+
+func newPublisherUDPSender(pub *topicPublisher, addr *net.UDPAddr, maxDatagramSize int) *publisherUDPSender {
+	s := &publisherUDPSender{
+		pub:             pub,
+		addr:            addr,
+		maxDatagramSize: maxDatagramSize,
+	}
+
+	if pub.conf.UdpRate > 0 || pub.conf.UdpFrameGap > 0 {
+		s.pacer = &protoudp.Pacer{
+			Rate:     pub.conf.UdpRate,
+			Burst:    maxDatagramSize,
+			FrameGap: pub.conf.UdpFrameGap,
+		}
+		s.queue = make(chan []*protoudp.Frame, udpPacedQueueSize)
+	}
+
+	return s
+}
+
+// run sends queued frames, spacing them out in time, until ctx is done.
+// It returns immediately if pacing is disabled.
+func (s *publisherUDPSender) run(ctx context.Context) {
+	if s.queue == nil {
+		return
+	}
+
+	for {
+		select {
+		case frames := <-s.queue:
+			for _, f := range frames {
+				wait := s.pacer.Reserve(f, time.Now())
+				if wait > 0 {
+					t := time.NewTimer(wait)
+					select {
+					case <-t.C:
+					case <-ctx.Done():
+						t.Stop()
+						return
+					}
+				}
+
+				s.pub.conf.Node.udprosServer.WriteFrame(f, s.addr)
+			}
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// write writes a message and returns its size.
+// If pacing is enabled, frames are queued and sent by run.
+func (s *publisherUDPSender) write(msg interface{}) (int, error) {
+	s.curMessageID++
+
+	var rawMessage bytes.Buffer
+	err := protocommon.MessageEncode(&rawMessage, msg)
+	if err != nil {
+		return 0, err
+	}
+	byts := rawMessage.Bytes()
+
+	frames := protoudp.FramesForPayloadWithSize(
+		uint32(s.pub.id),
+		s.curMessageID,
+		byts,
+		s.maxDatagramSize)
+
+	if s.queue != nil {
+		select {
+		case s.queue <- frames:
+		default:
+			return 0, errUDPQueueFull
+		}
+		return len(byts), nil
+	}
+
+	for _, f := range frames {
+		s.pub.conf.Node.udprosServer.WriteFrame(f, s.addr)
+	}
+
+	return len(byts), nil
+}
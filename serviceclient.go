@@ -2,7 +2,6 @@ package goroslib
 
 import (
 	"fmt"
-	"net"
 	"reflect"
 	"time"
 
@@ -79,6 +78,14 @@ func (sc *ServiceClient) Call(req interface{}, res interface{}) error {
 		panic("wrong res")
 	}
 
+	start := time.Now()
+	err := sc.call(req, res)
+	sc.conf.Node.metrics.onServiceCall(sc.conf.Node.absoluteTopicName(sc.conf.Name),
+		"client", start, err)
+	return err
+}
+
+func (sc *ServiceClient) call(req interface{}, res interface{}) error {
 	connCreatedInThisCall := false
 	if sc.conn == nil {
 		err := sc.createConn()
@@ -97,7 +104,9 @@ func (sc *ServiceClient) Call(req interface{}, res interface{}) error {
 		// linked to an invalid provider.
 		// do another try.
 		if !connCreatedInThisCall {
-			return sc.Call(req, res)
+			sc.conf.Node.metrics.inc(metricsServiceReconnects, metricsLabels(
+				"service", sc.conf.Node.absoluteTopicName(sc.conf.Name)))
+			return sc.call(req, res)
 		}
 
 		return err
@@ -117,7 +126,9 @@ func (sc *ServiceClient) Call(req interface{}, res interface{}) error {
 		// linked to an invalid provider.
 		// do another try.
 		if !connCreatedInThisCall {
-			return sc.Call(req, res)
+			sc.conf.Node.metrics.inc(metricsServiceReconnects, metricsLabels(
+				"service", sc.conf.Node.absoluteTopicName(sc.conf.Name)))
+			return sc.call(req, res)
 		}
 
 		return err
@@ -150,14 +161,14 @@ func (sc *ServiceClient) createConn() error {
 		return err
 	}
 
-	conn, err := prototcp.NewClient(address)
+	conn, err := prototcp.NewClientWithTLS(address, sc.conf.Node.tlsClientConf)
 	if err != nil {
 		return err
 	}
 
 	if sc.conf.EnableKeepAlive {
-		conn.NetConn().(*net.TCPConn).SetKeepAlive(true)
-		conn.NetConn().(*net.TCPConn).SetKeepAlivePeriod(60 * time.Second)
+		conn.TCPConn().SetKeepAlive(true)
+		conn.TCPConn().SetKeepAlivePeriod(60 * time.Second)
 	}
 
 	err = conn.WriteHeader(&prototcp.HeaderServiceClient{
@@ -195,6 +206,12 @@ func (sc *ServiceClient) createConn() error {
 			srvMD5, outHeader.Md5sum)
 	}
 
+	err = sc.conf.Node.checkPeerIdentity(conn, outHeader.Callerid)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
 	sc.conn = conn
 	return nil
 }
@@ -188,3 +188,48 @@ func TestServiceClientRequestBeforeProvider(t *testing.T) {
 		})
 	}
 }
+
+func TestServiceClientOf(t *testing.T) {
+	m, err := newContainerMaster()
+	require.NoError(t, err)
+	defer m.close()
+
+	n, err := NewNode(NodeConf{
+		Namespace:     "/myns",
+		Name:          "goroslib",
+		MasterAddress: m.IP() + ":11311",
+	})
+	require.NoError(t, err)
+	defer n.Close()
+
+	sp, err := NewServiceProviderOf(ServiceProviderConfOf[TestServiceReq, TestServiceRes]{
+		Node: n,
+		Name: "test_srv",
+		Srv:  &TestService{},
+		Callback: func(req *TestServiceReq) *TestServiceRes {
+			return &TestServiceRes{C: req.A * 2}
+		},
+	})
+	require.NoError(t, err)
+	defer sp.Close()
+
+	_, err = NewServiceClientOf(ServiceClientConfOf[TestServiceRes, TestServiceRes]{
+		Node: n,
+		Name: "test_srv",
+		Srv:  &TestService{},
+	})
+	require.EqualError(t, err, "request type must be goroslib.TestServiceReq, "+
+		"while is goroslib.TestServiceRes")
+
+	sc, err := NewServiceClientOf(ServiceClientConfOf[TestServiceReq, TestServiceRes]{
+		Node: n,
+		Name: "test_srv",
+		Srv:  &TestService{},
+	})
+	require.NoError(t, err)
+	defer sc.Close()
+
+	res, err := sc.Call(&TestServiceReq{A: 123, B: "456"})
+	require.NoError(t, err)
+	require.Equal(t, &TestServiceRes{C: 246}, res)
+}
@@ -0,0 +1,80 @@
+package goroslib
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/aler9/goroslib/pkg/serviceproc"
+)
+
+// ServiceClientConfOf is the configuration of a ServiceClientOf.
+type ServiceClientConfOf[Req, Res any] struct {
+	// parent node.
+	Node *Node
+
+	// name of the service from which providers will be obtained.
+	Name string
+
+	// an instance of the service type.
+	// Its request and response must be Req and Res.
+	Srv interface{}
+
+	// (optional) enable keep-alive packets, that are
+	// useful when there's a firewall between nodes.
+	EnableKeepAlive bool
+}
+
+// ServiceClientOf is a type-safe wrapper around ServiceClient, where Req
+// and Res are the request and the response of the service.
+type ServiceClientOf[Req, Res any] struct {
+	sc *ServiceClient
+}
+
+// NewServiceClientOf allocates a ServiceClientOf. See ServiceClientConfOf for the options.
+func NewServiceClientOf[Req, Res any](conf ServiceClientConfOf[Req, Res]) (*ServiceClientOf[Req, Res], error) {
+	if conf.Srv != nil {
+		srvReq, srvRes, err := serviceproc.RequestResponse(conf.Srv)
+		if err != nil {
+			return nil, err
+		}
+
+		if reflect.TypeOf(srvReq) != reflect.TypeOf((*Req)(nil)).Elem() {
+			return nil, fmt.Errorf("request type must be %s, while is %s",
+				reflect.TypeOf(srvReq), reflect.TypeOf((*Req)(nil)).Elem())
+		}
+
+		if reflect.TypeOf(srvRes) != reflect.TypeOf((*Res)(nil)).Elem() {
+			return nil, fmt.Errorf("response type must be %s, while is %s",
+				reflect.TypeOf(srvRes), reflect.TypeOf((*Res)(nil)).Elem())
+		}
+	}
+
+	sc, err := NewServiceClient(ServiceClientConf{
+		Node:            conf.Node,
+		Name:            conf.Name,
+		Srv:             conf.Srv,
+		EnableKeepAlive: conf.EnableKeepAlive,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ServiceClientOf[Req, Res]{
+		sc: sc,
+	}, nil
+}
+
+// Close closes a ServiceClientOf and shuts down all its operations.
+func (sc *ServiceClientOf[Req, Res]) Close() error {
+	return sc.sc.Close()
+}
+
+// Call sends a request to a service provider and returns its response.
+func (sc *ServiceClientOf[Req, Res]) Call(req *Req) (*Res, error) {
+	var res Res
+	err := sc.sc.Call(req, &res)
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"reflect"
 	"sync"
+	"time"
 
 	"github.com/aler9/goroslib/pkg/prototcp"
 	"github.com/aler9/goroslib/pkg/serviceproc"
@@ -190,19 +191,25 @@ outer:
 			spc.close()
 
 		case req := <-sp.clientRequest:
+			start := time.Now()
 			res := cbv.Call([]reflect.Value{reflect.ValueOf(req.req)})
 
-			client, ok := sp.clients[req.callerID]
-			if !ok {
-				continue
-			}
+			err := func() error {
+				client, ok := sp.clients[req.callerID]
+				if !ok {
+					return fmt.Errorf("client disconnected")
+				}
 
-			err := client.conn.WriteServiceResState(1)
-			if err != nil {
-				continue
-			}
+				err := client.conn.WriteServiceResState(1)
+				if err != nil {
+					return err
+				}
+
+				return client.conn.WriteMessage(res[0].Interface())
+			}()
 
-			client.conn.WriteMessage(res[0].Interface())
+			sp.conf.Node.metrics.onServiceCall(sp.conf.Node.absoluteTopicName(sp.conf.Name),
+				"provider", start, err)
 
 		case <-sp.ctx.Done():
 			break outer
@@ -0,0 +1,53 @@
+package goroslib
+
+import (
+	"fmt"
+)
+
+// ServiceProviderConfOf is the configuration of a ServiceProviderOf.
+type ServiceProviderConfOf[Req, Res any] struct {
+	// parent node.
+	Node *Node
+
+	// name of the service.
+	Name string
+
+	// an instance of the service type.
+	// Its request and response must be Req and Res.
+	Srv interface{}
+
+	// function that will be called whenever a request arrives.
+	Callback func(*Req) *Res
+}
+
+// ServiceProviderOf is a type-safe wrapper around ServiceProvider, where Req
+// and Res are the request and the response of the service.
+type ServiceProviderOf[Req, Res any] struct {
+	sp *ServiceProvider
+}
+
+// NewServiceProviderOf allocates a ServiceProviderOf. See ServiceProviderConfOf for the options.
+func NewServiceProviderOf[Req, Res any](conf ServiceProviderConfOf[Req, Res]) (*ServiceProviderOf[Req, Res], error) {
+	if conf.Callback == nil {
+		return nil, fmt.Errorf("Callback is empty")
+	}
+
+	sp, err := NewServiceProvider(ServiceProviderConf{
+		Node:     conf.Node,
+		Name:     conf.Name,
+		Srv:      conf.Srv,
+		Callback: conf.Callback,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ServiceProviderOf[Req, Res]{
+		sp: sp,
+	}, nil
+}
+
+// Close closes a ServiceProviderOf and shuts down all its operations.
+func (sp *ServiceProviderOf[Req, Res]) Close() error {
+	return sp.sp.Close()
+}
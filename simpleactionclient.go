@@ -1,9 +1,11 @@
 package goroslib
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"sync"
+	"time"
 )
 
 type simpleActionClientGoalSimpleState int
@@ -42,9 +44,12 @@ type SimpleActionClientConf struct {
 }
 
 type simpleActionClientGoalHandler struct {
-	conf  SimpleActionClientGoalConf
-	state simpleActionClientGoalSimpleState
-	gh    *ActionClientGoalHandler
+	conf      SimpleActionClientGoalConf
+	state     simpleActionClientGoalSimpleState
+	gh        *ActionClientGoalHandler
+	doneState SimpleActionClientGoalState
+	result    interface{}
+	done      chan struct{}
 }
 
 // SimpleActionClient is a ROS simple action client, an entity that can call simple actions.
@@ -141,6 +146,7 @@ func (sac *SimpleActionClient) SendGoal(conf SimpleActionClientGoalConf) error {
 
 	sac.sgh = &simpleActionClientGoalHandler{
 		conf: conf,
+		done: make(chan struct{}),
 	}
 
 	fixedSGH := sac.sgh
@@ -166,6 +172,7 @@ func (sac *SimpleActionClient) SendGoal(conf SimpleActionClientGoalConf) error {
 		).Interface(),
 	})
 	if err != nil {
+		sac.sgh = nil
 		return err
 	}
 
@@ -174,9 +181,125 @@ func (sac *SimpleActionClient) SendGoal(conf SimpleActionClientGoalConf) error {
 	return nil
 }
 
+// SendGoalAndWait sends a goal and waits until it is done.
+// If the goal is not done within execTimeout, it is canceled, and the
+// goal is waited for additional preemptTimeout.
+// A zero timeout means no timeout.
+// It returns the state of the goal.
+func (sac *SimpleActionClient) SendGoalAndWait(goal interface{},
+	execTimeout time.Duration, preemptTimeout time.Duration) (SimpleActionClientGoalState, error) {
+	err := sac.SendGoal(SimpleActionClientGoalConf{
+		Goal: goal,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	err = sac.waitForResultTimeout(execTimeout)
+	if err == context.DeadlineExceeded {
+		sac.CancelGoal()
+
+		err = sac.waitForResultTimeout(preemptTimeout)
+		if err == context.DeadlineExceeded {
+			err = nil
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return sac.GetState(), nil
+}
+
+func (sac *SimpleActionClient) waitForResultTimeout(timeout time.Duration) error {
+	ctx := context.Background()
+	if timeout != 0 {
+		var ctxCancel func()
+		ctx, ctxCancel = context.WithTimeout(ctx, timeout)
+		defer ctxCancel()
+	}
+
+	return sac.WaitForResult(ctx)
+}
+
+// WaitForResult waits until the current goal is done, or until the context
+// is canceled.
+func (sac *SimpleActionClient) WaitForResult(ctx context.Context) error {
+	sac.mutex.Lock()
+	sgh := sac.sgh
+	sac.mutex.Unlock()
+
+	if sgh == nil {
+		return fmt.Errorf("no goal is being tracked")
+	}
+
+	select {
+	case <-sgh.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-sac.ac.ctx.Done():
+		return fmt.Errorf("terminated")
+	}
+}
+
+// GetState returns the state of the current goal.
+// If no goal is being tracked, it returns SimpleActionClientGoalStateLost.
+func (sac *SimpleActionClient) GetState() SimpleActionClientGoalState {
+	sac.mutex.Lock()
+	defer sac.mutex.Unlock()
+
+	if sac.sgh == nil {
+		return SimpleActionClientGoalStateLost
+	}
+
+	switch sac.sgh.state {
+	case simpleActionClientGoalSimpleStatePending:
+		return SimpleActionClientGoalStatePending
+
+	case simpleActionClientGoalSimpleStateActive:
+		return SimpleActionClientGoalStateActive
+	}
+	return sac.sgh.doneState
+}
+
+// GetResult returns the result of the current goal.
+// It returns an error if no goal is being tracked or if the goal is not done.
+func (sac *SimpleActionClient) GetResult() (interface{}, error) {
+	sac.mutex.Lock()
+	defer sac.mutex.Unlock()
+
+	if sac.sgh == nil {
+		return nil, fmt.Errorf("no goal is being tracked")
+	}
+
+	if sac.sgh.state != simpleActionClientGoalSimpleStateDone {
+		return nil, fmt.Errorf("goal is not done")
+	}
+
+	return sac.sgh.result, nil
+}
+
+// StopTrackingGoal stops tracking the current goal, without canceling it.
+// Callbacks of the goal are not called anymore.
+func (sac *SimpleActionClient) StopTrackingGoal() {
+	sac.mutex.Lock()
+	defer sac.mutex.Unlock()
+
+	sac.sgh = nil
+}
+
 // CancelGoal cancels the current goal.
 func (sac *SimpleActionClient) CancelGoal() {
-	sac.sgh.gh.Cancel()
+	sac.mutex.Lock()
+	sgh := sac.sgh
+	sac.mutex.Unlock()
+
+	if sgh == nil {
+		return
+	}
+
+	sgh.gh.Cancel()
 }
 
 // CancelAllGoals cancels all goals running on the server.
@@ -205,9 +328,17 @@ func (sac *SimpleActionClient) onTransition(
 	}
 
 	switchToDone := func() {
+		sgh.doneState = sac.fullState()
 		sgh.state = simpleActionClientGoalSimpleStateDone
+
+		sgh.result = res.Interface()
+		if res.IsNil() {
+			sgh.result = reflect.New(sac.ac.resType).Interface()
+		}
+		close(sgh.done)
+
 		if sgh.conf.OnDone != nil {
-			reflect.ValueOf(sgh.conf.OnDone).Call([]reflect.Value{reflect.ValueOf(sac.fullState()), res})
+			reflect.ValueOf(sgh.conf.OnDone).Call([]reflect.Value{reflect.ValueOf(sgh.doneState), res})
 		}
 	}
 
@@ -1,6 +1,7 @@
 package goroslib
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -236,3 +237,88 @@ func TestSimpleActionClientGoalOverride(t *testing.T) {
 		})
 	}
 }
+
+func TestSimpleActionClientSendGoalAndWait(t *testing.T) {
+	m, err := newContainerMaster()
+	require.NoError(t, err)
+	defer m.close()
+
+	ns, err := NewNode(NodeConf{
+		Namespace:     "/myns",
+		Name:          "goroslib-server",
+		MasterAddress: m.IP() + ":11311",
+	})
+	require.NoError(t, err)
+	defer ns.Close()
+
+	sas, err := NewSimpleActionServer(SimpleActionServerConf{
+		Node:   ns,
+		Name:   "test_action",
+		Action: &DoSomethingAction{},
+		OnExecute: func(sas *SimpleActionServer, goal *DoSomethingActionGoal) {
+			if goal.Input == 2 {
+				for !sas.IsPreemptRequested() {
+					time.Sleep(100 * time.Millisecond)
+				}
+				sas.SetAborted(&DoSomethingActionResult{})
+				return
+			}
+
+			sas.SetSucceeded(&DoSomethingActionResult{Output: 123456})
+		},
+	})
+	require.NoError(t, err)
+	defer sas.Close()
+
+	nc, err := NewNode(NodeConf{
+		Namespace:     "/myns",
+		Name:          "goroslib-client",
+		MasterAddress: m.IP() + ":11311",
+	})
+	require.NoError(t, err)
+	defer nc.Close()
+
+	sac, err := NewSimpleActionClient(SimpleActionClientConf{
+		Node:   nc,
+		Name:   "test_action",
+		Action: &DoSomethingAction{},
+	})
+	require.NoError(t, err)
+	defer sac.Close()
+
+	sac.WaitForServer()
+
+	require.Equal(t, SimpleActionClientGoalStateLost, sac.GetState())
+	_, err = sac.GetResult()
+	require.EqualError(t, err, "no goal is being tracked")
+
+	state, err := sac.SendGoalAndWait(&DoSomethingActionGoal{Input: 1}, 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, SimpleActionClientGoalStateSucceeded, state)
+	require.Equal(t, SimpleActionClientGoalStateSucceeded, sac.GetState())
+
+	res, err := sac.GetResult()
+	require.NoError(t, err)
+	require.Equal(t, &DoSomethingActionResult{Output: 123456}, res)
+
+	// the goal is canceled when the execution timeout expires
+	state, err = sac.SendGoalAndWait(&DoSomethingActionGoal{Input: 2},
+		500*time.Millisecond, 5*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, SimpleActionClientGoalStateAborted, state)
+
+	err = sac.SendGoal(SimpleActionClientGoalConf{
+		Goal: &DoSomethingActionGoal{Input: 1},
+	})
+	require.NoError(t, err)
+
+	ctx, ctxCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer ctxCancel()
+	err = sac.WaitForResult(ctx)
+	require.NoError(t, err)
+
+	sac.StopTrackingGoal()
+	require.Equal(t, SimpleActionClientGoalStateLost, sac.GetState())
+	err = sac.WaitForResult(ctx)
+	require.EqualError(t, err, "no goal is being tracked")
+}
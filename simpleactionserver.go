@@ -9,11 +9,6 @@ import (
 	"github.com/aler9/goroslib/pkg/actionproc"
 )
 
-type goalHandlerPair struct {
-	gh   *ActionServerGoalHandler
-	goal interface{}
-}
-
 // SimpleActionServerConf is the configuration of a SimpleActionServer.
 type SimpleActionServerConf struct {
 	// parent node.
@@ -25,25 +20,46 @@ type SimpleActionServerConf struct {
 	// an instance of the action type.
 	Action interface{}
 
-	// (optional) function in the form func(*ActionGoal) that will be
-	// called when a goal arrives.
+	// (optional) function in the form func(*SimpleActionServer, *ActionGoal) that will be
+	// called when a goal is accepted. The goal is accepted automatically
+	// as soon as the server is not busy.
+	// The function must set the goal as succeeded, aborted or preempted before returning,
+	// otherwise the goal is set as aborted.
 	OnExecute interface{}
+
+	// (optional) function that will be called when a new goal is available.
+	// It can be used to implement a polling-style server, that calls
+	// IsNewGoalAvailable() and AcceptNewGoal() manually.
+	// It can't be used together with OnExecute.
+	OnNewGoal func()
+
+	// (optional) function that will be called when the current goal has been
+	// preempted, by a cancellation request or by a newer goal.
+	OnPreempt func()
+
+	// (optional) function in the form func(*ActionGoal) bool that will be
+	// called when a goal arrives. If it returns false, the goal is rejected.
+	OnAccept interface{}
 }
 
 // SimpleActionServer is a ROS simple action server, an entity that can provide actions.
+// It processes one goal at a time: a newer goal preempts the current one.
 type SimpleActionServer struct {
 	conf SimpleActionServerConf
 
-	ctx         context.Context
-	ctxCancel   func()
-	as          *ActionServer
-	currentGoal *ActionServerGoalHandler
-	preemptFlag bool
-	mutex       sync.Mutex
+	ctx                   context.Context
+	ctxCancel             func()
+	as                    *ActionServer
+	mutex                 sync.Mutex
+	currentGoal           *ActionServerGoalHandler
+	nextGoal              *ActionServerGoalHandler
+	nextGoalValue         interface{}
+	newGoal               bool
+	preemptRequest        bool
+	newGoalPreemptRequest bool
 
 	// in
-	goal   chan goalHandlerPair
-	cancel chan *ActionServerGoalHandler
+	newGoalAvailable chan struct{}
 
 	// out
 	done chan struct{}
@@ -54,12 +70,11 @@ func NewSimpleActionServer(conf SimpleActionServerConf) (*SimpleActionServer, er
 	ctx, ctxCancel := context.WithCancel(context.Background())
 
 	sas := &SimpleActionServer{
-		conf:      conf,
-		ctx:       ctx,
-		ctxCancel: ctxCancel,
-		goal:      make(chan goalHandlerPair),
-		cancel:    make(chan *ActionServerGoalHandler),
-		done:      make(chan struct{}),
+		conf:             conf,
+		ctx:              ctx,
+		ctxCancel:        ctxCancel,
+		newGoalAvailable: make(chan struct{}, 1),
+		done:             make(chan struct{}),
 	}
 
 	goal, _, _, err := actionproc.GoalResultFeedback(conf.Action)
@@ -86,6 +101,27 @@ func NewSimpleActionServer(conf SimpleActionServerConf) (*SimpleActionServer, er
 			return nil, fmt.Errorf("OnExecute 2nd argument must be %s, while is %v",
 				reflect.PtrTo(reflect.TypeOf(goal)), cbt.In(1))
 		}
+
+		if conf.OnNewGoal != nil {
+			return nil, fmt.Errorf("OnExecute and OnNewGoal can't be used together")
+		}
+	}
+
+	if conf.OnAccept != nil {
+		cbt := reflect.TypeOf(conf.OnAccept)
+		if cbt.Kind() != reflect.Func {
+			return nil, fmt.Errorf("OnAccept is not a function")
+		}
+		if cbt.NumIn() != 1 {
+			return nil, fmt.Errorf("OnAccept must accept a single argument")
+		}
+		if cbt.NumOut() != 1 || cbt.Out(0) != reflect.TypeOf(false) {
+			return nil, fmt.Errorf("OnAccept must return a single bool")
+		}
+		if cbt.In(0) != reflect.PtrTo(reflect.TypeOf(goal)) {
+			return nil, fmt.Errorf("OnAccept 1st argument must be %s, while is %v",
+				reflect.PtrTo(reflect.TypeOf(goal)), cbt.In(0))
+		}
 	}
 
 	sas.as, err = NewActionServer(ActionServerConf{
@@ -111,122 +147,217 @@ func NewSimpleActionServer(conf SimpleActionServerConf) (*SimpleActionServer, er
 }
 
 // Close closes a SimpleActionServer.
+// If an OnExecute callback is running, it waits for its completion.
 func (sas *SimpleActionServer) Close() error {
 	sas.ctxCancel()
 	<-sas.done
 	return nil
 }
 
-// IsPreemptRequested checks whether the goal has been canceled.
+// IsNewGoalAvailable checks whether a new goal is available and can be
+// accepted with AcceptNewGoal().
+func (sas *SimpleActionServer) IsNewGoalAvailable() bool {
+	sas.mutex.Lock()
+	defer sas.mutex.Unlock()
+	return sas.newGoal
+}
+
+// AcceptNewGoal accepts the new goal and returns it.
+// If there's a current goal that is still active, it is set as preempted.
+func (sas *SimpleActionServer) AcceptNewGoal() (interface{}, error) {
+	sas.mutex.Lock()
+	defer sas.mutex.Unlock()
+
+	if !sas.newGoal {
+		return nil, fmt.Errorf("no new goal is available")
+	}
+
+	if sas.isActive() {
+		sas.currentGoal.SetCanceled(sas.as.emptyResult())
+	}
+
+	sas.currentGoal = sas.nextGoal
+	sas.newGoal = false
+	sas.preemptRequest = sas.newGoalPreemptRequest
+	sas.newGoalPreemptRequest = false
+
+	sas.currentGoal.SetAccepted()
+
+	return sas.nextGoalValue, nil
+}
+
+// IsActive checks whether the current goal is being processed.
+func (sas *SimpleActionServer) IsActive() bool {
+	sas.mutex.Lock()
+	defer sas.mutex.Unlock()
+	return sas.isActive()
+}
+
+func (sas *SimpleActionServer) isActive() bool {
+	if sas.currentGoal == nil {
+		return false
+	}
+
+	switch sas.currentGoal.State() {
+	case ActionServerGoalStateActive,
+		ActionServerGoalStatePreempting:
+		return true
+	}
+	return false
+}
+
+// IsPreemptRequested checks whether the current goal has been preempted.
 func (sas *SimpleActionServer) IsPreemptRequested() bool {
 	sas.mutex.Lock()
 	defer sas.mutex.Unlock()
-	return sas.preemptFlag
+	return sas.preemptRequest
+}
+
+func (sas *SimpleActionServer) current() *ActionServerGoalHandler {
+	sas.mutex.Lock()
+	defer sas.mutex.Unlock()
+	return sas.currentGoal
 }
 
 // PublishFeedback publishes a feedback about the current goal.
-// This can be called only from an OnExecute callback.
 func (sas *SimpleActionServer) PublishFeedback(fb interface{}) {
-	sas.currentGoal.PublishFeedback(fb)
+	if gh := sas.current(); gh != nil {
+		gh.PublishFeedback(fb)
+	}
 }
 
 // SetAborted sets the current goal as aborted.
-// This can be called only from an OnExecute callback.
 func (sas *SimpleActionServer) SetAborted(res interface{}) {
-	sas.currentGoal.SetAborted(res)
+	if gh := sas.current(); gh != nil {
+		gh.SetAborted(res)
+	}
 }
 
 // SetSucceeded sets the current goal as succeeded.
-// This can be called only from an OnExecute callback.
 func (sas *SimpleActionServer) SetSucceeded(res interface{}) {
-	sas.currentGoal.SetSucceeded(res)
+	if gh := sas.current(); gh != nil {
+		gh.SetSucceeded(res)
+	}
+}
+
+// SetPreempted sets the current goal as preempted.
+func (sas *SimpleActionServer) SetPreempted(res interface{}) {
+	if gh := sas.current(); gh != nil {
+		gh.SetCanceled(res)
+	}
 }
 
 func (sas *SimpleActionServer) onGoal(in []reflect.Value) []reflect.Value {
 	gh := in[0].Interface().(*ActionServerGoalHandler)
 	goal := in[1].Interface()
 
-	select {
-	case sas.goal <- goalHandlerPair{gh, goal}:
-	case <-sas.ctx.Done():
+	if sas.conf.OnAccept != nil {
+		out := reflect.ValueOf(sas.conf.OnAccept).Call([]reflect.Value{in[1]})
+		if !out[0].Bool() {
+			gh.SetRejected(sas.as.emptyResult())
+			return []reflect.Value{}
+		}
 	}
 
-	return []reflect.Value{}
-}
+	accepted, preempted := func() (bool, bool) {
+		sas.mutex.Lock()
+		defer sas.mutex.Unlock()
 
-func (sas *SimpleActionServer) onCancel(gh *ActionServerGoalHandler) {
-	select {
-	case sas.cancel <- gh:
-	case <-sas.ctx.Done():
+		// goals older than the current or the next one are canceled
+		if (sas.currentGoal != nil && gh.stamp.Before(sas.currentGoal.stamp)) ||
+			(sas.nextGoal != nil && gh.stamp.Before(sas.nextGoal.stamp)) {
+			return false, false
+		}
+
+		// the next goal has not been accepted yet and is replaced
+		if sas.nextGoal != nil && sas.nextGoal != sas.currentGoal {
+			sas.nextGoal.SetCanceled(sas.as.emptyResult())
+		}
+
+		sas.nextGoal = gh
+		sas.nextGoalValue = goal
+		sas.newGoal = true
+		sas.newGoalPreemptRequest = false
+
+		if sas.isActive() {
+			sas.preemptRequest = true
+			return true, true
+		}
+		return true, false
+	}()
+
+	if !accepted {
+		gh.SetCanceled(sas.as.emptyResult())
+		return []reflect.Value{}
 	}
-}
 
-func (sas *SimpleActionServer) run() {
-	defer close(sas.done)
+	if preempted && sas.conf.OnPreempt != nil {
+		sas.conf.OnPreempt()
+	}
+
+	if sas.conf.OnNewGoal != nil {
+		sas.conf.OnNewGoal()
+	}
+
+	select {
+	case sas.newGoalAvailable <- struct{}{}:
+	default:
+	}
 
-	executeRunning := false
-	var executeDone chan struct{}
+	return []reflect.Value{}
+}
 
-	executeStart := func(goal interface{}, gh *ActionServerGoalHandler, preemptFlag bool) {
-		sas.currentGoal = gh
-		sas.preemptFlag = preemptFlag
+func (sas *SimpleActionServer) onCancel(gh *ActionServerGoalHandler) {
+	preempted := func() bool {
+		sas.mutex.Lock()
+		defer sas.mutex.Unlock()
 
-		executeRunning = true
-		executeDone = make(chan struct{})
+		switch gh {
+		case sas.currentGoal:
+			sas.preemptRequest = true
+			return true
 
-		go func() {
-			defer close(executeDone)
+		case sas.nextGoal:
+			sas.newGoalPreemptRequest = true
+		}
+		return false
+	}()
 
-			if sas.conf.OnExecute != nil {
-				reflect.ValueOf(sas.conf.OnExecute).Call([]reflect.Value{
-					reflect.ValueOf(sas),
-					reflect.ValueOf(goal),
-				})
-			}
-		}()
+	if preempted && sas.conf.OnPreempt != nil {
+		sas.conf.OnPreempt()
 	}
+}
 
-	var lastGoal *ActionServerGoalHandler
-	var nextGoal *goalHandlerPair
-	nextGoalPreemptFlag := false
+func (sas *SimpleActionServer) run() {
+	defer close(sas.done)
 
 outer:
 	for {
 		select {
-		case pair := <-sas.goal:
-			if lastGoal != nil {
-				lastGoal.SetCanceled(reflect.New(sas.as.resType).Interface())
+		case <-sas.newGoalAvailable:
+			if sas.conf.OnExecute == nil {
+				continue
 			}
 
-			lastGoal = pair.gh
-
-			pair.gh.SetAccepted()
-
-			if !executeRunning {
-				executeStart(pair.goal, pair.gh, false)
-			} else {
-				nextGoal = &pair
-				nextGoalPreemptFlag = false
-			}
+			for sas.IsNewGoalAvailable() {
+				goal, err := sas.AcceptNewGoal()
+				if err != nil {
+					break
+				}
 
-		case gh := <-sas.cancel:
-			switch gh {
-			case sas.currentGoal:
-				sas.mutex.Lock()
-				sas.preemptFlag = true
-				sas.mutex.Unlock()
+				reflect.ValueOf(sas.conf.OnExecute).Call([]reflect.Value{
+					reflect.ValueOf(sas),
+					reflect.ValueOf(goal),
+				})
 
-			case nextGoal.gh:
-				nextGoalPreemptFlag = true
-			}
+				// the callback did not set a terminal state
+				if sas.IsActive() {
+					sas.SetAborted(sas.as.emptyResult())
+				}
 
-		case <-executeDone:
-			if nextGoal != nil {
-				executeStart(nextGoal.goal, nextGoal.gh, nextGoalPreemptFlag)
-				nextGoal = nil
-				nextGoalPreemptFlag = false
-			} else {
-				lastGoal = nil
+				if sas.ctx.Err() != nil {
+					break outer
+				}
 			}
 
 		case <-sas.ctx.Done():
@@ -237,8 +368,4 @@ outer:
 	sas.ctxCancel()
 
 	sas.as.Close()
-
-	if executeRunning {
-		<-executeDone
-	}
 }
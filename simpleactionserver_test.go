@@ -1,6 +1,7 @@
 package goroslib
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -95,3 +96,105 @@ func TestSimpleActionServer(t *testing.T) {
 		})
 	}
 }
+
+func TestSimpleActionServerPolling(t *testing.T) {
+	m, err := newContainerMaster()
+	require.NoError(t, err)
+	defer m.close()
+
+	ns, err := NewNode(NodeConf{
+		Namespace:     "/myns",
+		Name:          "goroslib-server",
+		MasterAddress: m.IP() + ":11311",
+	})
+	require.NoError(t, err)
+	defer ns.Close()
+
+	newGoal := make(chan struct{}, 10)
+	preempt := make(chan struct{}, 10)
+
+	sas, err := NewSimpleActionServer(SimpleActionServerConf{
+		Node:   ns,
+		Name:   "test_action",
+		Action: &DoSomethingAction{},
+		OnNewGoal: func() {
+			newGoal <- struct{}{}
+		},
+		OnPreempt: func() {
+			preempt <- struct{}{}
+		},
+		OnAccept: func(goal *DoSomethingActionGoal) bool {
+			return goal.Input != 0
+		},
+	})
+	require.NoError(t, err)
+	defer sas.Close()
+
+	nc, err := NewNode(NodeConf{
+		Namespace:     "/myns",
+		Name:          "goroslib-client",
+		MasterAddress: m.IP() + ":11311",
+	})
+	require.NoError(t, err)
+	defer nc.Close()
+
+	sac, err := NewSimpleActionClient(SimpleActionClientConf{
+		Node:   nc,
+		Name:   "test_action",
+		Action: &DoSomethingAction{},
+	})
+	require.NoError(t, err)
+	defer sac.Close()
+
+	sac.WaitForServer()
+
+	// rejected goal
+	state, err := sac.SendGoalAndWait(&DoSomethingActionGoal{Input: 0}, 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, SimpleActionClientGoalStateRejected, state)
+	require.Equal(t, false, sas.IsNewGoalAvailable())
+
+	// preempted goal
+	err = sac.SendGoal(SimpleActionClientGoalConf{
+		Goal: &DoSomethingActionGoal{Input: 1},
+	})
+	require.NoError(t, err)
+
+	<-newGoal
+	require.Equal(t, true, sas.IsNewGoalAvailable())
+	goal, err := sas.AcceptNewGoal()
+	require.NoError(t, err)
+	require.Equal(t, &DoSomethingActionGoal{Input: 1}, goal)
+	require.Equal(t, true, sas.IsActive())
+	require.Equal(t, false, sas.IsPreemptRequested())
+
+	_, err = sas.AcceptNewGoal()
+	require.EqualError(t, err, "no new goal is available")
+
+	sac.CancelGoal()
+	<-preempt
+	require.Equal(t, true, sas.IsPreemptRequested())
+	sas.SetPreempted(&DoSomethingActionResult{})
+	require.Equal(t, false, sas.IsActive())
+
+	ctx, ctxCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer ctxCancel()
+	err = sac.WaitForResult(ctx)
+	require.NoError(t, err)
+	require.Equal(t, SimpleActionClientGoalStatePreempted, sac.GetState())
+
+	// succeeded goal
+	err = sac.SendGoal(SimpleActionClientGoalConf{
+		Goal: &DoSomethingActionGoal{Input: 2},
+	})
+	require.NoError(t, err)
+
+	<-newGoal
+	_, err = sas.AcceptNewGoal()
+	require.NoError(t, err)
+	sas.SetSucceeded(&DoSomethingActionResult{Output: 123456})
+
+	err = sac.WaitForResult(ctx)
+	require.NoError(t, err)
+	require.Equal(t, SimpleActionClientGoalStateSucceeded, sac.GetState())
+}
@@ -3,9 +3,7 @@ package goroslib
 import (
 	"context"
 	"fmt"
-	"net/url"
 	"reflect"
-	"sync"
 
 	"github.com/aler9/goroslib/pkg/msgproc"
 )
@@ -21,6 +19,73 @@ const (
 	UDP
 )
 
+// TransportHints contains preferences about the transport used to receive
+// messages from publishers, like roscpp's TransportHints.
+type TransportHints struct {
+	// protocols that are requested to publishers, in order of preference.
+	// Publishers use the first protocol they support. If a publisher rejects
+	// the request, it is repeated without the first protocol, until no protocols are left.
+	Protocols []Protocol
+
+	// (optional) if protocol is TCP, disables the TCP_NODELAY flag, which
+	// is enabled by default.
+	// It defaults to false.
+	DisableNoDelay bool
+
+	// (optional) if protocol is UDP, maximum size of datagrams sent by publishers.
+	// It can't be greater than NodeConf.UdprosMaxDatagramSize.
+	// It defaults to NodeConf.UdprosMaxDatagramSize.
+	MaxDatagramSize int
+}
+
+func (h *TransportHints) validate(n *Node) error {
+	if len(h.Protocols) == 0 {
+		return fmt.Errorf("Protocols is empty")
+	}
+
+	for _, proto := range h.Protocols {
+		switch proto {
+		case TCP:
+		case UDP:
+			if n.conf.TLS != nil {
+				return fmt.Errorf("UDP can't be used since TLS is enabled")
+			}
+		default:
+			return fmt.Errorf("invalid protocol: %d", proto)
+		}
+	}
+
+	if h.MaxDatagramSize != 0 &&
+		(h.MaxDatagramSize < udprosMinDatagramSize || h.MaxDatagramSize > n.conf.UdprosMaxDatagramSize) {
+		return fmt.Errorf("MaxDatagramSize must be between %d and %d",
+			udprosMinDatagramSize, n.conf.UdprosMaxDatagramSize)
+	}
+
+	return nil
+}
+
+// hostTransportHints returns the transport hints used with publishers of the given host.
+func (conf *SubscriberConf) hostTransportHints(host string) TransportHints {
+	var h TransportHints
+
+	if v, ok := conf.HostTransportHints[host]; ok {
+		h = *v
+	} else if conf.TransportHints != nil {
+		h = *conf.TransportHints
+	} else {
+		h = TransportHints{
+			Protocols:      []Protocol{conf.Protocol},
+			DisableNoDelay: conf.DisableNoDelay,
+		}
+	}
+
+	if h.MaxDatagramSize == 0 {
+		h.MaxDatagramSize = conf.Node.conf.UdprosMaxDatagramSize
+	}
+
+	return h
+}
+
 // SubscriberConf is the configuration of a Subscriber.
 type SubscriberConf struct {
 	// parent node.
@@ -35,41 +100,77 @@ type SubscriberConf struct {
 
 	// (optional) protocol that will be used to receive messages
 	// it defaults to TCP.
+	// Subscribers of the same topic must use the same protocol.
 	Protocol Protocol
 
+	// (optional) preferences about the transport, that replace Protocol
+	// and DisableNoDelay when set.
+	// Subscribers of the same topic must use the same transport hints.
+	TransportHints *TransportHints
+
+	// (optional) preferences about the transport used with publishers of
+	// specific hosts, that replace TransportHints. Keys are hosts,
+	// as they appear in the URLs of publishers.
+	// Subscribers of the same topic must use the same transport hints.
+	HostTransportHints map[string]*TransportHints
+
 	// (optional) queue size. If the Callback is too slow, the queue fills up,
 	// and newer messages are discarded.
 	// It defaults to zero (wait the Callback synchronously).
 	QueueSize uint
 
-	// (optional) enable keep-alive packets, that are
+	// (optional) if protocol is TCP, enable keep-alive packets, that are
 	// useful when there's a firewall between nodes.
+	// If protocol is UDP, pings are always sent (see NodeConf.UdprosPingPeriod).
+	// Subscribers of the same topic must use the same setting.
 	EnableKeepAlive bool
 
+	// (optional) if protocol is UDP, asks publishers to send messages through
+	// multicast (see PublisherConf.UdpMulticastGroup). Publishers that do not
+	// support multicast send messages through UDPROS.
+	// It defaults to false.
+	// Subscribers of the same topic must use the same setting.
+	UdpMulticast bool
+
 	// (optional) if protocol is TCP, disables the TCP_NODELAY flag, which
 	// is enabled by default.
 	// It defaults to false.
+	// Subscribers of the same topic must use the same setting.
 	DisableNoDelay bool
 
+	// (optional) messages coming from publishers of the same process are
+	// passed to the Callback without being serialized, as deep copies.
+	// If this is true, they are not copied and are shared with the publisher
+	// and with other subscribers, therefore they must not be modified.
+	// It defaults to false.
+	IntraProcessShare bool
+
 	onPublisher func()
+
+	// called instead of Callback, with the size of the serialized message.
+	onMessage func(msg interface{}, bytes uint64)
+}
+
+type subscriberMessage struct {
+	msg   interface{}
+	bytes uint64
 }
 
 // Subscriber is a ROS subscriber, an entity that can receive messages from a named channel.
+// Subscribers of the same node that read from the same topic share a single
+// registration with the master and a single set of connections; incoming messages
+// are passed to every Subscriber and must not be modified by callbacks.
 type Subscriber struct {
 	conf SubscriberConf
 
-	ctx          context.Context
-	ctxCancel    func()
-	msgMsg       reflect.Type
-	msgType      string
-	msgMd5       string
-	publishers   map[string]*subscriberPublisher
-	publishersWg sync.WaitGroup
+	ctx       context.Context
+	ctxCancel func()
+	msgMsg    reflect.Type
+	msgType   string
+	msgMd5    string
 
 	// in
-	getBusInfo          chan getBusInfoSubReq
-	subscriberPubUpdate chan []string
-	message             chan interface{}
+	message chan subscriberMessage
 
 	// out
 	done chan struct{}
@@ -85,6 +186,28 @@ func NewSubscriber(conf SubscriberConf) (*Subscriber, error) {
 		return nil, fmt.Errorf("Topic is empty")
 	}
 
+	if conf.Protocol == UDP && conf.Node.conf.TLS != nil {
+		return nil, fmt.Errorf("UDP can't be used since TLS is enabled")
+	}
+
+	if conf.TransportHints != nil {
+		err := conf.TransportHints.validate(conf.Node)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TransportHints: %s", err)
+		}
+	}
+
+	for host, hints := range conf.HostTransportHints {
+		if hints == nil {
+			return nil, fmt.Errorf("invalid HostTransportHints of '%s': hints are nil", host)
+		}
+
+		err := hints.validate(conf.Node)
+		if err != nil {
+			return nil, fmt.Errorf("invalid HostTransportHints of '%s': %s", host, err)
+		}
+	}
+
 	cbt := reflect.TypeOf(conf.Callback)
 	if cbt.Kind() != reflect.Func {
 		return nil, fmt.Errorf("Callback is not a function")
@@ -117,17 +240,14 @@ func NewSubscriber(conf SubscriberConf) (*Subscriber, error) {
 	ctx, ctxCancel := context.WithCancel(conf.Node.ctx)
 
 	s := &Subscriber{
-		conf:                conf,
-		ctx:                 ctx,
-		ctxCancel:           ctxCancel,
-		msgMsg:              msgMsg.Elem(),
-		msgType:             msgType,
-		msgMd5:              msgMd5,
-		publishers:          make(map[string]*subscriberPublisher),
-		getBusInfo:          make(chan getBusInfoSubReq),
-		subscriberPubUpdate: make(chan []string),
-		message:             make(chan interface{}, conf.QueueSize),
-		done:                make(chan struct{}),
+		conf:      conf,
+		ctx:       ctx,
+		ctxCancel: ctxCancel,
+		msgMsg:    msgMsg.Elem(),
+		msgType:   msgType,
+		msgMd5:    msgMd5,
+		message:   make(chan subscriberMessage, conf.QueueSize),
+		done:      make(chan struct{}),
 	}
 
 	cerr := make(chan error)
@@ -138,10 +258,12 @@ func NewSubscriber(conf SubscriberConf) (*Subscriber, error) {
 	}:
 		err = <-cerr
 		if err != nil {
+			ctxCancel()
 			return nil, err
 		}
 
 	case <-conf.Node.ctx.Done():
+		ctxCancel()
 		return nil, fmt.Errorf("terminated")
 	}
 
@@ -151,83 +273,52 @@ func NewSubscriber(conf SubscriberConf) (*Subscriber, error) {
 }
 
 // Close closes a Subscriber and shuts down all its operations.
+// The topic is unregistered when the last Subscriber of the topic is closed.
 func (s *Subscriber) Close() error {
 	s.ctxCancel()
 	<-s.done
 	return nil
 }
 
-func (s *Subscriber) run() {
-	defer close(s.done)
+// push queues a message, and returns false if the message has been discarded
+// since the queue is full.
+// bytes is the size of the serialized message, or zero if the message was not serialized.
+func (s *Subscriber) push(msg interface{}, bytes uint64, intra bool) bool {
+	if intra && !s.conf.IntraProcessShare {
+		msg = copyMessage(msg)
+	}
 
-	dispatcherDone := make(chan struct{})
-	go func() {
-		defer close(dispatcherDone)
+	m := subscriberMessage{msg: msg, bytes: bytes}
 
-		cbv := reflect.ValueOf(s.conf.Callback)
+	if s.conf.QueueSize == 0 {
+		select {
+		case s.message <- m:
+		case <-s.ctx.Done():
+		}
+		return true
+	}
 
-		for {
-			select {
-			case msg := <-s.message:
-				cbv.Call([]reflect.Value{reflect.ValueOf(msg)})
+	select {
+	case s.message <- m:
+		return true
+	default:
+		return false
+	}
+}
 
-			case <-s.ctx.Done():
-				return
-			}
-		}
-	}()
+func (s *Subscriber) run() {
+	defer close(s.done)
+
+	cbv := reflect.ValueOf(s.conf.Callback)
 
 outer:
 	for {
 		select {
-		case req := <-s.getBusInfo:
-			proto := func() string {
-				if s.conf.Protocol == UDP {
-					return "UDPROS"
-				}
-				return "TCPROS"
-			}()
-
-			for _, ps := range s.publishers {
-				ur := (&url.URL{
-					Scheme: "http",
-					Host:   ps.address,
-					Path:   "/",
-				}).String()
-				*req.pbusInfo = append(*req.pbusInfo,
-					[]interface{}{
-						0, ur, "i", proto,
-						s.conf.Node.absoluteTopicName(s.conf.Topic), true,
-					})
-			}
-			close(req.done)
-
-		case urls := <-s.subscriberPubUpdate:
-			var addresses []string
-			for _, u := range urls {
-				addr, err := urlToAddress(u)
-				if err != nil {
-					continue
-				}
-				addresses = append(addresses, addr)
-			}
-
-			validPublishers := make(map[string]struct{})
-
-			// add new publishers
-			for _, addr := range addresses {
-				validPublishers[addr] = struct{}{}
-
-				if _, ok := s.publishers[addr]; !ok {
-					newSubscriberPublisher(s, addr)
-				}
-			}
-
-			// remove outdated publishers
-			for addr, pub := range s.publishers {
-				if _, ok := validPublishers[addr]; !ok {
-					pub.close()
-				}
+		case m := <-s.message:
+			if s.conf.onMessage != nil {
+				s.conf.onMessage(m.msg, m.bytes)
+			} else {
+				cbv.Call([]reflect.Value{reflect.ValueOf(m.msg)})
 			}
 
 		case <-s.ctx.Done():
@@ -235,18 +326,17 @@ outer:
 		}
 	}
 
-	s.ctxCancel()
-
-	s.conf.Node.apiMasterClient.UnregisterSubscriber(
-		s.conf.Node.absoluteTopicName(s.conf.Topic),
-		s.conf.Node.apiSlaveServerURL)
-
-	s.publishersWg.Wait()
-
-	<-dispatcherDone
-
+	res := make(chan *topicSubscriber)
 	select {
-	case s.conf.Node.subscriberClose <- s:
+	case s.conf.Node.subscriberClose <- subscriberCloseReq{
+		sub: s,
+		res: res,
+	}:
+		// wait until the topic has been closed
+		if ts := <-res; ts != nil {
+			<-ts.done
+		}
+
 	case <-s.conf.Node.ctx.Done():
 	}
 }
@@ -457,3 +457,271 @@ func TestSubscriberQueue(t *testing.T) {
 	case <-time.After(1 * time.Second):
 	}
 }
+
+func TestSubscriberSameTopic(t *testing.T) {
+	m, err := newContainerMaster()
+	require.NoError(t, err)
+	defer m.close()
+
+	p, err := NewNode(NodeConf{
+		Namespace:     "/myns",
+		Name:          "goroslib_pub",
+		MasterAddress: m.IP() + ":11311",
+	})
+	require.NoError(t, err)
+	defer p.Close()
+
+	pub, err := NewPublisher(PublisherConf{
+		Node:  p,
+		Topic: "test_topic",
+		Msg:   &std_msgs.Int64{},
+	})
+	require.NoError(t, err)
+	defer pub.Close()
+
+	n, err := NewNode(NodeConf{
+		Namespace:     "/myns",
+		Name:          "goroslib",
+		MasterAddress: m.IP() + ":11311",
+	})
+	require.NoError(t, err)
+	defer n.Close()
+
+	recv1 := make(chan *std_msgs.Int64)
+	sub1, err := NewSubscriber(SubscriberConf{
+		Node:  n,
+		Topic: "test_topic",
+		Callback: func(msg *std_msgs.Int64) {
+			recv1 <- msg
+		},
+	})
+	require.NoError(t, err)
+	defer sub1.Close()
+
+	recv2 := make(chan *std_msgs.Int64)
+	sub2, err := NewSubscriber(SubscriberConf{
+		Node:  n,
+		Topic: "test_topic",
+		Callback: func(msg *std_msgs.Int64) {
+			recv2 <- msg
+		},
+	})
+	require.NoError(t, err)
+	defer sub2.Close()
+
+	_, err = NewSubscriber(SubscriberConf{
+		Node:     n,
+		Topic:    "test_topic",
+		Callback: func(msg *std_msgs.Int32) {},
+	})
+	require.EqualError(t, err, "Topic test_topic already subscribed with a different message type")
+
+	_, err = NewSubscriber(SubscriberConf{
+		Node:  n,
+		Topic: "test_topic",
+		TransportHints: &TransportHints{
+			Protocols: []Protocol{UDP, TCP},
+		},
+		Callback: func(msg *std_msgs.Int64) {},
+	})
+	require.EqualError(t, err, "Topic test_topic already subscribed with different transport hints")
+
+	_, err = NewSubscriber(SubscriberConf{
+		Node:            n,
+		Topic:           "test_topic",
+		EnableKeepAlive: true,
+		Callback:        func(msg *std_msgs.Int64) {},
+	})
+	require.EqualError(t, err, "Topic test_topic already subscribed with a different keep-alive setting")
+
+	_, err = NewSubscriber(SubscriberConf{
+		Node:           n,
+		Topic:          "test_topic",
+		DisableNoDelay: true,
+		Callback:       func(msg *std_msgs.Int64) {},
+	})
+	require.EqualError(t, err, "Topic test_topic already subscribed with a different TCP_NODELAY setting")
+
+	time.Sleep(1 * time.Second)
+
+	pub.Write(&std_msgs.Int64{Data: 1})
+
+	require.Equal(t, &std_msgs.Int64{Data: 1}, <-recv1)
+	require.Equal(t, &std_msgs.Int64{Data: 1}, <-recv2)
+
+	// the remaining subscriber keeps receiving messages
+	sub1.Close()
+
+	pub.Write(&std_msgs.Int64{Data: 2})
+
+	require.Equal(t, &std_msgs.Int64{Data: 2}, <-recv2)
+}
+
+func TestSubscriberTransportHints(t *testing.T) {
+	for _, ca := range []string{
+		"fallback",
+		"host",
+	} {
+		t.Run(ca, func(t *testing.T) {
+			m, err := newContainerMaster()
+			require.NoError(t, err)
+			defer m.close()
+
+			n, err := NewNode(NodeConf{
+				Namespace:           "/myns",
+				Name:                "goroslib_sub",
+				MasterAddress:       m.IP() + ":11311",
+				DisableIntraProcess: true,
+			})
+			require.NoError(t, err)
+			defer n.Close()
+
+			conf := SubscriberConf{
+				Node:  n,
+				Topic: "test_topic",
+			}
+
+			if ca == "fallback" {
+				// rostopic supports TCPROS only
+				p, err := newContainer("rostopic-pub", m.IP())
+				require.NoError(t, err)
+				defer p.close()
+
+				conf.TransportHints = &TransportHints{
+					Protocols: []Protocol{UDP, TCP},
+				}
+			} else {
+				p, err := NewNode(NodeConf{
+					Namespace:     "/myns",
+					Name:          "goroslib_pub",
+					MasterAddress: m.IP() + ":11311",
+					Host:          "127.0.0.1",
+				})
+				require.NoError(t, err)
+				defer p.Close()
+
+				pub, err := NewPublisher(PublisherConf{
+					Node:  p,
+					Topic: "test_topic",
+					Msg:   &sensor_msgs.Imu{},
+					Latch: true,
+				})
+				require.NoError(t, err)
+				defer pub.Close()
+
+				pub.Write(&sensor_msgs.Imu{})
+
+				conf.Protocol = UDP
+				conf.HostTransportHints = map[string]*TransportHints{
+					"127.0.0.1": {
+						Protocols:      []Protocol{TCP},
+						DisableNoDelay: true,
+					},
+				}
+			}
+
+			recv := make(chan *sensor_msgs.Imu, 10)
+			conf.Callback = func(msg *sensor_msgs.Imu) {
+				recv <- msg
+			}
+
+			sub, err := NewSubscriber(conf)
+			require.NoError(t, err)
+			defer sub.Close()
+
+			<-recv
+
+			for _, cs := range n.Stats() {
+				if cs.Topic == "/myns/test_topic" {
+					require.Equal(t, "TCPROS", cs.Transport)
+				}
+			}
+		})
+	}
+}
+
+func TestSubscriberIntraProcess(t *testing.T) {
+	m, err := newContainerMaster()
+	require.NoError(t, err)
+	defer m.close()
+
+	for _, ca := range []string{
+		"copy",
+		"share",
+		"disabled",
+	} {
+		t.Run(ca, func(t *testing.T) {
+			p, err := NewNode(NodeConf{
+				Namespace:     "/myns",
+				Name:          "goroslib_pub",
+				MasterAddress: m.IP() + ":11311",
+			})
+			require.NoError(t, err)
+			defer p.Close()
+
+			pub, err := NewPublisher(PublisherConf{
+				Node:              p,
+				Topic:             "test_topic",
+				Msg:               &TestMessage{},
+				IntraProcessShare: (ca == "share"),
+			})
+			require.NoError(t, err)
+			defer pub.Close()
+
+			n, err := NewNode(NodeConf{
+				Namespace:           "/myns",
+				Name:                "goroslib",
+				MasterAddress:       m.IP() + ":11311",
+				DisableIntraProcess: (ca == "disabled"),
+			})
+			require.NoError(t, err)
+			defer n.Close()
+
+			recv := make(chan *TestMessage)
+			sub, err := NewSubscriber(SubscriberConf{
+				Node:  n,
+				Topic: "test_topic",
+				Callback: func(msg *TestMessage) {
+					recv <- msg
+				},
+				IntraProcessShare: (ca == "share"),
+			})
+			require.NoError(t, err)
+			defer sub.Close()
+
+			time.Sleep(1 * time.Second)
+
+			sent := &TestMessage{
+				A: 1,
+				B: []TestParent{{A: "other test"}},
+			}
+			pub.Write(sent)
+
+			msg := <-recv
+			require.Equal(t, sent, msg)
+
+			switch ca {
+			case "share":
+				require.Equal(t, true, sent == msg)
+			case "copy":
+				require.Equal(t, false, sent == msg)
+				require.Equal(t, false, &sent.B[0] == &msg.B[0])
+			}
+
+			conns, err := p.NodeGetConns("/myns/goroslib")
+			require.NoError(t, err)
+
+			transport := ""
+			for _, c := range conns {
+				if c.Topic == "/myns/test_topic" {
+					transport = c.Transport
+				}
+			}
+			if ca == "disabled" {
+				require.Equal(t, "TCPROS", transport)
+			} else {
+				require.Equal(t, "INTRAPROCESS", transport)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package goroslib
+
+import (
+	"fmt"
+)
+
+// SubscriberConfOf is the configuration of a SubscriberOf.
+type SubscriberConfOf[T any] struct {
+	// parent node.
+	Node *Node
+
+	// name of the topic from which messages will be read.
+	Topic string
+
+	// function that will be called whenever a message arrives.
+	Callback func(*T)
+
+	// (optional) protocol that will be used to receive messages
+	// it defaults to TCP.
+	// Subscribers of the same topic must use the same protocol.
+	Protocol Protocol
+
+	// (optional) preferences about the transport, that replace Protocol
+	// and DisableNoDelay when set.
+	// Subscribers of the same topic must use the same transport hints.
+	TransportHints *TransportHints
+
+	// (optional) preferences about the transport used with publishers of
+	// specific hosts, that replace TransportHints. Keys are hosts,
+	// as they appear in the URLs of publishers.
+	// Subscribers of the same topic must use the same transport hints.
+	HostTransportHints map[string]*TransportHints
+
+	// (optional) queue size. If the Callback is too slow, the queue fills up,
+	// and newer messages are discarded.
+	// It defaults to zero (wait the Callback synchronously).
+	QueueSize uint
+
+	// (optional) if protocol is TCP, enable keep-alive packets, that are
+	// useful when there's a firewall between nodes.
+	// If protocol is UDP, pings are always sent (see NodeConf.UdprosPingPeriod).
+	// Subscribers of the same topic must use the same setting.
+	EnableKeepAlive bool
+
+	// (optional) if protocol is UDP, asks publishers to send messages through
+	// multicast (see PublisherConf.UdpMulticastGroup). Publishers that do not
+	// support multicast send messages through UDPROS.
+	// It defaults to false.
+	// Subscribers of the same topic must use the same setting.
+	UdpMulticast bool
+
+	// (optional) if protocol is TCP, disables the TCP_NODELAY flag, which
+	// is enabled by default.
+	// It defaults to false.
+	// Subscribers of the same topic must use the same setting.
+	DisableNoDelay bool
+
+	// (optional) messages coming from publishers of the same process are
+	// passed to the Callback without being serialized, as deep copies.
+	// If this is true, they are not copied and are shared with the publisher
+	// and with other subscribers, therefore they must not be modified.
+	// It defaults to false.
+	IntraProcessShare bool
+}
+
+// SubscriberOf is a type-safe wrapper around Subscriber, where T is the type
+// of the messages.
+type SubscriberOf[T any] struct {
+	s *Subscriber
+}
+
+// NewSubscriberOf allocates a SubscriberOf. See SubscriberConfOf for the options.
+func NewSubscriberOf[T any](conf SubscriberConfOf[T]) (*SubscriberOf[T], error) {
+	if conf.Callback == nil {
+		return nil, fmt.Errorf("Callback is empty")
+	}
+
+	s, err := NewSubscriber(SubscriberConf{
+		Node:               conf.Node,
+		Topic:              conf.Topic,
+		Callback:           conf.Callback,
+		Protocol:           conf.Protocol,
+		QueueSize:          conf.QueueSize,
+		TransportHints:     conf.TransportHints,
+		HostTransportHints: conf.HostTransportHints,
+		EnableKeepAlive:    conf.EnableKeepAlive,
+		UdpMulticast:       conf.UdpMulticast,
+		DisableNoDelay:     conf.DisableNoDelay,
+		IntraProcessShare:  conf.IntraProcessShare,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &SubscriberOf[T]{
+		s: s,
+	}, nil
+}
+
+// Close closes a SubscriberOf and shuts down all its operations.
+func (s *SubscriberOf[T]) Close() error {
+	return s.s.Close()
+}
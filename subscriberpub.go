@@ -3,15 +3,19 @@ package goroslib
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"net"
+	"net/url"
 	"reflect"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/aler9/goroslib/pkg/apislave"
 	"github.com/aler9/goroslib/pkg/protocommon"
+	"github.com/aler9/goroslib/pkg/protoshm"
 	"github.com/aler9/goroslib/pkg/prototcp"
 	"github.com/aler9/goroslib/pkg/protoudp"
 )
@@ -19,26 +23,50 @@ import (
 var errSubscriberPubTerminate = errors.New("subscriberPublisher terminated")
 
 type subscriberPublisher struct {
-	sub     *Subscriber
+	sub     *topicSubscriber
 	address string
-
-	ctx       context.Context
-	ctxCancel func()
-	udpAddr   *net.UDPAddr
-	udpID     uint32
+	intra   bool
+	hints   TransportHints
+
+	ctx        context.Context
+	ctxCancel  func()
+	udpAddr    *net.UDPAddr
+	udpGroup   *net.UDPAddr
+	udpID      uint32
+	udpSource  *net.UDPAddr
+	shmFailed  bool
+	shmActive  int32
+	udpActive  int32
+	stats      connectionStats
+	statistics *topicStatistics
 
 	// in
 	udpFrame chan *protoudp.Frame
 }
 
-func newSubscriberPublisher(sub *Subscriber, address string) {
+func newSubscriberPublisher(sub *topicSubscriber, address string) {
 	ctx, ctxCancel := context.WithCancel(sub.ctx)
 
+	host, _, _ := net.SplitHostPort(address)
+
 	sp := &subscriberPublisher{
-		sub:       sub,
-		address:   address,
+		sub:     sub,
+		address: address,
+		intra: !sub.conf.Node.conf.DisableIntraProcess &&
+			intraProcessFind(address, sub.conf.Node.absoluteTopicName(sub.conf.Topic)) != nil,
+		hints:     sub.conf.hostTransportHints(host),
 		ctx:       ctx,
 		ctxCancel: ctxCancel,
+		stats: connectionStats{
+			id: sub.conf.Node.newConnectionID(),
+			topic: sub.conf.Node.metrics.topic(
+				sub.conf.Node.absoluteTopicName(sub.conf.Topic), 'i'),
+		},
+	}
+
+	topic := sub.conf.Node.absoluteTopicName(sub.conf.Topic)
+	if sub.conf.Node.statisticsPublisher != nil && topic != "/statistics" {
+		sp.statistics = newTopicStatistics(sub.conf.Node, topic)
 	}
 
 	sub.publishers[address] = sp
@@ -52,6 +80,49 @@ func (sp *subscriberPublisher) close() {
 	sp.ctxCancel()
 }
 
+func (sp *subscriberPublisher) info() InfoConnection {
+	return InfoConnection{
+		ID: sp.stats.id,
+		To: (&url.URL{
+			Scheme: "http",
+			Host:   sp.address,
+			Path:   "/",
+		}).String(),
+		Direction: 'i',
+		Transport: func() string {
+			switch {
+			case sp.intra:
+				return "INTRAPROCESS"
+
+			case atomic.LoadInt32(&sp.shmActive) == 1:
+				return "SHMROS"
+
+			case atomic.LoadInt32(&sp.udpActive) == 1:
+				return "UDPROS"
+
+			// when not connected, the preferred protocol is reported
+			case !sp.stats.isConnected() && sp.hints.Protocols[0] == UDP:
+				return "UDPROS"
+			}
+			return "TCPROS"
+		}(),
+		Topic:     sp.sub.conf.Node.absoluteTopicName(sp.sub.conf.Topic),
+		Connected: sp.stats.isConnected(),
+	}
+}
+
+func (sp *subscriberPublisher) onMessage(msg interface{}, bytes uint64, intra bool) {
+	sp.stats.onMessage(bytes, sp.sub.conf.Node.messageLatency(msg))
+
+	if sp.statistics != nil {
+		sp.statistics.onMessage(msg, bytes)
+	}
+
+	if !sp.sub.dispatch(msg, bytes, intra) {
+		sp.stats.onDrop()
+	}
+}
+
 func (sp *subscriberPublisher) run() {
 	defer sp.sub.publishersWg.Done()
 
@@ -67,6 +138,8 @@ func (sp *subscriberPublisher) run() {
 
 			select {
 			case <-t.C:
+				sp.sub.conf.Node.metrics.inc(metricsTopicReconnects, metricsLabels(
+					"topic", sp.sub.conf.Node.absoluteTopicName(sp.sub.conf.Topic)))
 				return true
 
 			case <-sp.ctx.Done():
@@ -82,7 +155,16 @@ func (sp *subscriberPublisher) run() {
 }
 
 func (sp *subscriberPublisher) runInner() error {
-	xcs := apislave.NewClient(sp.address, sp.sub.conf.Node.absoluteName())
+	if sp.intra {
+		tp := intraProcessFind(sp.address, sp.sub.conf.Node.absoluteTopicName(sp.sub.conf.Topic))
+		if tp == nil {
+			return fmt.Errorf("intra-process publisher not found")
+		}
+		return sp.runInnerIntra(tp)
+	}
+
+	xcs := apislave.NewClientWithHTTPClient(sp.address, sp.sub.conf.Node.absoluteName(),
+		sp.sub.conf.Node.slaveHTTPClient)
 
 	subDone := make(chan struct{}, 1)
 	var proto []interface{}
@@ -91,30 +173,42 @@ func (sp *subscriberPublisher) runInner() error {
 	go func() {
 		defer close(subDone)
 
-		protocols := func() [][]interface{} {
-			if sp.sub.conf.Protocol == TCP {
-				return [][]interface{}{{"TCPROS"}}
+		nodeIP, _, _ := net.SplitHostPort(sp.sub.conf.Node.nodeAddr.String())
+
+		protocols := func(prefs []Protocol) [][]interface{} {
+			var ret [][]interface{}
+
+			for _, pref := range prefs {
+				// publishers pick the first protocol they support,
+				// therefore TCPROS is used by publishers that do not support shared memory
+				// or that are on another host, and UDPROS is used by publishers
+				// that do not support multicast.
+				if pref == TCP {
+					if sp.sub.conf.Node.conf.EnableSharedMemory && !sp.shmFailed {
+						ret = append(ret, []interface{}{"SHMROS", sp.sub.conf.Node.sharedMemoryHostID})
+					}
+					ret = append(ret, []interface{}{"TCPROS"})
+					continue
+				}
+
+				if sp.sub.conf.UdpMulticast {
+					ret = append(ret, sp.udpProtocol("UDPROS_MULTICAST", nodeIP))
+				}
+				ret = append(ret, sp.udpProtocol("UDPROS", nodeIP))
 			}
 
-			nodeIP, _, _ := net.SplitHostPort(sp.sub.conf.Node.nodeAddr.String())
-			return [][]interface{}{{
-				"UDPROS",
-				func() []byte {
-					var buf bytes.Buffer
-					protocommon.HeaderEncode(&buf, &protoudp.HeaderSubscriber{
-						Callerid: sp.sub.conf.Node.absoluteName(),
-						Md5sum:   sp.sub.msgMd5,
-						Topic:    sp.sub.conf.Node.absoluteTopicName(sp.sub.conf.Topic),
-						Type:     sp.sub.msgType,
-					})
-					return buf.Bytes()[4:]
-				}(),
-				nodeIP,
-				sp.sub.conf.Node.udprosServer.Port(),
-				1500,
-			}}
-		}()
-		proto, err = xcs.RequestTopic(sp.sub.conf.Node.absoluteTopicName(sp.sub.conf.Topic), protocols)
+			return ret
+		}
+
+		// publishers that reject the request are asked again without
+		// the first protocol, until no protocols are left.
+		for i := range sp.hints.Protocols {
+			proto, err = xcs.RequestTopic(sp.sub.conf.Node.absoluteTopicName(sp.sub.conf.Topic),
+				protocols(sp.hints.Protocols[i:]))
+			if err == nil || sp.ctx.Err() != nil {
+				return
+			}
+		}
 	}()
 
 	select {
@@ -128,10 +222,43 @@ func (sp *subscriberPublisher) runInner() error {
 		return err
 	}
 
-	if sp.sub.conf.Protocol == TCP {
+	if len(proto) < 1 {
+		return fmt.Errorf("wrong protocol length")
+	}
+
+	protoName, ok := proto[0].(string)
+	if !ok {
+		return fmt.Errorf("wrong protoName")
+	}
+
+	switch protoName {
+	case "TCPROS", "SHMROS":
 		return sp.runInnerTCP(proto)
+
+	case "UDPROS", "UDPROS_MULTICAST":
+		return sp.runInnerUDP(proto)
+	}
+
+	return fmt.Errorf("unsupported protocol '%s'", protoName)
+}
+
+// udpProtocol returns the entry of a UDP protocol that is sent to publishers.
+func (sp *subscriberPublisher) udpProtocol(name string, nodeIP string) []interface{} {
+	var buf bytes.Buffer
+	protocommon.HeaderEncode(&buf, &protoudp.HeaderSubscriber{
+		Callerid: sp.sub.conf.Node.absoluteName(),
+		Md5sum:   sp.sub.msgMd5,
+		Topic:    sp.sub.conf.Node.absoluteTopicName(sp.sub.conf.Topic),
+		Type:     sp.sub.msgType,
+	})
+
+	return []interface{}{
+		name,
+		buf.Bytes()[4:],
+		nodeIP,
+		sp.sub.conf.Node.udprosServer.Port(),
+		sp.hints.MaxDatagramSize,
 	}
-	return sp.runInnerUDP(proto)
 }
 
 func (sp *subscriberPublisher) runInnerTCP(proto []interface{}) error {
@@ -154,7 +281,7 @@ func (sp *subscriberPublisher) runInnerTCP(proto []interface{}) error {
 		return fmt.Errorf("wrong protoPort")
 	}
 
-	if protoName != "TCPROS" {
+	if protoName != "TCPROS" && protoName != "SHMROS" {
 		return fmt.Errorf("wrong protoName")
 	}
 
@@ -165,7 +292,7 @@ func (sp *subscriberPublisher) runInnerTCP(proto []interface{}) error {
 	var err error
 	go func() {
 		defer close(subDone)
-		conn, err = prototcp.NewClient(addr)
+		conn, err = prototcp.NewClientWithTLS(addr, sp.sub.conf.Node.tlsClientConf)
 	}()
 
 	select {
@@ -181,12 +308,12 @@ func (sp *subscriberPublisher) runInnerTCP(proto []interface{}) error {
 	defer conn.Close()
 
 	if sp.sub.conf.EnableKeepAlive {
-		conn.NetConn().(*net.TCPConn).SetKeepAlive(true)
-		conn.NetConn().(*net.TCPConn).SetKeepAlivePeriod(60 * time.Second)
+		conn.TCPConn().SetKeepAlive(true)
+		conn.TCPConn().SetKeepAlivePeriod(60 * time.Second)
 	}
 
-	if sp.sub.conf.DisableNoDelay {
-		err := conn.NetConn().(*net.TCPConn).SetNoDelay(false)
+	if sp.hints.DisableNoDelay {
+		err := conn.TCPConn().SetNoDelay(false)
 		if err != nil {
 			return err
 		}
@@ -203,11 +330,25 @@ func (sp *subscriberPublisher) runInnerTCP(proto []interface{}) error {
 			Topic:    sp.sub.conf.Node.absoluteTopicName(sp.sub.conf.Topic),
 			Type:     sp.sub.msgType,
 			TcpNodelay: func() int {
-				if sp.sub.conf.DisableNoDelay {
+				if sp.hints.DisableNoDelay {
 					return 0
 				}
 				return 1
 			}(),
+			SharedMemory: func() *int {
+				if protoName != "SHMROS" {
+					return nil
+				}
+				v := 1
+				return &v
+			}(),
+			Compression: func() *string {
+				if protoName != "TCPROS" {
+					return nil
+				}
+				v := prototcp.CompressionZstd
+				return &v
+			}(),
 		})
 		if err != nil {
 			return
@@ -250,14 +391,57 @@ func (sp *subscriberPublisher) runInnerTCP(proto []interface{}) error {
 		return fmt.Errorf("wrong md5")
 	}
 
-	if sp.sub.conf.onPublisher != nil {
-		sp.sub.conf.onPublisher()
+	err = sp.sub.conf.Node.checkPeerIdentity(conn, outHeader.Callerid)
+	if err != nil {
+		return err
 	}
 
+	err = sp.sub.conf.Node.checkTopicAccess(sp.sub.conf.Node.absoluteTopicName(sp.sub.conf.Topic),
+		outHeader.Callerid, true)
+	if err != nil {
+		return err
+	}
+
+	if sp.statistics != nil {
+		sp.statistics.setPublisher(outHeader.Callerid)
+	}
+
+	if outHeader.SharedMemory != nil {
+		ring, err := protoshm.Open(*outHeader.SharedMemory)
+		if err != nil {
+			// use TCPROS in the next attempts
+			sp.shmFailed = true
+			return fmt.Errorf("unable to open shared memory: %s", err)
+		}
+		defer ring.Close()
+
+		// the file is not needed anymore, since it has been mapped by both nodes
+		ring.Remove()
+
+		return sp.runInnerSharedMemory(conn, ring)
+	}
+
+	if outHeader.Compression != nil {
+		err = conn.SetCompression(*outHeader.Compression)
+		if err != nil {
+			return err
+		}
+		sp.stats.setCompression(*outHeader.Compression)
+	}
+
+	sp.sub.onPublisherConnect()
+	defer sp.sub.onPublisherDisconnect()
+
+	sp.stats.setConnected(true)
+	defer sp.stats.setConnected(false)
+
 	subDone = make(chan struct{})
 	go func() {
 		defer close(subDone)
 
+		read := conn.BytesRead()
+		uncompressed := conn.UncompressedBytesRead()
+
 		for {
 			msg := reflect.New(sp.sub.msgMsg).Interface()
 			err = conn.ReadMessage(msg)
@@ -265,16 +449,70 @@ func (sp *subscriberPublisher) runInnerTCP(proto []interface{}) error {
 				return
 			}
 
-			if sp.sub.conf.QueueSize == 0 {
-				select {
-				case sp.sub.message <- msg:
-				case <-sp.sub.ctx.Done():
+			u := conn.UncompressedBytesRead()
+			sp.stats.onUncompressedBytes(u - uncompressed)
+			uncompressed = u
+
+			n := conn.BytesRead()
+			sp.onMessage(msg, n-read, false)
+			read = n
+		}
+	}()
+
+	select {
+	case <-subDone:
+		return err
+
+	case <-sp.ctx.Done():
+		conn.Close()
+		<-subDone
+		return errSubscriberPubTerminate
+	}
+}
+
+func (sp *subscriberPublisher) runInnerSharedMemory(conn *prototcp.Conn, ring *protoshm.Ring) error {
+	sp.sub.onPublisherConnect()
+	defer sp.sub.onPublisherDisconnect()
+
+	sp.stats.setConnected(true)
+	defer sp.stats.setConnected(false)
+
+	atomic.StoreInt32(&sp.shmActive, 1)
+	defer atomic.StoreInt32(&sp.shmActive, 0)
+
+	subDone := make(chan struct{})
+	var err error
+	go func() {
+		defer close(subDone)
+
+		buf := make([]byte, 64)
+
+		for {
+			// the publisher writes a byte after each message
+			_, err = conn.Read(buf)
+			if err != nil {
+				return
+			}
+
+			for {
+				var byts []byte
+				byts, err = ring.Next()
+				if err == protoshm.ErrEmpty {
+					break
 				}
-			} else {
-				select {
-				case sp.sub.message <- msg:
-				default:
+				if err != nil {
+					return
 				}
+
+				msg := reflect.New(sp.sub.msgMsg).Interface()
+				err := protocommon.MessageDecode(bytes.NewReader(byts), msg)
+				ring.Release()
+				if err != nil {
+					sp.stats.onDrop()
+					continue
+				}
+
+				sp.onMessage(msg, uint64(len(byts)), false)
 			}
 		}
 	}()
@@ -291,7 +529,7 @@ func (sp *subscriberPublisher) runInnerTCP(proto []interface{}) error {
 }
 
 func (sp *subscriberPublisher) runInnerUDP(proto []interface{}) error {
-	if len(proto) != 6 {
+	if len(proto) < 1 {
 		return fmt.Errorf("wrong protocol length")
 	}
 
@@ -300,6 +538,13 @@ func (sp *subscriberPublisher) runInnerUDP(proto []interface{}) error {
 		return fmt.Errorf("wrong protoName")
 	}
 
+	switch {
+	case protoName == "UDPROS" && len(proto) == 6:
+	case protoName == "UDPROS_MULTICAST" && len(proto) == 8:
+	default:
+		return fmt.Errorf("wrong protocol length")
+	}
+
 	protoHost, ok := proto[1].(string)
 	if !ok {
 		return fmt.Errorf("wrong protoHost")
@@ -315,8 +560,24 @@ func (sp *subscriberPublisher) runInnerUDP(proto []interface{}) error {
 		return fmt.Errorf("wrong protoID")
 	}
 
-	if protoName != "UDPROS" {
-		return fmt.Errorf("wrong protoName")
+	// the publisher header is encoded without its length
+	var pubHeader protocommon.HeaderRaw
+	if byts, ok := proto[5].([]byte); ok {
+		buf := make([]byte, 4+len(byts))
+		binary.LittleEndian.PutUint32(buf, uint32(len(byts)))
+		copy(buf[4:], byts)
+
+		pubHeader, _ = protocommon.HeaderRawDecode(bytes.NewReader(buf))
+	}
+
+	err := sp.sub.conf.Node.checkTopicAccess(sp.sub.conf.Node.absoluteTopicName(sp.sub.conf.Topic),
+		pubHeader["callerid"], true)
+	if err != nil {
+		return err
+	}
+
+	if sp.statistics != nil && pubHeader != nil {
+		sp.statistics.setPublisher(pubHeader["callerid"])
 	}
 
 	// solve host and port
@@ -325,7 +586,44 @@ func (sp *subscriberPublisher) runInnerUDP(proto []interface{}) error {
 		return fmt.Errorf("unable to solve host")
 	}
 
+	var group *net.UDPAddr
+	if protoName == "UDPROS_MULTICAST" {
+		groupHost, ok := proto[6].(string)
+		if !ok {
+			return fmt.Errorf("wrong groupHost")
+		}
+
+		groupPort, ok := proto[7].(int)
+		if !ok {
+			return fmt.Errorf("wrong groupPort")
+		}
+
+		group, err = net.ResolveUDPAddr("udp4", net.JoinHostPort(groupHost, strconv.FormatInt(int64(groupPort), 10)))
+		if err != nil || !group.IP.IsMulticast() {
+			return fmt.Errorf("invalid group")
+		}
+
+		res := make(chan error)
+		select {
+		case sp.sub.conf.Node.udpMulticastJoin <- udpMulticastJoinReq{group, res}:
+			err = <-res
+			if err != nil {
+				return err
+			}
+		case <-sp.sub.conf.Node.ctx.Done():
+			return errSubscriberPubTerminate
+		}
+
+		defer func() {
+			select {
+			case sp.sub.conf.Node.udpMulticastLeave <- group:
+			case <-sp.sub.conf.Node.ctx.Done():
+			}
+		}()
+	}
+
 	sp.udpAddr = addr
+	sp.udpGroup = group
 	sp.udpID = uint32(protoID)
 	sp.udpFrame = make(chan *protoudp.Frame)
 
@@ -344,83 +642,143 @@ func (sp *subscriberPublisher) runInnerUDP(proto []interface{}) error {
 		close(sp.udpFrame)
 	}()
 
-	if sp.sub.conf.onPublisher != nil {
-		sp.sub.conf.onPublisher()
-	}
+	sp.sub.onPublisherConnect()
+	defer sp.sub.onPublisherDisconnect()
 
-	readerClose := make(chan struct{})
-	readerDone := make(chan struct{})
-	if sp.sub.conf.EnableKeepAlive {
-		go func() {
-			defer close(readerDone)
+	sp.stats.setConnected(true)
+	defer sp.stats.setConnected(false)
 
-			curMessageID := uint8(0)
+	atomic.StoreInt32(&sp.udpActive, 1)
+	defer atomic.StoreInt32(&sp.udpActive, 0)
 
-			t := time.NewTicker(60 * time.Second)
-			defer t.Stop()
+	node := sp.sub.conf.Node
 
-			for {
-				select {
-				case <-t.C:
-					sp.sub.conf.Node.udprosServer.WriteFrame(&protoudp.Frame{
-						ConnectionID: sp.udpID,
-						Opcode:       protoudp.Ping,
-						MessageID:    curMessageID,
-					}, sp.udpAddr)
-					curMessageID++
-
-				case <-readerClose:
-					return
-				}
-			}
-		}()
+	r := protoudp.Reassembler{
+		Timeout: node.conf.UdprosReassemblyTimeout,
 	}
 
-	var curMsg []byte
-	curFieldID := 0
-	curFieldCount := 0
+	evictTicker := time.NewTicker(node.conf.UdprosReassemblyTimeout / 2)
+	defer evictTicker.Stop()
+
+	// pings allow the publisher to detect whether the subscriber is alive,
+	// and keep open firewalls between nodes.
+	pingTicker := time.NewTicker(node.conf.UdprosPingPeriod)
+	defer pingTicker.Stop()
+
+	curPingID := uint8(0)
 
 	for {
 		select {
 		case frame := <-sp.udpFrame:
-			switch frame.Opcode {
-			case protoudp.Data0:
-				curMsg = append([]byte{}, frame.Payload...)
-				curFieldID = 0
-				curFieldCount = int(frame.BlockID)
-
-			case protoudp.DataN:
-				if int(frame.BlockID) != (curFieldID + 1) {
-					continue
-				}
-				curMsg = append(curMsg, frame.Payload...)
-				curFieldID++
+			byts, lost := r.Push(frame, time.Now())
+			if lost > 0 {
+				sp.stats.onLost(uint64(lost))
 			}
 
-			if (curFieldID + 1) == curFieldCount {
+			if byts != nil {
 				msg := reflect.New(sp.sub.msgMsg).Interface()
-				err := protocommon.MessageDecode(bytes.NewBuffer(curMsg), msg)
+				err := protocommon.MessageDecode(bytes.NewBuffer(byts), msg)
 				if err != nil {
+					sp.stats.onDrop()
 					continue
 				}
 
-				if sp.sub.conf.QueueSize == 0 {
-					sp.sub.message <- msg
-				} else {
-					select {
-					case sp.sub.message <- msg:
-					default:
-					}
-				}
+				sp.onMessage(msg, uint64(len(byts)), false)
 			}
 
-		case <-sp.ctx.Done():
-			if sp.sub.conf.EnableKeepAlive {
-				close(readerClose)
-				<-readerDone
+		case now := <-evictTicker.C:
+			if r.Evict(now) > 0 {
+				sp.stats.onLost(1)
 			}
 
+		case <-pingTicker.C:
+			node.udprosServer.WriteFrame(&protoudp.Frame{
+				ConnectionID: sp.udpID,
+				Opcode:       protoudp.Ping,
+				MessageID:    curPingID,
+			}, sp.udpAddr)
+			curPingID++
+
+		case <-sp.ctx.Done():
+			return errSubscriberPubTerminate
+		}
+	}
+}
+
+func (sp *subscriberPublisher) runInnerIntra(tp *topicPublisher) error {
+	err := sp.sub.conf.Node.checkTopicAccess(sp.sub.conf.Node.absoluteTopicName(sp.sub.conf.Topic),
+		tp.conf.Node.absoluteName(), true)
+	if err != nil {
+		return err
+	}
+
+	conn := &intraProcessConn{
+		messages: make(chan interface{}, intraProcessQueueSize),
+		done:     sp.ctx.Done(),
+	}
+
+	res := make(chan subscriberIntraNewRes)
+	select {
+	case tp.subscriberIntraNew <- subscriberIntraNewReq{
+		callerID: sp.sub.conf.Node.absoluteName(),
+		md5:      sp.sub.msgMd5,
+		conn:     conn,
+		res:      res,
+	}:
+	case <-tp.ctx.Done():
+		return fmt.Errorf("intra-process publisher terminated")
+	case <-sp.ctx.Done():
+		return errSubscriberPubTerminate
+	}
+
+	r := <-res
+	if r.err != nil {
+		return r.err
+	}
+
+	if sp.statistics != nil {
+		sp.statistics.setPublisher(tp.conf.Node.absoluteName())
+	}
+
+	defer func() {
+		select {
+		case tp.subscriberTCPClose <- r.ps:
+		case <-tp.ctx.Done():
+		}
+	}()
+
+	sp.sub.onPublisherConnect()
+	defer sp.sub.onPublisherDisconnect()
+
+	sp.stats.setConnected(true)
+	defer sp.stats.setConnected(false)
+
+	for {
+		select {
+		case msg := <-conn.messages:
+			sp.onMessage(msg, 0, true)
+
+		// wait for the publisher to stop writing, in order not to lose messages
+		case <-tp.done:
+			sp.drainIntra(conn)
+			return fmt.Errorf("intra-process publisher terminated")
+
+		case <-sp.ctx.Done():
+			sp.drainIntra(conn)
 			return errSubscriberPubTerminate
 		}
 	}
 }
+
+// drainIntra delivers messages that were written before the connection was closed.
+func (sp *subscriberPublisher) drainIntra(conn *intraProcessConn) {
+	for {
+		select {
+		case msg := <-conn.messages:
+			sp.onMessage(msg, 0, true)
+
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package goroslib
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// TopicMonitorConf is the configuration of a TopicMonitor.
+type TopicMonitorConf struct {
+	// parent node.
+	Node *Node
+
+	// name of the topic to monitor.
+	Topic string
+
+	// an instance of the message type.
+	Msg interface{}
+
+	// (optional) protocol that will be used to receive messages
+	// it defaults to TCP.
+	Protocol Protocol
+
+	// (optional) number of messages that are used to compute statistics.
+	// It defaults to 100.
+	WindowSize int
+}
+
+// TopicMonitorStats contains the statistics computed by a TopicMonitor.
+type TopicMonitorStats struct {
+	// number of messages in the window.
+	Messages int
+
+	// average rate, in messages per second.
+	Rate float64
+
+	// minimum and maximum period between two messages.
+	MinPeriod time.Duration
+	MaxPeriod time.Duration
+
+	// standard deviation of the period between two messages.
+	PeriodStdDev time.Duration
+
+	// average bandwidth, in bytes per second.
+	// Messages coming from publishers of the same process are not serialized
+	// and are not counted.
+	Bandwidth float64
+
+	// average, minimum and maximum delay between the stamp of
+	// the header of messages and their arrival.
+	// They are zero if messages do not have a header.
+	Delay    time.Duration
+	MinDelay time.Duration
+	MaxDelay time.Duration
+}
+
+type topicMonitorEntry struct {
+	time     time.Time
+	size     int
+	delay    time.Duration
+	hasDelay bool
+}
+
+// TopicMonitor is an entity that subscribes to a topic and computes
+// rate, bandwidth and delay of messages over a sliding window,
+// like rostopic hz, bw and delay.
+type TopicMonitor struct {
+	conf TopicMonitorConf
+
+	sub       *Subscriber
+	mutex     sync.Mutex
+	window    []topicMonitorEntry
+	windowPos int
+	windowLen int
+}
+
+// NewTopicMonitor allocates a TopicMonitor. See TopicMonitorConf for the options.
+func NewTopicMonitor(conf TopicMonitorConf) (*TopicMonitor, error) {
+	if conf.Node == nil {
+		return nil, fmt.Errorf("Node is empty")
+	}
+
+	if conf.Msg == nil {
+		return nil, fmt.Errorf("Msg is empty")
+	}
+
+	msgt := reflect.TypeOf(conf.Msg)
+	if msgt.Kind() != reflect.Ptr {
+		return nil, fmt.Errorf("Msg must be a pointer")
+	}
+
+	if conf.WindowSize < 0 {
+		return nil, fmt.Errorf("WindowSize must not be negative")
+	}
+
+	if conf.WindowSize == 0 {
+		conf.WindowSize = 100
+	}
+
+	m := &TopicMonitor{
+		conf:   conf,
+		window: make([]topicMonitorEntry, conf.WindowSize),
+	}
+
+	var err error
+	m.sub, err = NewSubscriber(SubscriberConf{
+		Node:     conf.Node,
+		Topic:    conf.Topic,
+		Protocol: conf.Protocol,
+		// the callback is replaced by onMessage and is only used to
+		// define the message type.
+		Callback: reflect.Zero(
+			reflect.FuncOf([]reflect.Type{msgt}, []reflect.Type{}, false)).Interface(),
+		onMessage: m.onMessage,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Close closes a TopicMonitor.
+func (m *TopicMonitor) Close() error {
+	return m.sub.Close()
+}
+
+// Clear resets the statistics.
+func (m *TopicMonitor) Clear() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.windowPos = 0
+	m.windowLen = 0
+}
+
+func (m *TopicMonitor) onMessage(msg interface{}, bytes uint64) {
+	now := m.conf.Node.TimeNow()
+
+	entry := topicMonitorEntry{
+		time: now,
+		size: int(bytes),
+	}
+
+	if stamp, ok := messageStamp(msg); ok {
+		entry.delay = now.Sub(stamp)
+		entry.hasDelay = true
+	}
+
+	m.tick(entry)
+}
+
+func (m *TopicMonitor) tick(entry topicMonitorEntry) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.window[m.windowPos] = entry
+	m.windowPos = (m.windowPos + 1) % len(m.window)
+	if m.windowLen < len(m.window) {
+		m.windowLen++
+	}
+}
+
+// Stats returns the statistics computed over the current window.
+func (m *TopicMonitor) Stats() TopicMonitorStats {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	st := TopicMonitorStats{
+		Messages: m.windowLen,
+	}
+
+	if m.windowLen == 0 {
+		return st
+	}
+
+	start := (m.windowPos - m.windowLen + len(m.window)) % len(m.window)
+	entry := func(i int) topicMonitorEntry {
+		return m.window[(start+i)%len(m.window)]
+	}
+
+	// delay
+	delays := 0
+	var delaySum time.Duration
+	for i := 0; i < m.windowLen; i++ {
+		e := entry(i)
+		if !e.hasDelay {
+			continue
+		}
+
+		if delays == 0 || e.delay < st.MinDelay {
+			st.MinDelay = e.delay
+		}
+		if delays == 0 || e.delay > st.MaxDelay {
+			st.MaxDelay = e.delay
+		}
+		delaySum += e.delay
+		delays++
+	}
+	if delays > 0 {
+		st.Delay = delaySum / time.Duration(delays)
+	}
+
+	// rate and bandwidth are computed on the intervals between messages
+	if m.windowLen < 2 {
+		return st
+	}
+
+	span := entry(m.windowLen - 1).time.Sub(entry(0).time)
+	if span <= 0 {
+		return st
+	}
+
+	periods := m.windowLen - 1
+	mean := span.Seconds() / float64(periods)
+	st.Rate = 1 / mean
+
+	bytes := 0
+	variance := float64(0)
+	for i := 1; i < m.windowLen; i++ {
+		period := entry(i).time.Sub(entry(i - 1).time)
+
+		if i == 1 || period < st.MinPeriod {
+			st.MinPeriod = period
+		}
+		if i == 1 || period > st.MaxPeriod {
+			st.MaxPeriod = period
+		}
+
+		d := period.Seconds() - mean
+		variance += d * d
+
+		bytes += entry(i).size
+	}
+
+	st.PeriodStdDev = time.Duration(math.Sqrt(variance/float64(periods)) * float64(time.Second))
+	st.Bandwidth = float64(bytes) / span.Seconds()
+
+	return st
+}
@@ -0,0 +1,109 @@
+package goroslib
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/aler9/goroslib/pkg/msgs/sensor_msgs"
+	"github.com/aler9/goroslib/pkg/msgs/std_msgs"
+)
+
+func TestTopicMonitorStats(t *testing.T) {
+	m := &TopicMonitor{
+		window: make([]topicMonitorEntry, 3),
+	}
+
+	require.Equal(t, TopicMonitorStats{}, m.Stats())
+
+	start := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// the first entry is pushed out of the window
+	m.tick(topicMonitorEntry{time: start, size: 1000})
+	m.tick(topicMonitorEntry{time: start.Add(1 * time.Second), size: 10})
+	m.tick(topicMonitorEntry{
+		time:     start.Add(1500 * time.Millisecond),
+		size:     20,
+		delay:    100 * time.Millisecond,
+		hasDelay: true,
+	})
+	m.tick(topicMonitorEntry{
+		time:     start.Add(2500 * time.Millisecond),
+		size:     30,
+		delay:    300 * time.Millisecond,
+		hasDelay: true,
+	})
+
+	require.Equal(t, TopicMonitorStats{
+		Messages:     3,
+		Rate:         4.0 / 3.0,
+		MinPeriod:    500 * time.Millisecond,
+		MaxPeriod:    1000 * time.Millisecond,
+		PeriodStdDev: 250 * time.Millisecond,
+		Bandwidth:    50 / 1.5,
+		Delay:        200 * time.Millisecond,
+		MinDelay:     100 * time.Millisecond,
+		MaxDelay:     300 * time.Millisecond,
+	}, m.Stats())
+
+	m.Clear()
+	require.Equal(t, TopicMonitorStats{}, m.Stats())
+}
+
+func TestTopicMonitorErrors(t *testing.T) {
+	_, err := NewTopicMonitor(TopicMonitorConf{
+		Node:       &Node{},
+		Topic:      "test_topic",
+		Msg:        &std_msgs.Int64{},
+		WindowSize: -1,
+	})
+	require.EqualError(t, err, "WindowSize must not be negative")
+}
+
+func TestTopicMonitor(t *testing.T) {
+	m, err := newContainerMaster()
+	require.NoError(t, err)
+	defer m.close()
+
+	n, err := NewNode(NodeConf{
+		Namespace:     "/myns",
+		Name:          "goroslib",
+		MasterAddress: m.IP() + ":11311",
+	})
+	require.NoError(t, err)
+	defer n.Close()
+
+	pub, err := NewPublisher(PublisherConf{
+		Node:  n,
+		Topic: "test_topic",
+		Msg:   &sensor_msgs.Imu{},
+	})
+	require.NoError(t, err)
+	defer pub.Close()
+
+	tm, err := NewTopicMonitor(TopicMonitorConf{
+		Node:  n,
+		Topic: "test_topic",
+		Msg:   &sensor_msgs.Imu{},
+	})
+	require.NoError(t, err)
+	defer tm.Close()
+
+	time.Sleep(1 * time.Second)
+
+	for i := 0; i < 10; i++ {
+		pub.Write(&sensor_msgs.Imu{
+			Header: std_msgs.Header{
+				Stamp: time.Now(),
+			},
+		})
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	st := tm.Stats()
+	require.Equal(t, 10, st.Messages)
+	require.InDelta(t, 10, st.Rate, 2)
+	require.Greater(t, st.Bandwidth, float64(0))
+	require.GreaterOrEqual(t, int64(st.MinDelay), int64(0))
+}
@@ -0,0 +1,548 @@
+package goroslib
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/aler9/goroslib/pkg/apislave"
+	"github.com/aler9/goroslib/pkg/protocommon"
+	"github.com/aler9/goroslib/pkg/protoshm"
+	"github.com/aler9/goroslib/pkg/prototcp"
+	"github.com/aler9/goroslib/pkg/protoudp"
+)
+
+// topicPublisher is shared among all the Publishers of a topic.
+// It owns the registration with the master and the connections with subscribers.
+type topicPublisher struct {
+	conf PublisherConf
+
+	ctx             context.Context
+	ctxCancel       func()
+	msgType         string
+	msgMd5          string
+	id              int
+	subscribers     map[string]*publisherSubscriber
+	subscribersWg   sync.WaitGroup
+	lastMessage     interface{}
+	mutex           sync.Mutex
+	handles         []*Publisher
+	subscriberCount int
+	multicastSender *publisherUDPSender
+
+	// number of intra-process subscribers, read by Publisher.Write
+	intraSubscribers int32
+
+	// in
+	getBusInfo         chan getBusInfoSubReq
+	getStats           chan getStatsSubReq
+	requestTopic       chan subscriberRequestTopicReq
+	subscriberTCPNew   chan tcpConnSubscriberReq
+	subscriberTCPClose chan *publisherSubscriber
+	subscriberIntraNew chan subscriberIntraNewReq
+	subscriberUDPPing  chan *net.UDPAddr
+	write              chan interface{}
+
+	// out
+	done chan struct{}
+}
+
+func newTopicPublisher(conf PublisherConf, msgType string, msgMd5 string, id int) *topicPublisher {
+	ctx, ctxCancel := context.WithCancel(conf.Node.ctx)
+
+	p := &topicPublisher{
+		conf:               conf,
+		ctx:                ctx,
+		ctxCancel:          ctxCancel,
+		msgType:            msgType,
+		msgMd5:             msgMd5,
+		id:                 id,
+		subscribers:        make(map[string]*publisherSubscriber),
+		getBusInfo:         make(chan getBusInfoSubReq),
+		getStats:           make(chan getStatsSubReq),
+		requestTopic:       make(chan subscriberRequestTopicReq),
+		subscriberTCPNew:   make(chan tcpConnSubscriberReq),
+		subscriberTCPClose: make(chan *publisherSubscriber),
+		subscriberIntraNew: make(chan subscriberIntraNewReq),
+		subscriberUDPPing:  make(chan *net.UDPAddr),
+		write:              make(chan interface{}),
+		done:               make(chan struct{}),
+	}
+
+	if conf.UdpMulticastGroup != "" {
+		// the group has already been validated by NewPublisher
+		addr, _ := net.ResolveUDPAddr("udp4", conf.UdpMulticastGroup)
+		p.multicastSender = newPublisherUDPSender(p, addr, conf.Node.conf.UdprosMaxDatagramSize)
+	}
+
+	go p.run()
+
+	return p
+}
+
+func (p *topicPublisher) close() {
+	p.ctxCancel()
+}
+
+// addPublisher attaches a Publisher.
+func (p *topicPublisher) addPublisher(pub *Publisher) {
+	p.mutex.Lock()
+	p.handles = append(append([]*Publisher(nil), p.handles...), pub)
+	hasSubscribers := (p.subscriberCount > 0)
+	p.mutex.Unlock()
+
+	if hasSubscribers && pub.conf.onSubscriber != nil {
+		pub.conf.onSubscriber()
+	}
+}
+
+// removePublisher removes a Publisher, and returns the number of remaining Publishers.
+func (p *topicPublisher) removePublisher(pub *Publisher) int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var handles []*Publisher
+	for _, h := range p.handles {
+		if h != pub {
+			handles = append(handles, h)
+		}
+	}
+	p.handles = handles
+
+	return len(p.handles)
+}
+
+func (p *topicPublisher) onSubscriberConnect() {
+	p.mutex.Lock()
+	p.subscriberCount++
+	handles := p.handles
+	p.mutex.Unlock()
+
+	for _, h := range handles {
+		if h.conf.onSubscriber != nil {
+			h.conf.onSubscriber()
+		}
+	}
+}
+
+func (p *topicPublisher) onSubscriberDisconnect() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.subscriberCount--
+}
+
+func (p *topicPublisher) run() {
+	defer close(p.done)
+
+	if p.multicastSender != nil {
+		senderDone := make(chan struct{})
+		go func() {
+			defer close(senderDone)
+			p.multicastSender.run(p.ctx)
+		}()
+		defer func() { <-senderDone }()
+	}
+
+outer:
+	for {
+		select {
+		case req := <-p.getBusInfo:
+			for _, ps := range p.subscribers {
+				*req.pbusInfo = append(*req.pbusInfo, ps.stats.busInfo(ps.info()))
+			}
+			close(req.done)
+
+		case req := <-p.getStats:
+			for _, ps := range p.subscribers {
+				*req.pstats = append(*req.pstats, ps.stats.get(ps.info()))
+			}
+			close(req.done)
+
+		case req := <-p.requestTopic:
+			err := func() error {
+				// use the first protocol that is supported.
+				// UDPROS_MULTICAST is supported only if a multicast group is set,
+				// otherwise subscribers fall back to the next protocol, that is UDPROS.
+				var proto []interface{}
+				var protoName string
+				for _, entry := range req.req.Protocols {
+					if len(entry) < 1 {
+						continue
+					}
+
+					name, ok := entry[0].(string)
+					if !ok {
+						continue
+					}
+
+					if name == "TCPROS" || name == "UDPROS" ||
+						(name == "UDPROS_MULTICAST" && p.multicastSender != nil) ||
+						(name == "SHMROS" && p.supportsSharedMemory(entry)) {
+						proto = entry
+						protoName = name
+						break
+					}
+				}
+
+				switch protoName {
+				// the subscriber connects to the TCPROS server in both cases
+				case "TCPROS", "SHMROS":
+					nodeIP, _, _ := net.SplitHostPort(p.conf.Node.nodeAddr.String())
+					req.res <- apislave.ResponseRequestTopic{
+						Code:          1,
+						StatusMessage: "",
+						Protocol: []interface{}{
+							protoName,
+							nodeIP,
+							p.conf.Node.tcprosServer.Port(),
+						},
+					}
+					return nil
+
+				case "UDPROS", "UDPROS_MULTICAST":
+					multicast := (protoName == "UDPROS_MULTICAST")
+
+					if p.conf.Node.conf.TLS != nil {
+						return fmt.Errorf("UDPROS can't be used since TLS is enabled")
+					}
+
+					if len(proto) < 5 {
+						return fmt.Errorf("invalid protocol")
+					}
+
+					protoDef, ok := proto[1].([]byte)
+					if !ok {
+						return fmt.Errorf("invalid protoDef")
+					}
+
+					protoHost, ok := proto[2].(string)
+					if !ok {
+						return fmt.Errorf("invalid protoHost")
+					}
+
+					protoPort, ok := proto[3].(int)
+					if !ok {
+						return fmt.Errorf("invalid protoPort")
+					}
+
+					maxDatagramSize, ok := proto[4].(int)
+					if !ok || maxDatagramSize < udprosMinDatagramSize {
+						return fmt.Errorf("invalid max datagram size")
+					}
+
+					if maxDatagramSize > p.conf.Node.conf.UdprosMaxDatagramSize {
+						maxDatagramSize = p.conf.Node.conf.UdprosMaxDatagramSize
+					}
+
+					newProtoDef := make([]byte, 4)
+					binary.LittleEndian.PutUint32(newProtoDef, uint32(len(protoDef)))
+					newProtoDef = append(newProtoDef, protoDef...)
+					buf := bytes.NewBuffer(newProtoDef)
+
+					raw, err := protocommon.HeaderRawDecode(buf)
+					if err != nil {
+						return err
+					}
+
+					var header protoudp.HeaderSubscriber
+					err = protocommon.HeaderDecode(raw, &header)
+					if err != nil {
+						return err
+					}
+
+					err = p.conf.Node.checkTopicAccess(p.conf.Node.absoluteTopicName(p.conf.Topic),
+						header.Callerid, false)
+					if err != nil {
+						return err
+					}
+
+					_, ok = p.subscribers[header.Callerid]
+					if ok {
+						return fmt.Errorf("topic '%s' is already subscribed by '%s'",
+							p.conf.Topic, header.Callerid)
+					}
+
+					if header.Md5sum != p.msgMd5 {
+						return fmt.Errorf("wrong md5: expected '%s', got '%s'",
+							p.msgMd5, header.Md5sum)
+					}
+
+					udpAddr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(protoHost, strconv.FormatInt(int64(protoPort), 10)))
+					if err != nil {
+						return fmt.Errorf("unable to solve udp address)")
+					}
+
+					// if subscriber is in localhost, send packets from localhost to localhost
+					// this avoids a bug in which the source ip is randomly chosen
+					// from all available interfaces, making ip-based filtering unpractical
+					isLocalhost := isLocalIP(udpAddr.IP)
+					if isLocalhost {
+						udpAddr.IP = net.IPv4(127, 0, 0, 1)
+					}
+
+					newPublisherSubscriber(p,
+						header.Callerid, nil, nil, udpAddr, maxDatagramSize, multicast, nil)
+
+					// messages are sent to the group with the datagram size of this node
+					if multicast {
+						maxDatagramSize = p.multicastSender.maxDatagramSize
+					}
+
+					res := apislave.ResponseRequestTopic{
+						Code:          1,
+						StatusMessage: "",
+						Protocol: []interface{}{
+							protoName,
+							func() string {
+								if isLocalhost {
+									return "127.0.0.1"
+								}
+								nodeIP, _, _ := net.SplitHostPort(p.conf.Node.nodeAddr.String())
+								return nodeIP
+							}(),
+							p.conf.Node.udprosServer.Port(),
+							p.id,
+							maxDatagramSize,
+							func() []byte {
+								var buf bytes.Buffer
+								protocommon.HeaderEncode(&buf, &protoudp.HeaderPublisher{
+									Callerid: p.conf.Node.absoluteName(),
+									Md5sum:   p.msgMd5,
+									Topic:    p.conf.Node.absoluteTopicName(p.conf.Topic),
+									Type:     p.msgType,
+								})
+								return buf.Bytes()[4:]
+							}(),
+						},
+					}
+
+					if multicast {
+						res.Protocol = append(res.Protocol,
+							p.multicastSender.addr.IP.String(),
+							p.multicastSender.addr.Port)
+					}
+
+					req.res <- res
+					return nil
+				}
+
+				return fmt.Errorf("invalid protocol")
+			}()
+			if err != nil {
+				req.res <- apislave.ResponseRequestTopic{
+					Code:          0,
+					StatusMessage: err.Error(),
+				}
+				continue
+			}
+
+		case req := <-p.subscriberTCPNew:
+			err := func() error {
+				err := p.conf.Node.checkTopicAccess(p.conf.Node.absoluteTopicName(p.conf.Topic),
+					req.header.Callerid, false)
+				if err != nil {
+					return err
+				}
+
+				_, ok := p.subscribers[req.header.Callerid]
+				if ok {
+					return fmt.Errorf("topic '%s' is already subscribed by '%s'",
+						p.conf.Topic, req.header.Callerid)
+				}
+
+				// wildcard is used by rostopic hz
+				if req.header.Md5sum != "*" && req.header.Md5sum != p.msgMd5 {
+					return fmt.Errorf("wrong md5: expected '%s', got '%s'",
+						p.msgMd5, req.header.Md5sum)
+				}
+
+				// if the ring buffer can't be allocated, TCPROS is used
+				var ring *protoshm.Ring
+				if req.header.SharedMemory != nil && *req.header.SharedMemory == 1 &&
+					p.conf.Node.conf.EnableSharedMemory {
+					ring, _ = protoshm.Create("", p.conf.Node.conf.SharedMemoryBufferSize)
+				}
+
+				// messages are compressed only if the subscriber supports it
+				var compression string
+				if p.conf.TcpCompression && ring == nil && req.header.Compression != nil {
+					compression = prototcp.ChooseCompression(*req.header.Compression)
+				}
+
+				err = req.conn.WriteHeader(&prototcp.HeaderPublisher{
+					Callerid: p.conf.Node.absoluteName(),
+					Md5sum:   p.msgMd5,
+					Topic:    p.conf.Node.absoluteTopicName(p.conf.Topic),
+					Type:     p.msgType,
+					Latching: func() int {
+						if p.conf.Latch {
+							return 1
+						}
+						return 0
+					}(),
+					SharedMemory: func() *string {
+						if ring == nil {
+							return nil
+						}
+						v := ring.Path()
+						return &v
+					}(),
+					Compression: func() *string {
+						if compression == "" {
+							return nil
+						}
+						return &compression
+					}(),
+				})
+				if err != nil {
+					if ring != nil {
+						ring.Remove()
+						ring.Close()
+					}
+					req.conn.Close()
+					return nil
+				}
+
+				if req.header.TcpNodelay == 0 {
+					req.conn.TCPConn().SetNoDelay(false)
+				}
+
+				if compression != "" {
+					err = req.conn.SetCompression(compression)
+					if err != nil {
+						req.conn.Close()
+						return nil
+					}
+				}
+
+				ps := newPublisherSubscriber(p,
+					req.header.Callerid, req.conn, ring, nil, 0, false, nil)
+
+				if compression != "" {
+					ps.stats.setCompression(compression)
+				}
+
+				if p.conf.Latch && p.lastMessage != nil {
+					p.subscribers[req.header.Callerid].writeMessage(p.lastMessage)
+				}
+
+				return nil
+			}()
+			if err != nil {
+				req.conn.WriteHeader(&prototcp.HeaderError{
+					Error: err.Error(),
+				})
+				req.conn.Close()
+				continue
+			}
+
+		case sub := <-p.subscriberTCPClose:
+			sub.close()
+
+		case addr := <-p.subscriberUDPPing:
+			for _, ps := range p.subscribers {
+				if ps.udpAddr != nil && sameUDPAddr(ps.udpAddr, addr) {
+					ps.onPing()
+					break
+				}
+			}
+
+		case req := <-p.subscriberIntraNew:
+			_, ok := p.subscribers[req.callerID]
+			if ok {
+				req.res <- subscriberIntraNewRes{err: fmt.Errorf("topic '%s' is already subscribed by '%s'",
+					p.conf.Topic, req.callerID)}
+				continue
+			}
+
+			if req.md5 != p.msgMd5 {
+				req.res <- subscriberIntraNewRes{err: fmt.Errorf("wrong md5: expected '%s', got '%s'",
+					p.msgMd5, req.md5)}
+				continue
+			}
+
+			err := p.conf.Node.checkTopicAccess(p.conf.Node.absoluteTopicName(p.conf.Topic),
+				req.callerID, false)
+			if err != nil {
+				req.res <- subscriberIntraNewRes{err: err}
+				continue
+			}
+
+			ps := newPublisherSubscriber(p,
+				req.callerID, nil, nil, nil, 0, false, req.conn)
+			atomic.AddInt32(&p.intraSubscribers, 1)
+
+			if p.conf.Latch && p.lastMessage != nil {
+				ps.writeMessage(p.lastMessage)
+			}
+
+			req.res <- subscriberIntraNewRes{ps: ps}
+
+		case msg := <-p.write:
+			if p.conf.Latch {
+				p.lastMessage = msg
+			}
+
+			if p.multicastSender != nil {
+				p.writeMulticast(msg)
+			}
+
+			for _, s := range p.subscribers {
+				s.writeMessage(msg)
+			}
+
+		case <-p.ctx.Done():
+			break outer
+		}
+	}
+
+	p.ctxCancel()
+
+	p.subscribersWg.Wait()
+
+	for _, ps := range p.subscribers {
+		ps.closeSharedMemory()
+	}
+}
+
+// supportsSharedMemory checks whether a SHMROS protocol entry,
+// that contains the host ID of the subscriber, can be accepted.
+func (p *topicPublisher) supportsSharedMemory(entry []interface{}) bool {
+	if !p.conf.Node.conf.EnableSharedMemory || len(entry) < 2 {
+		return false
+	}
+
+	hostID, ok := entry[1].(string)
+	return ok && hostID == p.conf.Node.sharedMemoryHostID
+}
+
+// writeMulticast sends a message once to the multicast group,
+// if there's at least a subscriber that joined it.
+func (p *topicPublisher) writeMulticast(msg interface{}) {
+	var subs []*publisherSubscriber
+	for _, ps := range p.subscribers {
+		if ps.udpMulticast {
+			subs = append(subs, ps)
+		}
+	}
+
+	if len(subs) == 0 {
+		return
+	}
+
+	latency := p.conf.Node.messageLatency(msg)
+
+	n, err := p.multicastSender.write(msg)
+	for _, ps := range subs {
+		if err != nil {
+			ps.stats.onDrop()
+		} else {
+			ps.stats.onMessage(uint64(n), latency)
+		}
+	}
+}
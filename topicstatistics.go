@@ -0,0 +1,215 @@
+package goroslib
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/aler9/goroslib/pkg/msgs/rosgraph_msgs"
+)
+
+// topicStatisticsConf contains the parameters of topic statistics.
+// https://wiki.ros.org/Topics#Topic_statistics
+type topicStatisticsConf struct {
+	minElements int
+	maxElements int
+	minWindow   int
+	maxWindow   int
+}
+
+func (n *Node) paramGetIntDefault(key string, def int) (int, error) {
+	isSet, err := n.ParamIsSet(key)
+	if err != nil {
+		return 0, err
+	}
+
+	if !isSet {
+		return def, nil
+	}
+
+	return n.ParamGetInt(key)
+}
+
+// initStatistics reads the statistics parameters and, if statistics are enabled,
+// creates the publisher of /statistics.
+func (n *Node) initStatistics() error {
+	isSet, err := n.ParamIsSet("/enable_statistics")
+	if err != nil {
+		return err
+	}
+
+	if !isSet {
+		return nil
+	}
+
+	enabled, err := n.ParamGetBool("/enable_statistics")
+	if err != nil {
+		return err
+	}
+
+	if !enabled {
+		return nil
+	}
+
+	for _, p := range []struct {
+		key string
+		def int
+		val *int
+	}{
+		{"/statistics_window_min_elements", 10, &n.statisticsConf.minElements},
+		{"/statistics_window_max_elements", 100, &n.statisticsConf.maxElements},
+		{"/statistics_window_min_size", 4, &n.statisticsConf.minWindow},
+		{"/statistics_window_max_size", 64, &n.statisticsConf.maxWindow},
+	} {
+		*p.val, err = n.paramGetIntDefault(p.key, p.def)
+		if err != nil {
+			return err
+		}
+	}
+
+	n.statisticsPublisher, err = NewPublisher(PublisherConf{
+		Node:  n,
+		Topic: "/statistics",
+		Msg:   &rosgraph_msgs.TopicStatistics{},
+	})
+	return err
+}
+
+// topicStatistics computes the statistics of the messages received
+// from a publisher, and periodically publishes them on /statistics.
+// https://github.com/ros/ros_comm/blob/noetic-devel/clients/roscpp/src/libros/statistics.cpp
+type topicStatistics struct {
+	n     *Node
+	topic string
+
+	mutex        sync.Mutex
+	nodePub      string
+	pubFrequency float64
+	windowStart  time.Time
+	arrivals     []time.Time
+	ages         []time.Duration
+	dropped      int32
+	traffic      int32
+	lastSeq      uint32
+	hasLastSeq   bool
+}
+
+func newTopicStatistics(n *Node, topic string) *topicStatistics {
+	return &topicStatistics{
+		n:            n,
+		topic:        topic,
+		pubFrequency: 1,
+		windowStart:  n.TimeNow(),
+	}
+}
+
+// setPublisher sets the name of the publisher, that is known after the connection.
+func (ts *topicStatistics) setPublisher(nodePub string) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+	ts.nodePub = nodePub
+}
+
+// onMessage is called when a message is received.
+// bytes is the size of the serialized message, or zero if the message was not serialized.
+func (ts *topicStatistics) onMessage(msg interface{}, bytes uint64) {
+	stats := ts.update(msg, bytes)
+	if stats != nil {
+		ts.n.statisticsPublisher.Write(stats)
+	}
+}
+
+// update updates the statistics with a message, and returns the statistics
+// that must be published when the window is over, or nil.
+func (ts *topicStatistics) update(msg interface{}, bytes uint64) *rosgraph_msgs.TopicStatistics {
+	now := ts.n.TimeNow()
+
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	ts.arrivals = append(ts.arrivals, now)
+	ts.traffic += int32(bytes)
+
+	if header, ok := messageHeader(msg); ok {
+		if !header.Stamp.IsZero() {
+			ts.ages = append(ts.ages, now.Sub(header.Stamp))
+		}
+
+		if ts.hasLastSeq && header.Seq > ts.lastSeq+1 {
+			ts.dropped += int32(header.Seq - ts.lastSeq - 1)
+		}
+		ts.lastSeq = header.Seq
+		ts.hasLastSeq = true
+	}
+
+	if now.Sub(ts.windowStart).Seconds() < 1/ts.pubFrequency {
+		return nil
+	}
+
+	periods := make([]time.Duration, 0, len(ts.arrivals))
+	for i := 1; i < len(ts.arrivals); i++ {
+		periods = append(periods, ts.arrivals[i].Sub(ts.arrivals[i-1]))
+	}
+
+	periodMean, periodStddev, periodMax := durationsMeanStddevMax(periods)
+	ageMean, ageStddev, ageMax := durationsMeanStddevMax(ts.ages)
+
+	stats := &rosgraph_msgs.TopicStatistics{
+		Topic:          ts.topic,
+		NodePub:        ts.nodePub,
+		NodeSub:        ts.n.absoluteName(),
+		WindowStart:    ts.windowStart,
+		WindowStop:     now,
+		DeliveredMsgs:  int32(len(ts.arrivals)),
+		DroppedMsgs:    ts.dropped,
+		Traffic:        ts.traffic,
+		PeriodMean:     periodMean,
+		PeriodStddev:   periodStddev,
+		PeriodMax:      periodMax,
+		StampAgeMean:   ageMean,
+		StampAgeStddev: ageStddev,
+		StampAgeMax:    ageMax,
+	}
+
+	// adapt the window to the message rate
+	conf := ts.n.statisticsConf
+	if len(ts.arrivals) > conf.maxElements && ts.pubFrequency*2 <= float64(conf.maxWindow) {
+		ts.pubFrequency *= 2
+	}
+	if len(ts.arrivals) < conf.minElements && ts.pubFrequency/2 >= float64(conf.minWindow) {
+		ts.pubFrequency /= 2
+	}
+
+	ts.windowStart = now
+	ts.arrivals = nil
+	ts.ages = nil
+	ts.dropped = 0
+	ts.traffic = 0
+
+	return stats
+}
+
+func durationsMeanStddevMax(vals []time.Duration) (time.Duration, time.Duration, time.Duration) {
+	if len(vals) == 0 {
+		return 0, 0, 0
+	}
+
+	var sum time.Duration
+	var max time.Duration
+	for _, v := range vals {
+		sum += v
+		if v > max {
+			max = v
+		}
+	}
+	mean := sum / time.Duration(len(vals))
+
+	variance := float64(0)
+	for _, v := range vals {
+		d := float64(v - mean)
+		variance += d * d
+	}
+	stddev := time.Duration(math.Sqrt(variance / float64(len(vals))))
+
+	return mean, stddev, max
+}
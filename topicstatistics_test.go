@@ -0,0 +1,123 @@
+package goroslib
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/aler9/goroslib/pkg/msgs/rosgraph_msgs"
+	"github.com/aler9/goroslib/pkg/msgs/sensor_msgs"
+	"github.com/aler9/goroslib/pkg/msgs/std_msgs"
+)
+
+func TestDurationsMeanStddevMax(t *testing.T) {
+	mean, stddev, max := durationsMeanStddevMax(nil)
+	require.Equal(t, time.Duration(0), mean)
+	require.Equal(t, time.Duration(0), stddev)
+	require.Equal(t, time.Duration(0), max)
+
+	mean, stddev, max = durationsMeanStddevMax([]time.Duration{
+		2 * time.Second,
+		4 * time.Second,
+		4 * time.Second,
+		4 * time.Second,
+		5 * time.Second,
+		5 * time.Second,
+		7 * time.Second,
+		9 * time.Second,
+	})
+	require.Equal(t, 5*time.Second, mean)
+	require.Equal(t, 2*time.Second, stddev)
+	require.Equal(t, 9*time.Second, max)
+}
+
+func TestTopicStatistics(t *testing.T) {
+	m, err := newContainerMaster()
+	require.NoError(t, err)
+	defer m.close()
+
+	func() {
+		n, err := NewNode(NodeConf{
+			Namespace:     "/myns",
+			Name:          "goroslib_param",
+			MasterAddress: m.IP() + ":11311",
+		})
+		require.NoError(t, err)
+		defer n.Close()
+
+		err = n.ParamSetBool("/enable_statistics", true)
+		require.NoError(t, err)
+	}()
+
+	p, err := NewNode(NodeConf{
+		Namespace:     "/myns",
+		Name:          "goroslib_pub",
+		MasterAddress: m.IP() + ":11311",
+	})
+	require.NoError(t, err)
+	defer p.Close()
+
+	pub, err := NewPublisher(PublisherConf{
+		Node:  p,
+		Topic: "test_topic",
+		Msg:   &sensor_msgs.Imu{},
+	})
+	require.NoError(t, err)
+	defer pub.Close()
+
+	recv := make(chan *rosgraph_msgs.TopicStatistics, 10)
+	statsSub, err := NewSubscriber(SubscriberConf{
+		Node:  p,
+		Topic: "/statistics",
+		Callback: func(msg *rosgraph_msgs.TopicStatistics) {
+			recv <- msg
+		},
+		QueueSize: 10,
+	})
+	require.NoError(t, err)
+	defer statsSub.Close()
+
+	n, err := NewNode(NodeConf{
+		Namespace:           "/myns",
+		Name:                "goroslib",
+		MasterAddress:       m.IP() + ":11311",
+		DisableIntraProcess: true,
+	})
+	require.NoError(t, err)
+	defer n.Close()
+
+	sub, err := NewSubscriber(SubscriberConf{
+		Node:     n,
+		Topic:    "test_topic",
+		Callback: func(msg *sensor_msgs.Imu) {},
+	})
+	require.NoError(t, err)
+	defer sub.Close()
+
+	time.Sleep(1 * time.Second)
+
+	var msg *rosgraph_msgs.TopicStatistics
+
+	for i := 0; msg == nil; i++ {
+		pub.Write(&sensor_msgs.Imu{
+			Header: std_msgs.Header{
+				Seq:   uint32(i),
+				Stamp: time.Now(),
+			},
+		})
+
+		select {
+		case msg = <-recv:
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	require.Equal(t, "/myns/test_topic", msg.Topic)
+	require.Equal(t, "/myns/goroslib_pub", msg.NodePub)
+	require.Equal(t, "/myns/goroslib", msg.NodeSub)
+	require.Greater(t, msg.DeliveredMsgs, int32(0))
+	require.Equal(t, int32(0), msg.DroppedMsgs)
+	require.Greater(t, msg.Traffic, int32(0))
+	require.Greater(t, int64(msg.PeriodMean), int64(0))
+}
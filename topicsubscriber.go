@@ -0,0 +1,179 @@
+package goroslib
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+// topicSubscriber is shared among all the Subscribers of a topic.
+// It owns the registration with the master and the connections with publishers.
+type topicSubscriber struct {
+	conf SubscriberConf
+
+	ctx            context.Context
+	ctxCancel      func()
+	msgMsg         reflect.Type
+	msgType        string
+	msgMd5         string
+	publishers     map[string]*subscriberPublisher
+	publishersWg   sync.WaitGroup
+	mutex          sync.Mutex
+	handles        []*Subscriber
+	publisherCount int
+
+	// in
+	getBusInfo          chan getBusInfoSubReq
+	getStats            chan getStatsSubReq
+	subscriberPubUpdate chan []string
+
+	// out
+	done chan struct{}
+}
+
+func newTopicSubscriber(conf SubscriberConf, msgMsg reflect.Type,
+	msgType string, msgMd5 string) *topicSubscriber {
+	ctx, ctxCancel := context.WithCancel(conf.Node.ctx)
+
+	s := &topicSubscriber{
+		conf:                conf,
+		ctx:                 ctx,
+		ctxCancel:           ctxCancel,
+		msgMsg:              msgMsg,
+		msgType:             msgType,
+		msgMd5:              msgMd5,
+		publishers:          make(map[string]*subscriberPublisher),
+		getBusInfo:          make(chan getBusInfoSubReq),
+		getStats:            make(chan getStatsSubReq),
+		subscriberPubUpdate: make(chan []string),
+		done:                make(chan struct{}),
+	}
+
+	go s.run()
+
+	return s
+}
+
+func (s *topicSubscriber) close() {
+	s.ctxCancel()
+}
+
+// addSubscriber attaches a Subscriber.
+func (s *topicSubscriber) addSubscriber(sub *Subscriber) {
+	s.mutex.Lock()
+	s.handles = append(append([]*Subscriber(nil), s.handles...), sub)
+	hasPublishers := (s.publisherCount > 0)
+	s.mutex.Unlock()
+
+	if hasPublishers && sub.conf.onPublisher != nil {
+		sub.conf.onPublisher()
+	}
+}
+
+// removeSubscriber removes a Subscriber, and returns the number of remaining Subscribers.
+func (s *topicSubscriber) removeSubscriber(sub *Subscriber) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var handles []*Subscriber
+	for _, h := range s.handles {
+		if h != sub {
+			handles = append(handles, h)
+		}
+	}
+	s.handles = handles
+
+	return len(s.handles)
+}
+
+func (s *topicSubscriber) onPublisherConnect() {
+	s.mutex.Lock()
+	s.publisherCount++
+	handles := s.handles
+	s.mutex.Unlock()
+
+	for _, h := range handles {
+		if h.conf.onPublisher != nil {
+			h.conf.onPublisher()
+		}
+	}
+}
+
+func (s *topicSubscriber) onPublisherDisconnect() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.publisherCount--
+}
+
+// dispatch sends a message to all the attached Subscribers.
+// intra tells whether the message comes from a publisher of the same process.
+// It returns false if the message has been discarded by at least one Subscriber.
+func (s *topicSubscriber) dispatch(msg interface{}, bytes uint64, intra bool) bool {
+	s.mutex.Lock()
+	handles := s.handles
+	s.mutex.Unlock()
+
+	ok := true
+	for _, h := range handles {
+		if !h.push(msg, bytes, intra) {
+			ok = false
+		}
+	}
+	return ok
+}
+
+func (s *topicSubscriber) run() {
+	defer close(s.done)
+
+outer:
+	for {
+		select {
+		case req := <-s.getBusInfo:
+			for _, sp := range s.publishers {
+				*req.pbusInfo = append(*req.pbusInfo, sp.stats.busInfo(sp.info()))
+			}
+			close(req.done)
+
+		case req := <-s.getStats:
+			for _, sp := range s.publishers {
+				*req.pstats = append(*req.pstats, sp.stats.get(sp.info()))
+			}
+			close(req.done)
+
+		case urls := <-s.subscriberPubUpdate:
+			var addresses []string
+			for _, u := range urls {
+				addr, err := urlToAddress(u)
+				if err != nil {
+					continue
+				}
+				addresses = append(addresses, addr)
+			}
+
+			validPublishers := make(map[string]struct{})
+
+			// add new publishers
+			for _, addr := range addresses {
+				validPublishers[addr] = struct{}{}
+
+				if _, ok := s.publishers[addr]; !ok {
+					newSubscriberPublisher(s, addr)
+				}
+			}
+
+			// remove outdated publishers
+			for addr, pub := range s.publishers {
+				if _, ok := validPublishers[addr]; !ok {
+					pub.close()
+				}
+			}
+
+		case <-s.ctx.Done():
+			break outer
+		}
+	}
+
+	s.ctxCancel()
+
+	s.publishersWg.Wait()
+}
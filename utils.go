@@ -1,6 +1,7 @@
 package goroslib
 
 import (
+	"net"
 	"net/url"
 )
 
@@ -12,3 +13,23 @@ func urlToAddress(in string) (string, error) {
 
 	return u.Host, nil
 }
+
+// isLocalIP checks whether an IP belongs to an interface of this host.
+func isLocalIP(ip net.IP) bool {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+
+	for _, addr := range addrs {
+		if v, ok := addr.(*net.IPNet); ok && v.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameUDPAddr checks whether the source of a datagram matches a recorded address.
+func sameUDPAddr(recorded *net.UDPAddr, source *net.UDPAddr) bool {
+	return recorded.IP.Equal(source.IP) && recorded.Port == source.Port
+}